@@ -0,0 +1,433 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	gxo "github.com/gxo-labs/gxo/pkg/gxo/v1"
+
+	"github.com/gxo-labs/gxo/internal/cache"
+	"github.com/gxo-labs/gxo/internal/checkpoint"
+	"github.com/gxo-labs/gxo/internal/config"
+	"github.com/gxo-labs/gxo/internal/tracing"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultConfigFile is the engine configuration file read from the working
+	// directory when no other file is named.
+	DefaultConfigFile = "gxo.yaml"
+	// ConfigEnvVar names the engine configuration file to read.
+	ConfigEnvVar = "GXO_CONFIG"
+)
+
+// Output formats of the config show command.
+const (
+	configFormatYAML = "yaml"
+	configFormatJSON = "json"
+)
+
+// engineConfig is the configuration of the engine run by the CLI. The
+// defaults are overridden by the configuration file, then by GXO_* environment
+// variables, then by command line flags.
+type engineConfig struct {
+	LogLevel         string              `yaml:"log_level" json:"log_level"`
+	LogFormat        string              `yaml:"log_format" json:"log_format"`
+	WorkerPoolSize   int                 `yaml:"worker_pool_size" json:"worker_pool_size"`
+	DefaultTimeout   string              `yaml:"default_timeout" json:"default_timeout"`
+	ChannelPolicy    channelPolicyConfig `yaml:"channel_policy" json:"channel_policy"`
+	RedactedKeywords []string            `yaml:"redacted_keywords" json:"redacted_keywords"`
+	StallPolicy      stallPolicyConfig   `yaml:"stall_policy" json:"stall_policy"`
+	Tracing          tracingConfig       `yaml:"tracing" json:"tracing"`
+	EventBusSize     int                 `yaml:"event_bus_size" json:"event_bus_size"`
+	CheckpointDir    string              `yaml:"checkpoint_dir" json:"checkpoint_dir"`
+	CacheDir         string              `yaml:"cache_dir" json:"cache_dir"`
+	ControlDir       string              `yaml:"control_dir" json:"control_dir"`
+}
+
+// channelPolicyConfig is the default policy of streaming channels.
+type channelPolicyConfig struct {
+	BufferSize       int    `yaml:"buffer_size" json:"buffer_size"`
+	OverflowStrategy string `yaml:"overflow_strategy" json:"overflow_strategy"`
+}
+
+// stallPolicyConfig configures the engine's stall detection.
+type stallPolicyConfig struct {
+	Interval  string `yaml:"interval" json:"interval"`
+	Tolerance int    `yaml:"tolerance" json:"tolerance"`
+}
+
+// tracingConfig configures the OTLP trace exporter. Empty values fall back to
+// the standard OTEL_* environment variables.
+type tracingConfig struct {
+	Enabled     bool   `yaml:"enabled" json:"enabled"`
+	Protocol    string `yaml:"protocol,omitempty" json:"protocol,omitempty"`
+	Endpoint    string `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+	Insecure    bool   `yaml:"insecure,omitempty" json:"insecure,omitempty"`
+	ServiceName string `yaml:"service_name,omitempty" json:"service_name,omitempty"`
+}
+
+// defaultEngineConfig returns the configuration used when nothing overrides it.
+func defaultEngineConfig() *engineConfig {
+	return &engineConfig{
+		LogLevel:         DefaultLogLevel,
+		LogFormat:        DefaultLogFmt,
+		WorkerPoolSize:   runtime.NumCPU(),
+		DefaultTimeout:   "0s",
+		ChannelPolicy:    channelPolicyConfig{BufferSize: DefaultChannelBufferSize, OverflowStrategy: config.OverflowBlock},
+		RedactedKeywords: []string{"password", "token", "secret", "apikey", "privatekey", "authorization", "bearer"},
+		StallPolicy:      stallPolicyConfig{Interval: "1s", Tolerance: 5},
+		Tracing:          tracingConfig{Enabled: true},
+		EventBusSize:     DefaultEventBusSize,
+		CheckpointDir:    DefaultCheckpointDir,
+		CacheDir:         DefaultCacheDir,
+		ControlDir:       DefaultControlDir,
+	}
+}
+
+// engineSetting is a configuration value that can be overridden by an
+// environment variable and by a command line flag.
+type engineSetting struct {
+	flag   string
+	env    string
+	usage  string
+	isBool bool
+	get    func(c *engineConfig) string
+	set    func(c *engineConfig, value string) error
+}
+
+// engineSettings lists the settings that can be overridden, in the order
+// flags are documented.
+var engineSettings = []engineSetting{
+	stringSetting("log-level", "GXO_LOG_LEVEL", "Log level (debug, info, warn, error)", func(c *engineConfig) *string { return &c.LogLevel }),
+	stringSetting("log-format", "GXO_LOG_FORMAT", "Log format (text, json)", func(c *engineConfig) *string { return &c.LogFormat }),
+	intSetting("worker-pool-size", "GXO_WORKER_POOL_SIZE", "Number of task execution workers (0 uses the number of CPUs)", func(c *engineConfig) *int { return &c.WorkerPoolSize }),
+	stringSetting("default-timeout", "GXO_DEFAULT_TIMEOUT", "Timeout of tasks that set none, e.g. '5m' ('0s' for none)", func(c *engineConfig) *string { return &c.DefaultTimeout }),
+	intSetting("channel-buffer-size", "GXO_CHANNEL_BUFFER_SIZE", "Default buffer size for streaming channels", func(c *engineConfig) *int { return &c.ChannelPolicy.BufferSize }),
+	stringSetting("channel-overflow-strategy", "GXO_CHANNEL_OVERFLOW_STRATEGY", "Default overflow strategy for streaming channels (block, drop_new, drop_oldest, error)", func(c *engineConfig) *string { return &c.ChannelPolicy.OverflowStrategy }),
+	{
+		flag:  "redacted-keywords",
+		env:   "GXO_REDACTED_KEYWORDS",
+		usage: "Comma-separated keywords whose values are redacted from errors and logs",
+		get:   func(c *engineConfig) string { return strings.Join(c.RedactedKeywords, ",") },
+		set: func(c *engineConfig, value string) error {
+			c.RedactedKeywords = nil
+			for _, keyword := range strings.Split(value, ",") {
+				if keyword = strings.TrimSpace(keyword); keyword != "" {
+					c.RedactedKeywords = append(c.RedactedKeywords, keyword)
+				}
+			}
+			return nil
+		},
+	},
+	stringSetting("stall-interval", "GXO_STALL_INTERVAL", "Interval at which the engine checks that a run makes progress", func(c *engineConfig) *string { return &c.StallPolicy.Interval }),
+	intSetting("stall-tolerance", "GXO_STALL_TOLERANCE", "Number of intervals without progress after which a run is halted as stalled", func(c *engineConfig) *int { return &c.StallPolicy.Tolerance }),
+	boolSetting("tracing-enabled", "GXO_TRACING_ENABLED", "Export traces over OTLP", func(c *engineConfig) *bool { return &c.Tracing.Enabled }),
+	stringSetting("tracing-protocol", "GXO_TRACING_PROTOCOL", "OTLP protocol (grpc, http/protobuf); defaults to OTEL_EXPORTER_OTLP_PROTOCOL", func(c *engineConfig) *string { return &c.Tracing.Protocol }),
+	stringSetting("tracing-endpoint", "GXO_TRACING_ENDPOINT", "OTLP collector endpoint; defaults to OTEL_EXPORTER_OTLP_ENDPOINT", func(c *engineConfig) *string { return &c.Tracing.Endpoint }),
+	boolSetting("tracing-insecure", "GXO_TRACING_INSECURE", "Connect to the OTLP collector without TLS", func(c *engineConfig) *bool { return &c.Tracing.Insecure }),
+	stringSetting("tracing-service-name", "GXO_TRACING_SERVICE_NAME", "Service name of exported spans; defaults to OTEL_SERVICE_NAME or 'gxo'", func(c *engineConfig) *string { return &c.Tracing.ServiceName }),
+	intSetting("event-bus-size", "GXO_EVENT_BUS_SIZE", "Buffer size of the engine's event bus", func(c *engineConfig) *int { return &c.EventBusSize }),
+	stringSetting("checkpoint-dir", "GXO_CHECKPOINT_DIR", "Directory for run checkpoints (empty disables checkpointing)", func(c *engineConfig) *string { return &c.CheckpointDir }),
+	stringSetting("cache-dir", "GXO_CACHE_DIR", "Directory for the cached results of tasks with a 'cache' (empty disables caching)", func(c *engineConfig) *string { return &c.CacheDir }),
+	stringSetting("control-dir", "GXO_CONTROL_DIR", "Directory for run control sockets used by 'gxo control' (empty disables run control)", func(c *engineConfig) *string { return &c.ControlDir }),
+}
+
+func stringSetting(flagName, env, usage string, field func(c *engineConfig) *string) engineSetting {
+	return engineSetting{
+		flag:  flagName,
+		env:   env,
+		usage: usage,
+		get:   func(c *engineConfig) string { return *field(c) },
+		set: func(c *engineConfig, value string) error {
+			*field(c) = value
+			return nil
+		},
+	}
+}
+
+func intSetting(flagName, env, usage string, field func(c *engineConfig) *int) engineSetting {
+	return engineSetting{
+		flag:  flagName,
+		env:   env,
+		usage: usage,
+		get:   func(c *engineConfig) string { return strconv.Itoa(*field(c)) },
+		set: func(c *engineConfig, value string) error {
+			parsed, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return fmt.Errorf("expected an integer, got '%s'", value)
+			}
+			*field(c) = parsed
+			return nil
+		},
+	}
+}
+
+func boolSetting(flagName, env, usage string, field func(c *engineConfig) *bool) engineSetting {
+	return engineSetting{
+		flag:   flagName,
+		env:    env,
+		usage:  usage,
+		isBool: true,
+		get:    func(c *engineConfig) string { return strconv.FormatBool(*field(c)) },
+		set: func(c *engineConfig, value string) error {
+			parsed, err := strconv.ParseBool(strings.TrimSpace(value))
+			if err != nil {
+				return fmt.Errorf("expected a boolean, got '%s'", value)
+			}
+			*field(c) = parsed
+			return nil
+		},
+	}
+}
+
+// settingFlag holds the raw value of an engine setting given on the command
+// line. It is applied once the configuration file and environment are read.
+type settingFlag struct {
+	value  string
+	isBool bool
+}
+
+func (f *settingFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.value
+}
+
+func (f *settingFlag) Set(value string) error {
+	f.value = value
+	return nil
+}
+
+func (f *settingFlag) IsBoolFlag() bool { return f.isBool }
+
+// engineFlags holds the engine configuration flags defined on a flag set.
+type engineFlags struct {
+	fs         *flag.FlagSet
+	configPath string
+	settings   map[string]*settingFlag
+}
+
+// registerEngineFlags defines the -config flag and a flag for each engine
+// setting on fs.
+func registerEngineFlags(fs *flag.FlagSet) *engineFlags {
+	flags := &engineFlags{fs: fs, settings: make(map[string]*settingFlag, len(engineSettings))}
+	fs.StringVar(&flags.configPath, "config", "", fmt.Sprintf("Path to the engine configuration file (default: $%s, else ./%s if it exists)", ConfigEnvVar, DefaultConfigFile))
+	defaults := defaultEngineConfig()
+	for _, setting := range engineSettings {
+		value := &settingFlag{value: setting.get(defaults), isBool: setting.isBool}
+		flags.settings[setting.flag] = value
+		fs.Var(value, setting.flag, fmt.Sprintf("%s (env %s)", setting.usage, setting.env))
+	}
+	return flags
+}
+
+// load returns the effective engine configuration and the path of the
+// configuration file it was read from, if any. It must be called after the
+// flag set has been parsed.
+func (f *engineFlags) load() (*engineConfig, string, error) {
+	cfg := defaultEngineConfig()
+
+	path, explicit := f.configPath, true
+	if path == "" {
+		path = os.Getenv(ConfigEnvVar)
+	}
+	if path == "" {
+		path, explicit = DefaultConfigFile, false
+	}
+	if err := cfg.readFile(path); err != nil {
+		if explicit || !errors.Is(err, os.ErrNotExist) {
+			return nil, "", err
+		}
+		path = ""
+	}
+
+	for _, setting := range engineSettings {
+		if value, isSet := os.LookupEnv(setting.env); isSet {
+			if err := setting.set(cfg, value); err != nil {
+				return nil, "", fmt.Errorf("invalid %s: %w", setting.env, err)
+			}
+		}
+	}
+	var flagErr error
+	f.fs.Visit(func(fl *flag.Flag) {
+		for _, setting := range engineSettings {
+			if setting.flag == fl.Name && flagErr == nil {
+				if err := setting.set(cfg, f.settings[fl.Name].value); err != nil {
+					flagErr = fmt.Errorf("invalid -%s: %w", fl.Name, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, "", flagErr
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, "", err
+	}
+	return cfg, path, nil
+}
+
+// readFile overrides the configuration with the values of a YAML file.
+// Unknown keys are rejected so that typos do not go unnoticed.
+func (c *engineConfig) readFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open engine configuration file: %w", err)
+	}
+	defer file.Close()
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid engine configuration file '%s': %w", path, err)
+	}
+	return nil
+}
+
+// validate checks the configuration and normalizes a worker pool size of 0.
+func (c *engineConfig) validate() error {
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("log_level must be one of debug, info, warn, error, got '%s'", c.LogLevel)
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		return fmt.Errorf("log_format must be 'text' or 'json', got '%s'", c.LogFormat)
+	}
+	if c.WorkerPoolSize < 0 {
+		return fmt.Errorf("worker_pool_size cannot be negative, got %d", c.WorkerPoolSize)
+	}
+	if c.WorkerPoolSize == 0 {
+		c.WorkerPoolSize = runtime.NumCPU()
+	}
+	if timeout, err := time.ParseDuration(c.DefaultTimeout); err != nil || timeout < 0 {
+		return fmt.Errorf("default_timeout must be a non-negative duration, got '%s'", c.DefaultTimeout)
+	}
+	if c.ChannelPolicy.BufferSize < 0 {
+		return fmt.Errorf("channel_policy.buffer_size cannot be negative, got %d", c.ChannelPolicy.BufferSize)
+	}
+	switch c.ChannelPolicy.OverflowStrategy {
+	case config.OverflowBlock, config.OverflowDropNew, config.OverflowDropOldest, config.OverflowError:
+	default:
+		return fmt.Errorf("channel_policy.overflow_strategy must be one of %s, %s, %s, %s, got '%s'", config.OverflowBlock, config.OverflowDropNew, config.OverflowDropOldest, config.OverflowError, c.ChannelPolicy.OverflowStrategy)
+	}
+	if interval, err := time.ParseDuration(c.StallPolicy.Interval); err != nil || interval <= 0 {
+		return fmt.Errorf("stall_policy.interval must be a positive duration, got '%s'", c.StallPolicy.Interval)
+	}
+	if c.StallPolicy.Tolerance <= 0 {
+		return fmt.Errorf("stall_policy.tolerance must be positive, got %d", c.StallPolicy.Tolerance)
+	}
+	switch strings.ToLower(c.Tracing.Protocol) {
+	case "", "grpc", "http", "http/protobuf":
+	default:
+		return fmt.Errorf("tracing.protocol must be 'grpc' or 'http/protobuf', got '%s'", c.Tracing.Protocol)
+	}
+	if c.EventBusSize <= 0 {
+		return fmt.Errorf("event_bus_size must be positive, got %d", c.EventBusSize)
+	}
+	return nil
+}
+
+// engineOptions returns the engine options set by the configuration. The
+// components the CLI builds itself, such as the state store and the event
+// bus, are not included.
+func (c *engineConfig) engineOptions() []gxo.EngineOption {
+	// The durations were checked by validate.
+	defaultTimeout, _ := time.ParseDuration(c.DefaultTimeout)
+	stallInterval, _ := time.ParseDuration(c.StallPolicy.Interval)
+	bufferSize := c.ChannelPolicy.BufferSize
+	opts := []gxo.EngineOption{
+		gxo.WithWorkerPoolSize(c.WorkerPoolSize),
+		gxo.WithDefaultTimeout(defaultTimeout),
+		gxo.WithDefaultChannelPolicy(gxo.ChannelPolicy{BufferSize: &bufferSize, OverflowStrategy: c.ChannelPolicy.OverflowStrategy}),
+		gxo.WithRedactedKeywords(c.RedactedKeywords),
+		gxo.WithStallPolicy(stallInterval, c.StallPolicy.Tolerance),
+	}
+	if c.CheckpointDir != "" {
+		opts = append(opts, gxo.WithCheckpointStore(checkpoint.NewFileStore(c.CheckpointDir)))
+	}
+	if c.CacheDir != "" {
+		opts = append(opts, gxo.WithCacheStore(cache.NewFileStore(c.CacheDir)))
+	}
+	return opts
+}
+
+// tracingSettings returns the settings of the tracer provider.
+func (c *engineConfig) tracingSettings() tracing.Settings {
+	return tracing.Settings{
+		Disabled:    !c.Tracing.Enabled,
+		Protocol:    c.Tracing.Protocol,
+		Endpoint:    c.Tracing.Endpoint,
+		Insecure:    c.Tracing.Insecure,
+		ServiceName: c.Tracing.ServiceName,
+	}
+}
+
+func runConfigCommand(args []string) int {
+	showFlags := flag.NewFlagSet("config show", flag.ContinueOnError)
+	format := showFlags.String("format", configFormatYAML, "Output format (yaml, json)")
+	engineFlags := registerEngineFlags(showFlags)
+
+	showFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s config show [-config <path>] [-format yaml|json] [flags...]\n\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Prints the effective engine configuration: the defaults, overridden by the")
+		fmt.Fprintf(os.Stderr, "configuration file (-config, $%s or ./%s), then by GXO_* environment\n", ConfigEnvVar, DefaultConfigFile)
+		fmt.Fprintln(os.Stderr, "variables, then by the flags given here.")
+		fmt.Fprintln(os.Stderr, "\nFlags:")
+		showFlags.PrintDefaults()
+	}
+
+	if len(args) == 0 || args[0] != "show" {
+		showFlags.Usage()
+		return ExitUsageError
+	}
+	if err := showFlags.Parse(args[1:]); err != nil {
+		return ExitUsageError
+	}
+	if *format != configFormatYAML && *format != configFormatJSON {
+		fmt.Fprintf(os.Stderr, "Error: -format must be '%s' or '%s'\n", configFormatYAML, configFormatJSON)
+		return ExitUsageError
+	}
+
+	cfg, path, err := engineFlags.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return ExitFailure
+	}
+	if path != "" {
+		fmt.Fprintf(os.Stderr, "Configuration file: %s\n", path)
+	} else {
+		fmt.Fprintln(os.Stderr, "Configuration file: none")
+	}
+
+	if err := writeEngineConfig(os.Stdout, cfg, *format); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write configuration: %v\n", err)
+		return ExitFailure
+	}
+	return ExitSuccess
+}
+
+// writeEngineConfig writes the configuration to w in the given format.
+func writeEngineConfig(w io.Writer, cfg *engineConfig, format string) error {
+	if format == configFormatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(cfg)
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
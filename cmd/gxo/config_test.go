@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gxo-labs/gxo/internal/cache"
+	"github.com/gxo-labs/gxo/internal/checkpoint"
+	"github.com/gxo-labs/gxo/internal/config"
+	gxo "github.com/gxo-labs/gxo/pkg/gxo/v1"
+	gxocache "github.com/gxo-labs/gxo/pkg/gxo/v1/cache"
+	gxocheckpoint "github.com/gxo-labs/gxo/pkg/gxo/v1/checkpoint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadTestConfig loads the engine configuration of a command given args, with
+// no GXO_* environment variables set other than those in env.
+func loadTestConfig(t *testing.T, args []string, env map[string]string) (*engineConfig, string, error) {
+	t.Helper()
+	for _, setting := range engineSettings {
+		t.Setenv(setting.env, "")
+		os.Unsetenv(setting.env)
+	}
+	t.Setenv(ConfigEnvVar, "")
+	os.Unsetenv(ConfigEnvVar)
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := registerEngineFlags(fs)
+	require.NoError(t, fs.Parse(args))
+	return flags.load()
+}
+
+// chdir changes the working directory for the duration of the test.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	previous, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { _ = os.Chdir(previous) })
+}
+
+func writeConfigFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestEngineConfig_Precedence(t *testing.T) {
+	testCases := []struct {
+		name     string
+		files    map[string]string
+		env      map[string]string
+		args     []string
+		wantFile string
+		want     map[string]string
+	}{
+		{
+			name: "Defaults",
+			want: map[string]string{"log-level": DefaultLogLevel, "log-format": DefaultLogFmt, "worker-pool-size": strconv.Itoa(runtime.NumCPU()), "stall-interval": "1s", "checkpoint-dir": DefaultCheckpointDir},
+		},
+		{
+			name:     "DefaultFileOverridesDefaults",
+			files:    map[string]string{DefaultConfigFile: "log_level: debug\nworker_pool_size: 3\n"},
+			wantFile: DefaultConfigFile,
+			want:     map[string]string{"log-level": "debug", "worker-pool-size": "3", "log-format": DefaultLogFmt},
+		},
+		{
+			name:     "ConfigEnvVarNamesFile",
+			files:    map[string]string{DefaultConfigFile: "log_level: debug\n", "other.yaml": "log_level: warn\n"},
+			env:      map[string]string{ConfigEnvVar: "other.yaml"},
+			wantFile: "other.yaml",
+			want:     map[string]string{"log-level": "warn"},
+		},
+		{
+			name:     "ConfigFlagOverridesConfigEnvVar",
+			files:    map[string]string{"env.yaml": "log_level: warn\n", "flag.yaml": "log_level: error\n"},
+			env:      map[string]string{ConfigEnvVar: "env.yaml"},
+			args:     []string{"-config", "flag.yaml"},
+			wantFile: "flag.yaml",
+			want:     map[string]string{"log-level": "error"},
+		},
+		{
+			name:     "EnvOverridesFile",
+			files:    map[string]string{DefaultConfigFile: "log_level: debug\nworker_pool_size: 3\nstall_policy:\n  interval: 2s\n"},
+			env:      map[string]string{"GXO_LOG_LEVEL": "warn", "GXO_STALL_TOLERANCE": "9"},
+			wantFile: DefaultConfigFile,
+			want:     map[string]string{"log-level": "warn", "worker-pool-size": "3", "stall-interval": "2s", "stall-tolerance": "9"},
+		},
+		{
+			name:     "FlagOverridesEnv",
+			files:    map[string]string{DefaultConfigFile: "log_level: debug\ntracing:\n  enabled: true\n"},
+			env:      map[string]string{"GXO_LOG_LEVEL": "warn", "GXO_TRACING_ENABLED": "true", "GXO_REDACTED_KEYWORDS": "a,b"},
+			args:     []string{"-log-level", "error", "-tracing-enabled=false"},
+			wantFile: DefaultConfigFile,
+			want:     map[string]string{"log-level": "error", "tracing-enabled": "false", "redacted-keywords": "a,b"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			chdir(t, dir)
+			for name, content := range tc.files {
+				writeConfigFile(t, dir, name, content)
+			}
+
+			cfg, path, err := loadTestConfig(t, tc.args, tc.env)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantFile, path)
+			for _, setting := range engineSettings {
+				want, checked := tc.want[setting.flag]
+				if !checked {
+					continue
+				}
+				assert.Equal(t, want, setting.get(cfg), "Setting %s", setting.flag)
+			}
+		})
+	}
+}
+
+func TestEngineConfig_EverySettingHasEnvAndFlag(t *testing.T) {
+	chdir(t, t.TempDir())
+	values := map[string]string{
+		"log-level": "debug", "log-format": "json", "worker-pool-size": "7", "default-timeout": "5m",
+		"channel-buffer-size": "42", "channel-overflow-strategy": config.OverflowDropNew, "redacted-keywords": "pin,otp",
+		"stall-interval": "3s", "stall-tolerance": "4", "tracing-enabled": "false", "tracing-protocol": "grpc",
+		"tracing-endpoint": "collector:4317", "tracing-insecure": "true", "tracing-service-name": "svc",
+		"event-bus-size": "64", "checkpoint-dir": "cp", "cache-dir": "cc", "control-dir": "ctl",
+	}
+	require.Len(t, values, len(engineSettings), "Every setting must be covered")
+
+	env := make(map[string]string)
+	var args []string
+	for _, setting := range engineSettings {
+		value, exists := values[setting.flag]
+		require.True(t, exists, "Setting %s is not covered", setting.flag)
+		env[setting.env] = value
+		args = append(args, "-"+setting.flag+"="+value)
+	}
+
+	for name, run := range map[string]struct {
+		args []string
+		env  map[string]string
+	}{"Env": {env: env}, "Flags": {args: args}} {
+		t.Run(name, func(t *testing.T) {
+			cfg, _, err := loadTestConfig(t, run.args, run.env)
+			require.NoError(t, err)
+			for _, setting := range engineSettings {
+				assert.Equal(t, values[setting.flag], setting.get(cfg), "Setting %s", setting.flag)
+			}
+		})
+	}
+}
+
+func TestEngineConfig_Errors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		file     string
+		env      map[string]string
+		args     []string
+		expected string
+	}{
+		{name: "UnknownKey", file: "log_levle: debug\n", expected: "field log_levle not found"},
+		{name: "FileLogLevel", file: "log_level: verbose\n", expected: "log_level must be one of debug, info, warn, error, got 'verbose'"},
+		{name: "EnvLogLevel", env: map[string]string{"GXO_LOG_LEVEL": "verbose"}, expected: "log_level must be one of debug, info, warn, error, got 'verbose'"},
+		{name: "FlagLogLevel", args: []string{"-log-level", "INFO"}, expected: "log_level must be one of"},
+		{name: "LogFormat", file: "log_format: xml\n", expected: "log_format must be 'text' or 'json'"},
+		{name: "EnvInteger", env: map[string]string{"GXO_WORKER_POOL_SIZE": "many"}, expected: "invalid GXO_WORKER_POOL_SIZE: expected an integer, got 'many'"},
+		{name: "FlagBoolean", args: []string{"-tracing-enabled=maybe"}, expected: "invalid -tracing-enabled: expected a boolean, got 'maybe'"},
+		{name: "Duration", file: "default_timeout: soon\n", expected: "default_timeout must be a non-negative duration"},
+		{name: "OverflowStrategy", env: map[string]string{"GXO_CHANNEL_OVERFLOW_STRATEGY": "spill"}, expected: "channel_policy.overflow_strategy must be one of"},
+		{name: "TracingProtocol", file: "tracing:\n  protocol: udp\n", expected: "tracing.protocol must be 'grpc' or 'http/protobuf'"},
+		{name: "MissingExplicitFile", args: []string{"-config", "missing.yaml"}, expected: "failed to open engine configuration file"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			chdir(t, dir)
+			if tc.file != "" {
+				writeConfigFile(t, dir, DefaultConfigFile, tc.file)
+			}
+			_, _, err := loadTestConfig(t, tc.args, tc.env)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.expected)
+		})
+	}
+}
+
+// recordingEngine records the settings applied by engine options.
+type recordingEngine struct {
+	gxo.EngineV1
+	workerPoolSize  int
+	defaultTimeout  time.Duration
+	channelPolicy   gxo.ChannelPolicy
+	keywords        []string
+	stallPolicy     *config.StallPolicy
+	checkpointStore gxocheckpoint.Store
+	cacheStore      gxocache.Store
+}
+
+func (e *recordingEngine) SetWorkerPoolSize(size int) error { e.workerPoolSize = size; return nil }
+func (e *recordingEngine) SetDefaultTimeout(timeout time.Duration) error {
+	e.defaultTimeout = timeout
+	return nil
+}
+func (e *recordingEngine) SetDefaultChannelPolicy(policy gxo.ChannelPolicy) error {
+	e.channelPolicy = policy
+	return nil
+}
+func (e *recordingEngine) SetRedactedKeywords(keywords []string) error {
+	e.keywords = keywords
+	return nil
+}
+func (e *recordingEngine) SetStallPolicy(policy *config.StallPolicy) error {
+	e.stallPolicy = policy
+	return nil
+}
+func (e *recordingEngine) SetCheckpointStore(store gxocheckpoint.Store) error {
+	e.checkpointStore = store
+	return nil
+}
+func (e *recordingEngine) SetCacheStore(store gxocache.Store) error {
+	e.cacheStore = store
+	return nil
+}
+
+func TestEngineConfig_EngineOptions(t *testing.T) {
+	cfg := defaultEngineConfig()
+	cfg.WorkerPoolSize = 6
+	cfg.DefaultTimeout = "90s"
+	cfg.ChannelPolicy = channelPolicyConfig{BufferSize: 12, OverflowStrategy: config.OverflowDropOldest}
+	cfg.RedactedKeywords = []string{"pin"}
+	cfg.StallPolicy = stallPolicyConfig{Interval: "2s", Tolerance: 3}
+	cfg.Tracing = tracingConfig{Enabled: false, Protocol: "grpc", Endpoint: "collector:4317", Insecure: true, ServiceName: "svc"}
+	require.NoError(t, cfg.validate())
+
+	recorder := &recordingEngine{}
+	for _, opt := range cfg.engineOptions() {
+		require.NoError(t, opt(recorder))
+	}
+	assert.Equal(t, 6, recorder.workerPoolSize)
+	assert.Equal(t, 90*time.Second, recorder.defaultTimeout)
+	require.NotNil(t, recorder.channelPolicy.BufferSize)
+	assert.Equal(t, 12, *recorder.channelPolicy.BufferSize)
+	assert.Equal(t, config.OverflowDropOldest, recorder.channelPolicy.OverflowStrategy)
+	assert.Equal(t, []string{"pin"}, recorder.keywords)
+	assert.Equal(t, &config.StallPolicy{Interval: 2 * time.Second, Tolerance: 3}, recorder.stallPolicy)
+	assert.IsType(t, &checkpoint.FileStore{}, recorder.checkpointStore)
+	assert.IsType(t, &cache.FileStore{}, recorder.cacheStore)
+
+	settings := cfg.tracingSettings()
+	assert.True(t, settings.Disabled)
+	assert.Equal(t, "grpc", settings.Protocol)
+	assert.Equal(t, "collector:4317", settings.Endpoint)
+	assert.True(t, settings.Insecure)
+	assert.Equal(t, "svc", settings.ServiceName)
+
+	cfg.CheckpointDir, cfg.CacheDir = "", ""
+	recorder = &recordingEngine{}
+	for _, opt := range cfg.engineOptions() {
+		require.NoError(t, opt(recorder))
+	}
+	assert.Nil(t, recorder.checkpointStore, "An empty checkpoint_dir disables checkpointing")
+	assert.Nil(t, recorder.cacheStore, "An empty cache_dir disables caching")
+}
+
+func TestEngineConfig_Show(t *testing.T) {
+	dir := t.TempDir()
+	chdir(t, dir)
+	writeConfigFile(t, dir, DefaultConfigFile, "log_level: debug\nredacted_keywords: [pin]\n")
+	cfg, _, err := loadTestConfig(t, []string{"-worker-pool-size", "5"}, map[string]string{"GXO_LOG_FORMAT": "json"})
+	require.NoError(t, err)
+
+	var yamlOut bytes.Buffer
+	require.NoError(t, writeEngineConfig(&yamlOut, cfg, configFormatYAML))
+	assert.Contains(t, yamlOut.String(), "log_level: debug\n")
+	assert.Contains(t, yamlOut.String(), "log_format: json\n")
+	assert.Contains(t, yamlOut.String(), "worker_pool_size: 5\n")
+	assert.Contains(t, yamlOut.String(), "redacted_keywords:\n  - pin\n")
+
+	// The YAML output is a valid configuration file for the same settings.
+	shown := writeConfigFile(t, dir, "shown.yaml", yamlOut.String())
+	reloaded := defaultEngineConfig()
+	require.NoError(t, reloaded.readFile(shown))
+	assert.Equal(t, cfg, reloaded)
+
+	var jsonOut bytes.Buffer
+	require.NoError(t, writeEngineConfig(&jsonOut, cfg, configFormatJSON))
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(jsonOut.Bytes(), &decoded))
+	assert.Equal(t, "debug", decoded["log_level"])
+	assert.Equal(t, "json", decoded["log_format"])
+	assert.Equal(t, float64(5), decoded["worker_pool_size"])
+	assert.Equal(t, []interface{}{"pin"}, decoded["redacted_keywords"])
+}
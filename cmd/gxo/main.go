@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -9,6 +10,8 @@ import (
 	"os"
 	"os/signal"
 	"runtime"
+	"sort"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -18,11 +21,15 @@ import (
 	gxolog "github.com/gxo-labs/gxo/pkg/gxo/v1/log"
 
 	"github.com/gxo-labs/gxo/internal/config"
+	"github.com/gxo-labs/gxo/internal/control"
 	"github.com/gxo-labs/gxo/internal/engine"
 	"github.com/gxo-labs/gxo/internal/events"
+	"github.com/gxo-labs/gxo/internal/graph"
 	"github.com/gxo-labs/gxo/internal/logger"
 	"github.com/gxo-labs/gxo/internal/metrics"
 	"github.com/gxo-labs/gxo/internal/module"
+	"github.com/gxo-labs/gxo/internal/plan"
+	reportexport "github.com/gxo-labs/gxo/internal/report"
 	"github.com/gxo-labs/gxo/internal/secrets"
 	"github.com/gxo-labs/gxo/internal/state"
 	"github.com/gxo-labs/gxo/internal/tracing"
@@ -43,6 +50,9 @@ const (
 	DefaultLogFmt            = "text"
 	DefaultChannelBufferSize = 100
 	DefaultEventBusSize      = 256
+	DefaultCheckpointDir     = ".gxo/checkpoints"
+	DefaultCacheDir          = ".gxo/cache"
+	DefaultControlDir        = ".gxo/control"
 )
 
 var (
@@ -56,6 +66,21 @@ func main() {
 		runValidateCommand(os.Args[2:])
 		return
 	}
+	if len(os.Args) > 1 && os.Args[1] == "resume" {
+		os.Exit(runResumeCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "graph" {
+		os.Exit(runGraphCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "plan" {
+		os.Exit(runPlanCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "control" {
+		os.Exit(runControlCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfigCommand(os.Args[2:]))
+	}
 	if len(os.Args) == 2 && (os.Args[1] == "--version" || os.Args[1] == "-version") {
 		printVersion()
 		os.Exit(ExitSuccess)
@@ -72,14 +97,31 @@ func printVersion() {
 	fmt.Printf("os/arch: %s/%s\n", runtime.GOOS, runtime.GOARCH)
 }
 
+// Output formats of the validate command.
+const (
+	validateFormatText = "text"
+	validateFormatJSON = "json"
+)
+
+// validationResult is the JSON output of the validate command.
+type validationResult struct {
+	Playbook    string              `json:"playbook"`
+	Valid       bool                `json:"valid"`
+	Diagnostics []config.Diagnostic `json:"diagnostics"`
+	Inputs      []config.Input      `json:"inputs,omitempty"`
+}
+
 func runValidateCommand(args []string) {
 	validateFlags := flag.NewFlagSet("validate", flag.ExitOnError)
 	playbookPath := validateFlags.String("playbook", "", "Path to the playbook YAML file to validate (required)")
+	format := validateFlags.String("format", validateFormatText, "Output format for diagnostics (text, json)")
 	logLevel := validateFlags.String("log-level", DefaultLogLevel, "Log level for validation output (debug, info, warn, error)")
 
 	validateFlags.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: %s validate -playbook <path> [flags...]\n\n", os.Args[0])
 		fmt.Fprintln(os.Stderr, "Validates the structure and schema compatibility of a GXO playbook.")
+		fmt.Fprintln(os.Stderr, "Each problem is printed to stdout as 'file:line:col: message', or as JSON with -format json.")
+		fmt.Fprintln(os.Stderr, "The inputs the playbook declares are listed after the problems.")
 		fmt.Fprintln(os.Stderr, "\nFlags:")
 		validateFlags.PrintDefaults()
 	}
@@ -94,22 +136,55 @@ func runValidateCommand(args []string) {
 		validateFlags.Usage()
 		os.Exit(ExitUsageError)
 	}
+	if *format != validateFormatText && *format != validateFormatJSON {
+		fmt.Fprintf(os.Stderr, "Error: -format must be '%s' or '%s'\n", validateFormatText, validateFormatJSON)
+		os.Exit(ExitUsageError)
+	}
 
 	log := logger.NewLogger(*logLevel, "text", os.Stderr)
 	log.Infof("Validating playbook: %s", *playbookPath)
 
+	var playbook *config.Playbook
 	playbookBytes, err := os.ReadFile(*playbookPath)
-	if err != nil {
-		log.Errorf("Failed to read playbook file '%s': %v", *playbookPath, err)
-		os.Exit(ExitFailure)
+	if err == nil {
+		playbook, err = config.LoadPlaybook(playbookBytes, *playbookPath)
+	} else {
+		err = gxoerrors.NewConfigError(fmt.Sprintf("failed to read playbook file '%s'", *playbookPath), err)
+	}
+	diagnostics := config.Diagnostics(err, *playbookPath)
+	var inputs []config.Input
+	if playbook != nil {
+		inputs = playbook.DeclaredInputs()
+	}
+
+	if *format == validateFormatJSON {
+		result := validationResult{Playbook: *playbookPath, Valid: err == nil, Diagnostics: diagnostics, Inputs: inputs}
+		if result.Diagnostics == nil {
+			result.Diagnostics = []config.Diagnostic{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if encodeErr := encoder.Encode(result); encodeErr != nil {
+			log.Errorf("Failed to write validation result: %v", encodeErr)
+			os.Exit(ExitFailure)
+		}
+	} else {
+		for _, diagnostic := range diagnostics {
+			fmt.Println(diagnostic.String())
+		}
+		if len(inputs) > 0 {
+			fmt.Println("Inputs:")
+			for _, input := range inputs {
+				fmt.Printf("  %s\n", input)
+			}
+		}
 	}
 
-	_, err = config.LoadPlaybook(playbookBytes, *playbookPath)
 	if err != nil {
 		var validationErr *gxoerrors.ValidationError
 		var configErr *gxoerrors.ConfigError
 		if errors.As(err, &validationErr) {
-			log.Errorf("Playbook validation failed:\n%s", validationErr.Error())
+			log.Errorf("Playbook validation failed with %d problem(s).", len(diagnostics))
 		} else if errors.As(err, &configErr) {
 			log.Errorf("Playbook configuration error:\n%s", configErr.Error())
 		} else {
@@ -122,19 +197,270 @@ func runValidateCommand(args []string) {
 	os.Exit(ExitSuccess)
 }
 
+func runGraphCommand(args []string) int {
+	graphFlags := flag.NewFlagSet("graph", flag.ContinueOnError)
+	playbookPath := graphFlags.String("playbook", "", "Path to the playbook YAML file to graph (required)")
+	format := graphFlags.String("format", graph.FormatDOT, "Output format (dot, mermaid, json)")
+	outputPath := graphFlags.String("output", "", "File to write the graph to (default: stdout)")
+	logLevel := graphFlags.String("log-level", DefaultLogLevel, "Log level for graph output (debug, info, warn, error)")
+
+	graphFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s graph -playbook <path> [flags...]\n\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Prints the execution DAG of a GXO playbook without executing it.")
+		fmt.Fprintln(os.Stderr, "\nFlags:")
+		graphFlags.PrintDefaults()
+	}
+
+	if err := graphFlags.Parse(args); err != nil {
+		return ExitUsageError
+	}
+	if *playbookPath == "" {
+		fmt.Fprintln(os.Stderr, "Error: -playbook flag is required")
+		graphFlags.Usage()
+		return ExitUsageError
+	}
+	if *format != graph.FormatDOT && *format != graph.FormatMermaid && *format != graph.FormatJSON {
+		fmt.Fprintf(os.Stderr, "Error: -format must be '%s', '%s' or '%s'\n", graph.FormatDOT, graph.FormatMermaid, graph.FormatJSON)
+		return ExitUsageError
+	}
+
+	log := logger.NewLogger(*logLevel, "text", os.Stderr)
+
+	playbookBytes, err := os.ReadFile(*playbookPath)
+	if err != nil {
+		log.Errorf("Failed to read playbook file '%s': %v", *playbookPath, err)
+		return ExitFailure
+	}
+	playbook, err := config.LoadPlaybook(playbookBytes, *playbookPath)
+	if err != nil {
+		log.Errorf("Failed to load or validate playbook: %v", err)
+		return ExitFailure
+	}
+	g, err := graph.Build(playbook)
+	if err != nil {
+		log.Errorf("Failed to build execution graph: %v", err)
+		return ExitFailure
+	}
+
+	var out io.Writer = os.Stdout
+	if *outputPath != "" {
+		file, err := os.Create(*outputPath)
+		if err != nil {
+			log.Errorf("Failed to create output file '%s': %v", *outputPath, err)
+			return ExitFailure
+		}
+		defer file.Close()
+		out = file
+	}
+	if err := graph.Render(out, g, *format); err != nil {
+		log.Errorf("Failed to render execution graph: %v", err)
+		return ExitFailure
+	}
+	return ExitSuccess
+}
+
+func runPlanCommand(args []string) int {
+	planFlags := flag.NewFlagSet("plan", flag.ContinueOnError)
+	playbookPath := planFlags.String("playbook", "", "Path to the playbook YAML file to plan (required)")
+	format := planFlags.String("format", plan.FormatTable, "Output format (table, json)")
+	workerPoolSize := planFlags.Int("worker-pool-size", runtime.NumCPU(), "Number of task execution workers to plan for")
+	logLevel := planFlags.String("log-level", DefaultLogLevel, "Log level for plan output (debug, info, warn, error)")
+
+	planFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s plan -playbook <path> [flags...]\n\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Previews the execution of a GXO playbook without executing it: the waves of")
+		fmt.Fprintln(os.Stderr, "tasks that run concurrently, the critical path, loop fan-out and 'when'")
+		fmt.Fprintln(os.Stderr, "conditions that can be evaluated from 'vars'.")
+		fmt.Fprintln(os.Stderr, "\nFlags:")
+		planFlags.PrintDefaults()
+	}
+
+	if err := planFlags.Parse(args); err != nil {
+		return ExitUsageError
+	}
+	if *playbookPath == "" {
+		fmt.Fprintln(os.Stderr, "Error: -playbook flag is required")
+		planFlags.Usage()
+		return ExitUsageError
+	}
+	if *format != plan.FormatTable && *format != plan.FormatJSON {
+		fmt.Fprintf(os.Stderr, "Error: -format must be '%s' or '%s'\n", plan.FormatTable, plan.FormatJSON)
+		return ExitUsageError
+	}
+	if *workerPoolSize <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: -worker-pool-size must be positive")
+		return ExitUsageError
+	}
+
+	log := logger.NewLogger(*logLevel, "text", os.Stderr)
+
+	playbookBytes, err := os.ReadFile(*playbookPath)
+	if err != nil {
+		log.Errorf("Failed to read playbook file '%s': %v", *playbookPath, err)
+		return ExitFailure
+	}
+	playbook, err := config.LoadPlaybook(playbookBytes, *playbookPath)
+	if err != nil {
+		log.Errorf("Failed to load or validate playbook: %v", err)
+		return ExitFailure
+	}
+	executionPlan, err := engine.BuildPlan(playbook, *workerPoolSize)
+	if err != nil {
+		log.Errorf("Failed to build execution plan: %v", err)
+		return ExitFailure
+	}
+	if err := plan.Render(os.Stdout, executionPlan, *format); err != nil {
+		log.Errorf("Failed to render execution plan: %v", err)
+		return ExitFailure
+	}
+	return ExitSuccess
+}
+
+func runControlCommand(args []string) int {
+	controlFlags := flag.NewFlagSet("control", flag.ContinueOnError)
+	runID := controlFlags.String("run-id", "", "ID of the running playbook to control (required)")
+	controlDir := controlFlags.String("control-dir", DefaultControlDir, "Directory holding the run's control socket")
+
+	controlFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s control -run-id <id> [flags...] <command>\n\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Controls a playbook run executing in another gxo process.")
+		fmt.Fprintln(os.Stderr, "\nCommands:")
+		fmt.Fprintln(os.Stderr, "  status              Show the run's state and the status of its tasks")
+		fmt.Fprintln(os.Stderr, "  pause               Stop starting new tasks; running tasks finish")
+		fmt.Fprintln(os.Stderr, "  resume              Start tasks again after a pause")
+		fmt.Fprintln(os.Stderr, "  cancel-task <task>  Cancel a single running task, by name or ID")
+		fmt.Fprintln(os.Stderr, "\nFlags:")
+		controlFlags.PrintDefaults()
+	}
+
+	if err := controlFlags.Parse(args); err != nil {
+		return ExitUsageError
+	}
+	if *runID == "" {
+		fmt.Fprintln(os.Stderr, "Error: -run-id flag is required")
+		controlFlags.Usage()
+		return ExitUsageError
+	}
+	if controlFlags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Error: a control command is required")
+		controlFlags.Usage()
+		return ExitUsageError
+	}
+
+	var req control.Request
+	command := controlFlags.Arg(0)
+	switch command {
+	case "status":
+		req.Action = control.ActionStatus
+	case "pause":
+		req.Action = control.ActionPause
+	case "resume":
+		req.Action = control.ActionResume
+	case "cancel-task":
+		if controlFlags.NArg() != 2 {
+			fmt.Fprintln(os.Stderr, "Error: cancel-task requires the name of the task to cancel")
+			return ExitUsageError
+		}
+		req.Action = control.ActionCancelTask
+		req.Task = controlFlags.Arg(1)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown control command '%s'\n", command)
+		controlFlags.Usage()
+		return ExitUsageError
+	}
+
+	resp, err := control.Send(control.SocketPath(*controlDir, *runID), req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s failed: %v\n", command, err)
+		return ExitFailure
+	}
+	if resp.Status != nil {
+		printRunStatus(resp.Status)
+	}
+	return ExitSuccess
+}
+
+// printRunStatus prints a run status reported over a control socket.
+func printRunStatus(status *gxo.RunStatus) {
+	fmt.Printf("Run %s (%s): %s, %d/%d tasks finished\n", status.RunID, status.PlaybookName, status.State, status.FinishedTasks, status.TotalTasks)
+	taskIDs := make([]string, 0, len(status.TaskStatuses))
+	for id := range status.TaskStatuses {
+		taskIDs = append(taskIDs, id)
+	}
+	sort.Strings(taskIDs)
+	for _, id := range taskIDs {
+		fmt.Printf("  %s: %s\n", id, status.TaskStatuses[id])
+	}
+}
+
+// runSettings holds the flag values shared by every command that executes a
+// playbook, and the engine configuration they resolve to.
+type runSettings struct {
+	engineFlags  *engineFlags
+	dryRun       bool
+	noCache      bool
+	reportFile   string
+	reportFormat string
+
+	// config and configPath are set by load.
+	config     *engineConfig
+	configPath string
+}
+
+// registerRunFlags defines the execution flags shared by the run and resume commands.
+func registerRunFlags(fs *flag.FlagSet) *runSettings {
+	settings := &runSettings{engineFlags: registerEngineFlags(fs)}
+	fs.BoolVar(&settings.dryRun, "dry-run", false, "Execute playbook in dry-run mode (simulate actions)")
+	fs.BoolVar(&settings.noCache, "no-cache", false, "Execute tasks with a 'cache' even if a cached result exists (results are still cached)")
+	fs.StringVar(&settings.reportFile, "report-file", "", "Write the execution report to this file")
+	fs.StringVar(&settings.reportFormat, "report-format", reportexport.FormatJSON, "Format of the -report-file (json, junit, markdown)")
+	return settings
+}
+
+// load resolves the engine configuration from the configuration file, the
+// environment and the parsed flags. It returns false if the configuration is
+// unusable.
+func (s *runSettings) load() bool {
+	if !reportexport.IsSupportedFormat(s.reportFormat) {
+		fmt.Fprintf(os.Stderr, "Error: -report-format must be '%s', '%s' or '%s'\n", reportexport.FormatJSON, reportexport.FormatJUnit, reportexport.FormatMarkdown)
+		return false
+	}
+	cfg, path, err := s.engineFlags.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return false
+	}
+	s.config, s.configPath = cfg, path
+	return true
+}
+
 func runExecuteCommand(args []string) int {
 	execFlags := flag.NewFlagSet("gxo", flag.ExitOnError)
 	playbookPath := execFlags.String("playbook", "", "Path to the main playbook YAML file (required)")
-	logLevel := execFlags.String("log-level", DefaultLogLevel, "Log level (debug, info, warn, error)")
-	logFormat := execFlags.String("log-format", DefaultLogFmt, "Log format (text, json)")
-	dryRun := execFlags.Bool("dry-run", false, "Execute playbook in dry-run mode (simulate actions)")
-	workerPoolSize := execFlags.Int("worker-pool-size", runtime.NumCPU(), "Number of task execution workers")
-	defaultChannelBufferSize := execFlags.Int("channel-buffer-size", DefaultChannelBufferSize, "Default buffer size for streaming channels")
+	var inputVars inputVarFlags
+	execFlags.Var(&inputVars, "var", "Value of a playbook input as 'name=value' (repeatable, overrides -var-file)")
+	varFile := execFlags.String("var-file", "", "Path to a YAML file mapping playbook input names to values")
+	var selection gxo.TaskSelection
+	execFlags.Var((*listFlag)(&selection.Tags), "tags", "Run only the tasks with one of these comma-separated tags, and their dependencies")
+	execFlags.Var((*listFlag)(&selection.SkipTags), "skip-tags", "Skip the tasks with one of these comma-separated tags")
+	execFlags.Var((*listFlag)(&selection.Only), "only", "Run only these comma-separated tasks, and their dependencies (repeatable)")
+	execFlags.StringVar(&selection.StartAtTask, "start-at-task", "", "Run only this task, the tasks depending on it, and their dependencies")
+	execFlags.StringVar(&selection.ReuseRunID, "reuse-run", "", "Reuse the values registered by this checkpointed run instead of rerunning the dependencies that registered them")
+	settings := registerRunFlags(execFlags)
 	versionFlag := execFlags.Bool("version", false, "Print version information and exit")
 
 	execFlags.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [flags...] -playbook <path>\n\n", os.Args[0])
-		fmt.Fprintln(os.Stderr, "Executes a GXO playbook.")
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags...] -playbook <path> [-var name=value]... [-var-file <path>]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [flags...] -playbook <path> [-tags a,b] [-skip-tags c] [-only <task>] [-start-at-task <task>] [-reuse-run <id>]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s resume -run-id <id> [flags...]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s validate -playbook <path> [flags...]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s graph -playbook <path> [-format dot|mermaid|json]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s plan -playbook <path> [-format table|json]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s control -run-id <id> <pause|resume|status|cancel-task <task>>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s config show [-config <path>] [-format yaml|json]\n\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Executes a GXO playbook. Its rendered outputs are printed to stdout as JSON.")
+		fmt.Fprintf(os.Stderr, "Engine settings are read from the configuration file (-config, $%s or ./%s),\n", ConfigEnvVar, DefaultConfigFile)
+		fmt.Fprintln(os.Stderr, "overridden by GXO_* environment variables, then by flags.")
 		fmt.Fprintln(os.Stderr, "\nFlags:")
 		execFlags.PrintDefaults()
 	}
@@ -153,45 +479,153 @@ func runExecuteCommand(args []string) int {
 		execFlags.Usage()
 		return ExitUsageError
 	}
-	if *logFormat != "text" && *logFormat != "json" {
-		fmt.Fprintln(os.Stderr, "Error: -log-format must be 'text' or 'json'")
+	if !settings.load() {
 		return ExitUsageError
 	}
-	if *workerPoolSize <= 0 {
-		*workerPoolSize = runtime.NumCPU()
-		fmt.Fprintf(os.Stderr, "Warning: -worker-pool-size must be positive, defaulting to %d\n", *workerPoolSize)
+
+	inputValues, err := inputVars.values(*varFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return ExitUsageError
+	}
+
+	log := newCLILogger(settings)
+
+	log.Infof("Loading playbook: %s", *playbookPath)
+	playbookBytes, err := os.ReadFile(*playbookPath)
+	if err != nil {
+		log.Errorf("Failed to read playbook file '%s': %v", *playbookPath, err)
+		return ExitFailure
+	}
+
+	return executeWithEngine(log, settings, func(ctx context.Context, gxoEngine gxo.EngineV1) (gxo.RunHandle, error) {
+		log.Infof("Starting playbook execution...")
+		ctx = context.WithValue(ctx, gxo.PlaybookPathKey{}, *playbookPath)
+		ctx = context.WithValue(ctx, gxo.InputValuesKey{}, inputValues)
+		ctx = context.WithValue(ctx, gxo.TaskSelectionKey{}, selection)
+		return gxoEngine.StartPlaybook(ctx, playbookBytes)
+	})
+}
+
+// inputVarFlags collects the repeatable -var flag.
+type inputVarFlags []string
+
+func (f *inputVarFlags) String() string { return strings.Join(*f, ", ") }
+
+func (f *inputVarFlags) Set(value string) error {
+	if name, _, found := strings.Cut(value, "="); !found || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("expected 'name=value', got '%s'", value)
+	}
+	*f = append(*f, value)
+	return nil
+}
+
+// values returns the input values read from varFile, if set, overridden by
+// those given with -var. Values given with -var are strings; the engine
+// parses them into the declared types.
+func (f inputVarFlags) values(varFile string) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	if varFile != "" {
+		fileValues, err := config.ReadInputValuesFile(varFile)
+		if err != nil {
+			return nil, err
+		}
+		values = fileValues
+	}
+	for _, assignment := range f {
+		name, value, _ := strings.Cut(assignment, "=")
+		values[strings.TrimSpace(name)] = value
+	}
+	return values, nil
+}
+
+// listFlag collects comma-separated values, from one or more occurrences of
+// a flag.
+type listFlag []string
+
+func (f *listFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *listFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*f = append(*f, item)
+		}
 	}
-	if *defaultChannelBufferSize < 0 {
-		fmt.Fprintf(os.Stderr, "Error: -channel-buffer-size cannot be negative. Using default %d.\n", DefaultChannelBufferSize)
-		*defaultChannelBufferSize = DefaultChannelBufferSize
+	return nil
+}
+
+func runResumeCommand(args []string) int {
+	resumeFlags := flag.NewFlagSet("resume", flag.ExitOnError)
+	runID := resumeFlags.String("run-id", "", "ID of the run to resume (required)")
+	settings := registerRunFlags(resumeFlags)
+
+	resumeFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s resume -run-id <id> [flags...]\n\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Resumes a failed or interrupted playbook run from its checkpoint.")
+		fmt.Fprintln(os.Stderr, "Completed and skipped tasks are not executed again.")
+		fmt.Fprintln(os.Stderr, "\nFlags:")
+		resumeFlags.PrintDefaults()
 	}
 
+	if err := resumeFlags.Parse(args); err != nil {
+		return ExitUsageError
+	}
+	if *runID == "" {
+		fmt.Fprintln(os.Stderr, "Error: -run-id flag is required")
+		resumeFlags.Usage()
+		return ExitUsageError
+	}
+	if !settings.load() {
+		return ExitUsageError
+	}
+	if settings.config.CheckpointDir == "" {
+		fmt.Fprintln(os.Stderr, "Error: -checkpoint-dir cannot be empty when resuming a run")
+		return ExitUsageError
+	}
+
+	log := newCLILogger(settings)
+	return executeWithEngine(log, settings, func(ctx context.Context, gxoEngine gxo.EngineV1) (gxo.RunHandle, error) {
+		log.Infof("Resuming run %s...", *runID)
+		return gxoEngine.StartResumeRun(ctx, *runID)
+	})
+}
+
+func newCLILogger(settings *runSettings) gxolog.Logger {
 	var logWriter io.Writer = os.Stderr
-	log := logger.NewLogger(*logLevel, *logFormat, logWriter)
+	cfg := settings.config
+	log := logger.NewLogger(cfg.LogLevel, cfg.LogFormat, logWriter)
 	log = log.With("gxo_version", version)
 
 	log.Infof("GXO Automation Kernel v%s starting...", version)
-	log.Debugf("Log level: %s", *logLevel)
-	log.Debugf("Log format: %s", *logFormat)
-	log.Debugf("Worker pool size: %d", *workerPoolSize)
-	log.Debugf("Default channel buffer size: %d", *defaultChannelBufferSize)
+	if settings.configPath != "" {
+		log.Infof("Using engine configuration file: %s", settings.configPath)
+	}
+	log.Debugf("Log level: %s", cfg.LogLevel)
+	log.Debugf("Log format: %s", cfg.LogFormat)
+	log.Debugf("Worker pool size: %d", cfg.WorkerPoolSize)
+	log.Debugf("Default channel policy: buffer size %d, overflow strategy %s", cfg.ChannelPolicy.BufferSize, cfg.ChannelPolicy.OverflowStrategy)
+	log.Debugf("Checkpoint directory: %s", cfg.CheckpointDir)
+	log.Debugf("Control directory: %s", cfg.ControlDir)
+	return log
+}
 
+// executeWithEngine builds the engine and its components from the settings,
+// installs signal handling, starts the run with start, serves its control
+// socket until it finishes, and reports the outcome as an exit code.
+func executeWithEngine(log gxolog.Logger, settings *runSettings, start func(ctx context.Context, gxoEngine gxo.EngineV1) (gxo.RunHandle, error)) int {
+	cfg := settings.config
 	stateStore := state.NewMemoryStateStore()
-	eventBus := events.NewChannelEventBus(DefaultEventBusSize, log)
+	eventBus := events.NewChannelEventBus(cfg.EventBusSize, log)
 	defer eventBus.Close()
 	secretsProvider := secrets.NewEnvProvider()
 	pluginRegistry := module.DefaultStaticRegistryGetter
 	metricsProvider := metrics.NewPrometheusRegistryProvider()
-	tracerProvider, err := tracing.NewProviderFromEnv(context.Background())
+	tracerProvider, err := tracing.NewProvider(context.Background(), cfg.tracingSettings())
 	if err != nil {
-		log.Warnf("Failed to initialize tracing from environment: %v. Using NoOp tracer.", err)
+		log.Warnf("Failed to initialize tracing: %v. Using NoOp tracer.", err)
 		tracerProvider, _ = tracing.NewNoOpProvider()
 	}
 
-	defaultChanPolicy := gxo.ChannelPolicy{
-		BufferSize: defaultChannelBufferSize,
-	}
-
 	engineOpts := []gxo.EngineOption{
 		gxo.WithStateStore(stateStore),
 		gxo.WithEventBus(eventBus),
@@ -199,16 +633,17 @@ func runExecuteCommand(args []string) int {
 		gxo.WithPluginRegistry(pluginRegistry),
 		gxo.WithTracerProvider(tracerProvider),
 		gxo.WithMetricsRegistryProvider(metricsProvider),
-		gxo.WithWorkerPoolSize(*workerPoolSize),
-		gxo.WithDefaultChannelPolicy(defaultChanPolicy),
-		gxo.WithRedactedKeywords([]string{"password", "token", "secret", "apikey", "privatekey", "authorization", "bearer"}),
 	}
+	engineOpts = append(engineOpts, cfg.engineOptions()...)
 
 	ctx := context.Background()
-	if *dryRun {
+	if settings.dryRun {
 		ctx = context.WithValue(ctx, module.DryRunKey{}, true)
 		log.Infof("Dry run mode enabled.")
 	}
+	if settings.noCache {
+		ctx = context.WithValue(ctx, gxo.NoCacheKey{}, true)
+	}
 
 	internalEngine, err := engine.NewEngine(log, engineOpts...)
 	if err != nil {
@@ -217,13 +652,6 @@ func runExecuteCommand(args []string) int {
 	}
 	var gxoEngine gxo.EngineV1 = internalEngine
 
-	log.Infof("Loading playbook: %s", *playbookPath)
-	playbookBytes, err := os.ReadFile(*playbookPath)
-	if err != nil {
-		log.Errorf("Failed to read playbook file '%s': %v", *playbookPath, err)
-		return ExitFailure
-	}
-
 	runCtx, cancelRun := context.WithCancel(ctx)
 	defer cancelRun()
 
@@ -254,8 +682,26 @@ func runExecuteCommand(args []string) int {
 	}()
 	defer wg.Wait()
 
-	log.Infof("Starting playbook execution...")
-	report, execErr := gxoEngine.RunPlaybook(runCtx, playbookBytes)
+	var report *gxo.ExecutionReport
+	handle, execErr := start(runCtx, gxoEngine)
+	if execErr == nil {
+		var server *control.Server
+		if cfg.ControlDir != "" {
+			server, err = control.Listen(cfg.ControlDir, handle, log)
+			if err != nil {
+				log.Warnf("Run control is unavailable: %v", err)
+			} else {
+				go server.Serve()
+				log.Infof("Control this run with: %s control -run-id %s -control-dir %s <pause|resume|status|cancel-task>", os.Args[0], handle.RunID(), cfg.ControlDir)
+			}
+		}
+		report, execErr = handle.Wait()
+		if server != nil {
+			if closeErr := server.Close(); closeErr != nil {
+				log.Warnf("Failed to close control socket: %v", closeErr)
+			}
+		}
+	}
 
 	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancelShutdown()
@@ -264,6 +710,21 @@ func runExecuteCommand(args []string) int {
 	}
 
 	printReportSummary(log, report, execErr)
+	if report != nil && len(report.Outputs) > 0 {
+		if err := printOutputs(os.Stdout, report.Outputs); err != nil {
+			log.Errorf("Failed to write playbook outputs: %v", err)
+		}
+	}
+	if settings.reportFile != "" {
+		if err := writeReportFile(settings.reportFile, settings.reportFormat, report); err != nil {
+			log.Errorf("Failed to write the execution report: %v", err)
+		} else {
+			log.Infof("Execution report written to %s (%s).", settings.reportFile, settings.reportFormat)
+		}
+	}
+	if report != nil && report.RunID != "" && cfg.CheckpointDir != "" && (report.OverallStatus == "Failed" || execErr != nil) {
+		log.Infof("Resume this run with: %s resume -run-id %s -checkpoint-dir %s", os.Args[0], report.RunID, cfg.CheckpointDir)
+	}
 
 	sigMu.Lock()
 	finalSignal := receivedSignal
@@ -272,6 +733,32 @@ func runExecuteCommand(args []string) int {
 	return exitCode
 }
 
+// writeReportFile writes the execution report to path in the given format.
+// Without a report, as when the run failed before it started, no file is
+// written.
+func writeReportFile(path, format string, report *gxo.ExecutionReport) error {
+	if report == nil {
+		return errors.New("no report was generated")
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := reportexport.Write(file, report, format); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// printOutputs writes the playbook's outputs to w as a JSON object, so
+// pipelines can consume them directly. Logs go to stderr and do not mix in.
+func printOutputs(w io.Writer, outputs map[string]interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(outputs)
+}
+
 func printReportSummary(log gxolog.Logger, report *gxo.ExecutionReport, execErr error) {
 	if report == nil {
 		log.Warnf("Execution finished but no report was generated (likely due to early failure).")
@@ -281,11 +768,14 @@ func printReportSummary(log gxolog.Logger, report *gxo.ExecutionReport, execErr
 		return
 	}
 
-	statusLine := fmt.Sprintf("Playbook '%s' finished. Status: %s", report.PlaybookName, report.OverallStatus)
+	statusLine := fmt.Sprintf("Playbook '%s' (run %s) finished. Status: %s", report.PlaybookName, report.RunID, report.OverallStatus)
 	duration := report.Duration.Truncate(time.Millisecond)
-	summaryLine := fmt.Sprintf("Duration: %v. Tasks: Total=%d, Completed=%d, Failed=%d, Skipped=%d",
+	summaryLine := fmt.Sprintf("Duration: %v. Tasks: Total=%d, Completed=%d, Failed=%d, Skipped=%d, Changed=%d",
 		duration,
-		report.TotalTasks, report.CompletedTasks, report.FailedTasks, report.SkippedTasks)
+		report.TotalTasks, report.CompletedTasks, report.FailedTasks, report.SkippedTasks, report.ChangedTasks)
+	if report.CacheHits > 0 || report.CacheMisses > 0 {
+		summaryLine += fmt.Sprintf(". Cache: Hits=%d, Misses=%d", report.CacheHits, report.CacheMisses)
+	}
 
 	if report.OverallStatus == "Failed" || execErr != nil {
 		log.Errorf("%s. %s", statusLine, summaryLine)
@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/gxo-labs/gxo/internal/util"
+	gxocache "github.com/gxo-labs/gxo/pkg/gxo/v1/cache"
+)
+
+// keyRegex restricts keys to hex-encoded SHA-256 hashes, which are safe to use
+// as file names.
+var keyRegex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// FileStore implements the cache Store interface as a content-addressed
+// directory: the entry for a key is written to <dir>/<first two hex digits of
+// the key>/<key>.json. Writes go to a temporary file that is renamed into
+// place, so concurrent writers and crashes never leave a partial entry behind.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a FileStore rooted at dir. The directory is created
+// lazily on the first Put.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// Dir returns the directory cache entries are stored in.
+func (s *FileStore) Dir() string {
+	return s.dir
+}
+
+// Put atomically writes the entry to its file. The file is created with
+// owner-only permissions because summaries may contain sensitive output.
+func (s *FileStore) Put(entry *gxocache.Entry) error {
+	if entry == nil {
+		return fmt.Errorf("cache entry cannot be nil")
+	}
+	path, err := s.pathFor(entry.Key)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry '%s': %w", entry.Key, err)
+	}
+
+	entryDir := filepath.Dir(path)
+	if err := os.MkdirAll(entryDir, 0o700); err != nil {
+		return fmt.Errorf("failed to create cache directory '%s': %w", entryDir, err)
+	}
+	tmp, err := os.CreateTemp(entryDir, entry.Key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary cache file: %w", err)
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to write cache entry '%s': %w", entry.Key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to write cache entry '%s': %w", entry.Key, err)
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to commit cache entry '%s': %w", entry.Key, err)
+	}
+	return nil
+}
+
+// Get reads the entry for key. It returns gxocache.ErrNotFound if no entry
+// exists for that key.
+func (s *FileStore) Get(key string) (*gxocache.Entry, error) {
+	path, err := s.pathFor(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("key '%s' in '%s': %w", key, s.dir, gxocache.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to read cache entry '%s': %w", key, err)
+	}
+
+	// Decode numbers as json.Number so integers survive the round trip as ints
+	// rather than turning into float64, which would change template comparisons.
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var entry gxocache.Entry
+	if err := decoder.Decode(&entry); err != nil {
+		return nil, fmt.Errorf("failed to decode cache entry '%s': %w", key, err)
+	}
+	entry.Summary = util.NormalizeJSONNumbers(entry.Summary)
+	return &entry, nil
+}
+
+func (s *FileStore) pathFor(key string) (string, error) {
+	if !keyRegex.MatchString(key) {
+		return "", fmt.Errorf("invalid cache key '%s' (expected a hex-encoded SHA-256 hash)", key)
+	}
+	return filepath.Join(s.dir, key[:2], key+".json"), nil
+}
+
+// Ensure FileStore implements the public cache Store interface.
+var _ gxocache.Store = (*FileStore)(nil)
@@ -0,0 +1,62 @@
+package cache_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gxo-labs/gxo/internal/cache"
+	gxocache "github.com/gxo-labs/gxo/pkg/gxo/v1/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// TestFileStore_PutGetRoundTrip verifies that an entry is stored under its
+// key's prefix directory and loaded back unchanged, integers included.
+func TestFileStore_PutGetRoundTrip(t *testing.T) {
+	store := cache.NewFileStore(filepath.Join(t.TempDir(), "cache"))
+	created := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	key := testKey("roundtrip")
+	entry := &gxocache.Entry{
+		Key:        key,
+		TaskID:     "build",
+		ModuleType: "exec",
+		Summary:    map[string]interface{}{"exit_code": 0, "artifacts": []interface{}{"a.tar", 2}},
+		CreatedAt:  created,
+	}
+	require.NoError(t, store.Put(entry))
+
+	info, err := os.Stat(filepath.Join(store.Dir(), key[:2], key+".json"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "Cache files must be owner-only")
+
+	loaded, err := store.Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, entry.Summary, loaded.Summary)
+	assert.Equal(t, "build", loaded.TaskID)
+	assert.Equal(t, "exec", loaded.ModuleType)
+	assert.True(t, created.Equal(loaded.CreatedAt))
+}
+
+// TestFileStore_GetErrors verifies missing entries and unsafe keys are rejected.
+func TestFileStore_GetErrors(t *testing.T) {
+	store := cache.NewFileStore(t.TempDir())
+
+	_, err := store.Get(testKey("missing"))
+	assert.True(t, errors.Is(err, gxocache.ErrNotFound), "Expected ErrNotFound, got: %v", err)
+
+	_, err = store.Get("../escape")
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, gxocache.ErrNotFound))
+
+	assert.Error(t, store.Put(&gxocache.Entry{Key: "not-a-hash"}))
+}
@@ -0,0 +1,119 @@
+package checkpoint
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/gxo-labs/gxo/internal/util"
+	gxocheckpoint "github.com/gxo-labs/gxo/pkg/gxo/v1/checkpoint"
+)
+
+// runIDRegex restricts run IDs to characters that are safe to use as file names.
+var runIDRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// FileStore implements the checkpoint Store interface by writing one JSON file
+// per run into a directory. Writes go to a temporary file that is renamed into
+// place, so a crash never leaves a partially written checkpoint behind.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileStore creates a FileStore rooted at dir. The directory is created
+// lazily on the first Save.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// Dir returns the directory checkpoints are stored in.
+func (s *FileStore) Dir() string {
+	return s.dir
+}
+
+// Save atomically writes the checkpoint to <dir>/<run-id>.json. The file is
+// created with owner-only permissions because it contains playbook variables.
+func (s *FileStore) Save(cp *gxocheckpoint.Checkpoint) error {
+	if cp == nil {
+		return fmt.Errorf("checkpoint cannot be nil")
+	}
+	path, err := s.pathFor(cp.RunID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint for run '%s': %w", cp.RunID, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory '%s': %w", s.dir, err)
+	}
+	tmp, err := os.CreateTemp(s.dir, cp.RunID+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary checkpoint file: %w", err)
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to write checkpoint for run '%s': %w", cp.RunID, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to write checkpoint for run '%s': %w", cp.RunID, err)
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to commit checkpoint for run '%s': %w", cp.RunID, err)
+	}
+	return nil
+}
+
+// Load reads the checkpoint for runID. It returns gxocheckpoint.ErrNotFound if
+// no checkpoint file exists for that run.
+func (s *FileStore) Load(runID string) (*gxocheckpoint.Checkpoint, error) {
+	path, err := s.pathFor(runID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("run '%s' in '%s': %w", runID, s.dir, gxocheckpoint.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to read checkpoint for run '%s': %w", runID, err)
+	}
+
+	// Decode numbers as json.Number so integers survive the round trip as ints
+	// rather than turning into float64, which would change template comparisons.
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var cp gxocheckpoint.Checkpoint
+	if err := decoder.Decode(&cp); err != nil {
+		return nil, fmt.Errorf("failed to decode checkpoint for run '%s': %w", runID, err)
+	}
+	cp.Vars = util.NormalizeJSONNumbers(cp.Vars).(map[string]interface{})
+	cp.Registered = util.NormalizeJSONNumbers(cp.Registered).(map[string]interface{})
+	return &cp, nil
+}
+
+func (s *FileStore) pathFor(runID string) (string, error) {
+	if !runIDRegex.MatchString(runID) {
+		return "", fmt.Errorf("invalid run ID '%s' (allowed: alphanumeric, underscore, hyphen)", runID)
+	}
+	return filepath.Join(s.dir, runID+".json"), nil
+}
+
+// Ensure FileStore implements the public checkpoint Store interface.
+var _ gxocheckpoint.Store = (*FileStore)(nil)
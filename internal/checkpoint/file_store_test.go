@@ -0,0 +1,55 @@
+package checkpoint_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gxo-labs/gxo/internal/checkpoint"
+	gxocheckpoint "github.com/gxo-labs/gxo/pkg/gxo/v1/checkpoint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFileStore_SaveLoadRoundTrip verifies that a saved checkpoint is loaded
+// back unchanged, including integer values inside vars and registered data.
+func TestFileStore_SaveLoadRoundTrip(t *testing.T) {
+	store := checkpoint.NewFileStore(filepath.Join(t.TempDir(), "checkpoints"))
+	created := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	cp := &gxocheckpoint.Checkpoint{
+		RunID:        "run-1",
+		PlaybookName: "roundtrip",
+		PlaybookYAML: "name: roundtrip",
+		Vars:         map[string]interface{}{"count": 3, "ratio": 0.5},
+		Registered:   map[string]interface{}{"out": map[string]interface{}{"items": []interface{}{1, "two"}}},
+		TaskStatuses: map[string]string{"task_a": "Completed"},
+		CreatedAt:    created,
+		UpdatedAt:    created,
+	}
+	require.NoError(t, store.Save(cp))
+
+	info, err := os.Stat(filepath.Join(store.Dir(), "run-1.json"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "Checkpoint files must be owner-only")
+
+	loaded, err := store.Load("run-1")
+	require.NoError(t, err)
+	assert.Equal(t, cp.Vars, loaded.Vars)
+	assert.Equal(t, cp.Registered, loaded.Registered)
+	assert.Equal(t, cp.TaskStatuses, loaded.TaskStatuses)
+	assert.True(t, created.Equal(loaded.CreatedAt))
+}
+
+// TestFileStore_LoadErrors verifies missing runs and unsafe run IDs are rejected.
+func TestFileStore_LoadErrors(t *testing.T) {
+	store := checkpoint.NewFileStore(t.TempDir())
+
+	_, err := store.Load("does-not-exist")
+	assert.True(t, errors.Is(err, gxocheckpoint.ErrNotFound), "Expected ErrNotFound, got: %v", err)
+
+	_, err = store.Load("../escape")
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, gxocheckpoint.ErrNotFound))
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"strings"
 	"time"
 )
 
@@ -12,6 +13,34 @@ const (
 	OverflowError      = "error"
 )
 
+// Names of the task sections a playbook can declare.
+const (
+	SectionTasks     = "tasks"
+	SectionOnFailure = "on_failure"
+	SectionFinally   = "finally"
+)
+
+// Trigger rules deciding whether a task runs once the tasks it depends on
+// have finished.
+const (
+	TriggerRuleAllSuccess = "all_success" // All dependencies completed.
+	TriggerRuleAllDone    = "all_done"    // All dependencies finished, whatever their status.
+	TriggerRuleOneFailed  = "one_failed"  // At least one dependency failed.
+	TriggerRuleOneSuccess = "one_success" // At least one dependency completed.
+	TriggerRuleNoneFailed = "none_failed" // No dependency failed; skipped ones are fine. The default.
+)
+
+// IncludePlaybookType is the built-in task type that runs another playbook file
+// as a nested DAG. Its params are 'path', the file to include, and 'vars', the
+// variables passed to it.
+const IncludePlaybookType = "include_playbook"
+
+// Params of an include_playbook task.
+const (
+	IncludeParamPath = "path"
+	IncludeParamVars = "vars"
+)
+
 // Playbook represents the top-level structure of a GXO playbook YAML file.
 type Playbook struct {
 	Name          string                 `yaml:"name"`
@@ -25,9 +54,37 @@ type Playbook struct {
 	// will apply to all tasks in the playbook unless overridden by a task's
 	// own state_policy block. Optional.
 	StatePolicy *StatePolicy `yaml:"state_policy,omitempty"`
+	// OnFailure lists tasks that run after the main tasks have finished, only if
+	// the run failed or was cancelled. They run before any 'finally' tasks. Optional.
+	OnFailure []Task `yaml:"on_failure,omitempty"`
+	// Finally lists tasks that always run after the main tasks (and any
+	// 'on_failure' tasks) have finished, regardless of the outcome. Optional.
+	Finally []Task `yaml:"finally,omitempty"`
+	// Policies defines named bundles of policies that tasks reference with
+	// 'policy_ref'. Optional.
+	Policies map[string]NamedPolicy `yaml:"policies,omitempty"`
+	// Inputs declares the values the playbook expects to be given when it is
+	// run. They are checked before the run starts and are available to
+	// templates like 'vars'. Optional.
+	Inputs map[string]Input `yaml:"inputs,omitempty"`
+	// Outputs maps output names to templates rendered against the playbook's
+	// state once it has finished. They are exported in the execution report;
+	// when the playbook is included by another, they are the include task's
+	// result. Optional.
+	Outputs map[string]string `yaml:"outputs,omitempty"`
+	// Environment holds the environment variables passed to the modules of
+	// all tasks, underneath those of blocks and tasks. Optional.
+	Environment map[string]string `yaml:"environment,omitempty"`
+	// Imports lists files defining reusable roles, resolved relative to the
+	// playbook. Their roles are expanded into the task lists when the playbook
+	// is loaded. Optional.
+	Imports []string `yaml:"imports,omitempty"`
 	// FilePath is an internal field for storing the source file path for context
 	// in logging and error messages. It is not parsed from the YAML.
 	FilePath string `yaml:"-"`
+
+	// positions locates the playbook's fields in their source files.
+	positions positionIndex
 }
 
 // Task represents a single unit of work within a playbook.
@@ -37,24 +94,113 @@ type Task struct {
 	Params         map[string]interface{} `yaml:"params,omitempty"`
 	Register       string                 `yaml:"register,omitempty"`
 	StreamInputs   []string               `yaml:"stream_inputs,omitempty"`
+	DependsOn      []string               `yaml:"depends_on,omitempty"`
 	IgnoreErrors   bool                   `yaml:"ignore_errors,omitempty"`
 	When           string                 `yaml:"when,omitempty"`
 	Loop           interface{}            `yaml:"loop,omitempty"`
+	LoopMatrix     *LoopMatrix            `yaml:"loop_matrix,omitempty"` // Alternative to 'loop' iterating over combinations.
 	LoopControl    *LoopControlConfig     `yaml:"loop_control,omitempty"`
 	Retry          *RetryConfig           `yaml:"retry,omitempty"`
+	Until          string                 `yaml:"until,omitempty"` // Condition polled against each attempt's summary, as '.result'.
+	UntilControl   *UntilControlConfig    `yaml:"until_control,omitempty"`
+	FailedWhen     string                 `yaml:"failed_when,omitempty"`  // Condition on the summary, as '.result', deciding whether the task failed.
+	ChangedWhen    string                 `yaml:"changed_when,omitempty"` // Condition on the summary, as '.result', deciding whether the task changed something.
+	Cache          *CacheConfig           `yaml:"cache,omitempty"`        // Reuses the summary of an earlier execution with the same inputs.
 	Timeout        string                 `yaml:"timeout,omitempty"`
-	PolicyRef      string                 `yaml:"policy_ref,omitempty"` // Reserved for future use.
+	PolicyRef      string                 `yaml:"policy_ref,omitempty"` // Name of an entry of the playbook's 'policies'.
 	Policy         *TaskPolicy            `yaml:"policy,omitempty"`
+	// TriggerRule decides whether the task runs, given the statuses of the
+	// tasks it depends on. Defaults to "none_failed". Optional.
+	TriggerRule string `yaml:"trigger_rule,omitempty"`
+	// Tags label the task for selecting part of a playbook to run. The
+	// members of a block also have the block's tags. Optional.
+	Tags []string `yaml:"tags,omitempty"`
+	// Vars holds variables visible only to the task's templates, on top of
+	// the playbook's state. They are rendered against the state, and for
+	// each iteration of a loop once its loop variable is bound; 'when' and
+	// 'loop' only see the vars that do not refer to the loop variable. The
+	// members of a block also have the block's vars. Optional.
+	Vars map[string]interface{} `yaml:"vars,omitempty"`
+	// Environment holds environment variables passed to the task's module,
+	// on top of those of the enclosing blocks and the playbook. Values are
+	// templates rendered for each loop iteration. Optional.
+	Environment map[string]string `yaml:"environment,omitempty"`
 
 	// StatePolicy defines a task-specific state access policy, overriding any
 	// global state_policy defined at the playbook level. Optional.
 	StatePolicy *StatePolicy `yaml:"state_policy,omitempty"`
+
+	// Block turns the task into a group of child tasks instead of a module
+	// invocation. The children inherit the group's 'when', 'retry', 'timeout',
+	// 'ignore_errors', 'policy', 'state_policy' and 'policy_ref' directives.
+	// Optional.
+	Block []Task `yaml:"block,omitempty"`
+	// Rescue lists tasks that run only if a task in 'block' failed. If they all
+	// succeed, the failure is considered handled. Requires 'block'.
+	Rescue []Task `yaml:"rescue,omitempty"`
+	// Always lists tasks that run after 'block' and 'rescue' have finished,
+	// regardless of their outcome. Requires 'block'.
+	Always []Task `yaml:"always,omitempty"`
+
 	// InternalID is a unique identifier assigned by the engine during loading.
 	// It is used for all internal referencing (e.g., in the DAG).
 	InternalID string `yaml:"-"`
+	// InheritedWhen holds the 'when' conditions of the enclosing blocks,
+	// outermost first. It is populated when blocks are flattened into the DAG.
+	InheritedWhen []string `yaml:"-"`
+	// Origin describes where a task expanded from a role was defined, e.g.
+	// "roles/deploy.yaml:12 (role 'deploy' used at playbook.yaml:8)". It is
+	// empty for tasks written in the playbook itself.
+	Origin string `yaml:"-"`
+}
+
+// IsBlock reports whether the task is a block grouping child tasks.
+func (t *Task) IsBlock() bool {
+	return len(t.Block) > 0
+}
+
+// HasLoop reports whether the task runs once per item of a 'loop' or
+// combination of a 'loop_matrix'.
+func (t *Task) HasLoop() bool {
+	return t.Loop != nil || t.LoopMatrix != nil
 }
 
-// LoopControlConfig specifies how loops defined by the 'loop' directive are executed.
+// IsInclude reports whether the task includes another playbook.
+func (t *Task) IsInclude() bool {
+	return t.Type == IncludePlaybookType
+}
+
+// WhenConditions returns all conditions that must hold for the task to run:
+// the inherited conditions of enclosing blocks followed by its own 'when'.
+func (t *Task) WhenConditions() []string {
+	conditions := make([]string, 0, len(t.InheritedWhen)+1)
+	for _, condition := range t.InheritedWhen {
+		if condition != "" {
+			conditions = append(conditions, condition)
+		}
+	}
+	if t.When != "" {
+		conditions = append(conditions, t.When)
+	}
+	return conditions
+}
+
+// FlattenTasks returns pointers to the given tasks and, depth-first, to all
+// tasks nested in their 'block', 'rescue' and 'always' lists.
+func FlattenTasks(tasks []Task) []*Task {
+	var flat []*Task
+	for i := range tasks {
+		task := &tasks[i]
+		flat = append(flat, task)
+		flat = append(flat, FlattenTasks(task.Block)...)
+		flat = append(flat, FlattenTasks(task.Rescue)...)
+		flat = append(flat, FlattenTasks(task.Always)...)
+	}
+	return flat
+}
+
+// LoopControlConfig specifies how loops defined by the 'loop' or 'loop_matrix'
+// directive are executed.
 type LoopControlConfig struct {
 	Parallel int    `yaml:"parallel,omitempty"`
 	LoopVar  string `yaml:"loop_var,omitempty"`
@@ -70,6 +216,35 @@ type RetryConfig struct {
 	OnError       *bool    `yaml:"on_error,omitempty"`
 }
 
+// UntilControlConfig specifies how a task with an 'until' condition is polled.
+type UntilControlConfig struct {
+	Delay       string `yaml:"delay,omitempty"`        // Wait between attempts.
+	MaxAttempts int    `yaml:"max_attempts,omitempty"` // Attempts before giving up.
+	Timeout     string `yaml:"timeout,omitempty"`      // Deadline for all the attempts.
+}
+
+// CacheConfig specifies how the summary of a task is cached across runs. An
+// execution is identified by the task's module type, its rendered params, its
+// rendered key and the contents of its input files. An execution restored
+// from the cache did not change anything, whatever its summary or
+// 'changed_when' condition says.
+type CacheConfig struct {
+	Key         string   `yaml:"key,omitempty"`          // Template adding to the identity of an execution.
+	TTL         string   `yaml:"ttl,omitempty"`          // Age after which an entry is stale; never if unset.
+	InputsFiles []string `yaml:"inputs_files,omitempty"` // Files whose contents are hashed, relative to the playbook.
+}
+
+// Templates returns the key and the input file paths that are templates.
+func (c *CacheConfig) Templates() []string {
+	var templates []string
+	for _, value := range append([]string{c.Key}, c.InputsFiles...) {
+		if strings.Contains(value, "{{") {
+			templates = append(templates, value)
+		}
+	}
+	return templates
+}
+
 // ChannelPolicy defines policies for data channels used for streaming between tasks.
 type ChannelPolicy struct {
 	Name             string `yaml:"-" json:"-"`
@@ -83,6 +258,21 @@ type TaskPolicy struct {
 	SkipOnNoInput *bool  `yaml:"skip_on_no_input,omitempty" json:"skip_on_no_input,omitempty"`
 }
 
+// SectionTasks returns the task list of the named section, or nil if the
+// section name is unknown.
+func (p *Playbook) SectionTasks(section string) []Task {
+	switch section {
+	case SectionTasks:
+		return p.Tasks
+	case SectionOnFailure:
+		return p.OnFailure
+	case SectionFinally:
+		return p.Finally
+	default:
+		return nil
+	}
+}
+
 // GetLoopParallel returns the configured loop parallelism or the default (1).
 func (t *Task) GetLoopParallel() int {
 	if t.LoopControl != nil && t.LoopControl.Parallel > 1 {
@@ -164,6 +354,56 @@ func (t *Task) ShouldRetryOnError() bool {
 	return true
 }
 
+// GetUntilDelay returns the configured wait between 'until' attempts or the default (5 seconds).
+func (t *Task) GetUntilDelay() time.Duration {
+	if t.UntilControl != nil && t.UntilControl.Delay != "" {
+		if duration, err := time.ParseDuration(t.UntilControl.Delay); err == nil && duration >= 0 {
+			return duration
+		}
+	}
+	return 5 * time.Second
+}
+
+// GetUntilMaxAttempts returns the configured number of 'until' attempts or the default (3).
+func (t *Task) GetUntilMaxAttempts() int {
+	if t.UntilControl != nil && t.UntilControl.MaxAttempts >= 1 {
+		return t.UntilControl.MaxAttempts
+	}
+	return 3
+}
+
+// GetUntilTimeout returns the configured deadline for all 'until' attempts, or 0 if unset/invalid.
+func (t *Task) GetUntilTimeout() time.Duration {
+	if t.UntilControl == nil || t.UntilControl.Timeout == "" {
+		return 0
+	}
+	duration, err := time.ParseDuration(t.UntilControl.Timeout)
+	if err != nil || duration < 0 {
+		return 0
+	}
+	return duration
+}
+
+// GetCacheTTL returns the configured age after which a cache entry is stale, or 0 if entries never expire.
+func (t *Task) GetCacheTTL() time.Duration {
+	if t.Cache == nil || t.Cache.TTL == "" {
+		return 0
+	}
+	duration, err := time.ParseDuration(t.Cache.TTL)
+	if err != nil || duration < 0 {
+		return 0
+	}
+	return duration
+}
+
+// GetTriggerRule returns the configured trigger rule or the default ("none_failed").
+func (t *Task) GetTriggerRule() string {
+	if t.TriggerRule != "" {
+		return t.TriggerRule
+	}
+	return TriggerRuleNoneFailed
+}
+
 // GetTimeout returns the configured task-specific timeout duration, or 0 if unset/invalid.
 func (t *Task) GetTimeout() time.Duration {
 	if t.Timeout == "" {
@@ -174,4 +414,4 @@ func (t *Task) GetTimeout() time.Duration {
 		return 0
 	}
 	return duration
-}
\ No newline at end of file
+}
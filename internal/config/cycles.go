@@ -0,0 +1,323 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gxo-labs/gxo/internal/template"
+)
+
+// Kinds of dependency edges between tasks.
+const (
+	// EdgeState orders a task after another whose status or registered result
+	// it reads, or which it lists in 'depends_on'.
+	EdgeState = "state"
+	// EdgeStream connects a producer to a consumer listing it in 'stream_inputs'.
+	EdgeStream = "stream"
+	// EdgeBlock orders the parts of a block: rescue after body, always after
+	// both, and the block itself after all of its members.
+	EdgeBlock = "block"
+)
+
+// DependencyEdge is a dependency of task To on task From.
+type DependencyEdge struct {
+	From string
+	To   string
+	Kind string
+}
+
+// DependencyEdges returns the dependencies between the tasks of a playbook
+// section, including the members of blocks, identified by their InternalID.
+// Dependencies on tasks of earlier sections are not edges, as those tasks
+// have finished before the section starts.
+func DependencyEdges(p *Playbook, section string) []DependencyEdge {
+	tasks := FlattenTasks(p.SectionTasks(section))
+	nameToID := make(map[string]string)
+	registerToID := make(map[string]string)
+	for _, task := range tasks {
+		if task.Name != "" {
+			nameToID[task.Name] = task.InternalID
+		}
+		if task.Register != "" {
+			registerToID[task.Register] = task.InternalID
+		}
+	}
+
+	var edges []DependencyEdge
+	renderer := template.NewGoRenderer(nil, nil, nil)
+	for _, task := range tasks {
+		edges = append(edges, TaskDependencies(task, nameToID, registerToID, renderer)...)
+	}
+	return append(edges, blockEdges(p.SectionTasks(section))...)
+}
+
+// TaskDependencies returns the edges leading to task from the tasks it names
+// in 'stream_inputs' or 'depends_on', and from those whose status or
+// registered result its templates read. Tasks are resolved by name through
+// nameToID and by registered variable through registerToID; references that
+// resolve to no task, or to task itself, are left out.
+func TaskDependencies(task *Task, nameToID, registerToID map[string]string, renderer template.Renderer) []DependencyEdge {
+	var edges []DependencyEdge
+	addEdge := func(from string, exists bool, kind string) {
+		if exists && from != "" && from != task.InternalID {
+			edges = append(edges, DependencyEdge{From: from, To: task.InternalID, Kind: kind})
+		}
+	}
+	for _, producer := range task.StreamInputs {
+		from, exists := nameToID[producer]
+		addEdge(from, exists, EdgeStream)
+	}
+	for _, dependency := range task.DependsOn {
+		from, exists := nameToID[dependency]
+		addEdge(from, exists, EdgeState)
+	}
+	for _, tmplStr := range collectTemplatesToScan(task) {
+		if tmplStr == "" {
+			continue
+		}
+		vars, err := renderer.ExtractVariables(tmplStr)
+		if err != nil {
+			continue
+		}
+		for _, fullVarPath := range vars {
+			if strings.HasPrefix(fullVarPath, template.GxoStateKeyPrefix+".tasks.") {
+				if parts := strings.Split(fullVarPath, "."); len(parts) == 4 {
+					from, exists := nameToID[parts[2]]
+					addEdge(from, exists, EdgeState)
+				}
+			} else {
+				from, exists := registerToID[fullVarPath]
+				addEdge(from, exists, EdgeState)
+			}
+		}
+	}
+	return edges
+}
+
+// blockEdges returns the edges ordering the parts of the blocks in tasks.
+func blockEdges(tasks []Task) []DependencyEdge {
+	var edges []DependencyEdge
+	connect := func(producers, consumers []Task) {
+		for _, consumer := range consumers {
+			for _, producer := range producers {
+				edges = append(edges, DependencyEdge{From: producer.InternalID, To: consumer.InternalID, Kind: EdgeBlock})
+			}
+		}
+	}
+	for _, task := range tasks {
+		if !task.IsBlock() {
+			continue
+		}
+		members := append(append(append([]Task{}, task.Block...), task.Rescue...), task.Always...)
+		connect(task.Block, task.Rescue)
+		connect(append(append([]Task{}, task.Block...), task.Rescue...), task.Always)
+		connect(members, []Task{task})
+		edges = append(edges, blockEdges(members)...)
+	}
+	return edges
+}
+
+// maxReportedCycles bounds the number of cycles FindCycles returns, as a
+// densely connected group of tasks can contain exponentially many.
+const maxReportedCycles = 100
+
+// FindCycles returns the elementary cycles of the dependency graph, at most
+// maxReportedCycles of them, found with Johnson's algorithm within each
+// strongly connected component. Each cycle is returned as the list of edges
+// leading from its smallest task back to it. Parallel edges of different
+// kinds between two tasks are merged into one edge, whose Kind lists them all
+// joined by "+". The result is deterministic for a given set of edges.
+func FindCycles(edges []DependencyEdge) [][]DependencyEdge {
+	adjacency := make(map[string]map[string]DependencyEdge)
+	var nodes []string
+	addNode := func(id string) {
+		if _, exists := adjacency[id]; !exists {
+			adjacency[id] = make(map[string]DependencyEdge)
+			nodes = append(nodes, id)
+		}
+	}
+	for _, edge := range edges {
+		addNode(edge.From)
+		addNode(edge.To)
+		existing, exists := adjacency[edge.From][edge.To]
+		if exists {
+			edge.Kind = mergeEdgeKinds(existing.Kind, edge.Kind)
+		}
+		adjacency[edge.From][edge.To] = edge
+	}
+	sort.Strings(nodes)
+	successors := func(id string) []string {
+		next := make([]string, 0, len(adjacency[id]))
+		for to := range adjacency[id] {
+			next = append(next, to)
+		}
+		sort.Strings(next)
+		return next
+	}
+
+	// Tarjan's algorithm for strongly connected components.
+	index := 0
+	indices := make(map[string]int)
+	lowLinks := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var components [][]string
+	var strongConnect func(id string)
+	strongConnect = func(id string) {
+		indices[id] = index
+		lowLinks[id] = index
+		index++
+		stack = append(stack, id)
+		onStack[id] = true
+
+		for _, next := range successors(id) {
+			if _, visited := indices[next]; !visited {
+				strongConnect(next)
+				lowLinks[id] = min(lowLinks[id], lowLinks[next])
+			} else if onStack[next] {
+				lowLinks[id] = min(lowLinks[id], indices[next])
+			}
+		}
+
+		if lowLinks[id] == indices[id] {
+			var component []string
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == id {
+					break
+				}
+			}
+			components = append(components, component)
+		}
+	}
+	for _, id := range nodes {
+		if _, visited := indices[id]; !visited {
+			strongConnect(id)
+		}
+	}
+
+	for _, component := range components {
+		sort.Strings(component)
+	}
+	sort.Slice(components, func(i, j int) bool { return components[i][0] < components[j][0] })
+	var cycles [][]DependencyEdge
+	for _, component := range components {
+		if len(component) == 1 {
+			if _, selfLoop := adjacency[component[0]][component[0]]; !selfLoop {
+				continue
+			}
+		}
+		cycles = append(cycles, componentCycles(component, adjacency, successors, maxReportedCycles-len(cycles))...)
+		if len(cycles) >= maxReportedCycles {
+			break
+		}
+	}
+	return cycles
+}
+
+// mergeEdgeKinds combines the kinds of two parallel edges, such as "state"
+// and "stream" into "state+stream".
+func mergeEdgeKinds(a, b string) string {
+	kinds := strings.Split(a, "+")
+	for _, kind := range strings.Split(b, "+") {
+		if !slices.Contains(kinds, kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+	sort.Strings(kinds)
+	return strings.Join(kinds, "+")
+}
+
+// componentCycles enumerates, with Johnson's algorithm, at most limit
+// elementary cycles of a strongly connected component whose tasks are sorted.
+// The cycles through each task are searched for among the tasks after it, so
+// that every cycle is found once, starting at its smallest task.
+func componentCycles(component []string, adjacency map[string]map[string]DependencyEdge, successors func(string) []string, limit int) [][]DependencyEdge {
+	position := make(map[string]int, len(component))
+	for i, id := range component {
+		position[id] = i
+	}
+
+	var cycles [][]DependencyEdge
+	for startIndex, start := range component {
+		allowed := func(id string) bool {
+			i, inComponent := position[id]
+			return inComponent && i >= startIndex
+		}
+		blocked := make(map[string]bool)
+		blockedBy := make(map[string]map[string]bool)
+		var unblock func(id string)
+		unblock = func(id string) {
+			blocked[id] = false
+			for waiting := range blockedBy[id] {
+				delete(blockedBy[id], waiting)
+				if blocked[waiting] {
+					unblock(waiting)
+				}
+			}
+		}
+
+		var path []string
+		var circuit func(id string) bool
+		circuit = func(id string) bool {
+			found := false
+			path = append(path, id)
+			blocked[id] = true
+			for _, next := range successors(id) {
+				if len(cycles) >= limit {
+					break
+				}
+				if !allowed(next) {
+					continue
+				}
+				if next == start {
+					cycle := make([]DependencyEdge, 0, len(path))
+					for i := 1; i < len(path); i++ {
+						cycle = append(cycle, adjacency[path[i-1]][path[i]])
+					}
+					cycles = append(cycles, append(cycle, adjacency[id][start]))
+					found = true
+				} else if !blocked[next] && circuit(next) {
+					found = true
+				}
+			}
+			if found {
+				unblock(id)
+			} else {
+				for _, next := range successors(id) {
+					if allowed(next) {
+						if blockedBy[next] == nil {
+							blockedBy[next] = make(map[string]bool)
+						}
+						blockedBy[next][id] = true
+					}
+				}
+			}
+			path = path[:len(path)-1]
+			return found
+		}
+		circuit(start)
+		if len(cycles) >= limit {
+			break
+		}
+	}
+	return cycles
+}
+
+// FormatCycle renders a cycle such as "a -[state]-> b -[stream]-> a".
+func FormatCycle(cycle []DependencyEdge) string {
+	if len(cycle) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(cycle[0].From)
+	for _, edge := range cycle {
+		sb.WriteString(fmt.Sprintf(" -[%s]-> %s", edge.Kind, edge.To))
+	}
+	return sb.String()
+}
@@ -0,0 +1,577 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	gxoerrors "github.com/gxo-labs/gxo/pkg/gxo/v1/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Keys involved in the expansion of roles.
+const (
+	importsKey = "imports"
+	rolesKey   = "roles"
+	useKey     = "use"
+	withKey    = "with"
+)
+
+// RolePrefixParam is the role parameter holding the prefix given to the names
+// of the tasks expanded from a role, e.g. to build unique 'register' keys with
+// "[[ .prefix ]]_result".
+const RolePrefixParam = "prefix"
+
+// nestedTaskListKeys are the task keys holding nested task lists.
+var nestedTaskListKeys = []string{"block", "rescue", "always"}
+
+// roleParamRegex matches a scalar made of a single role parameter reference,
+// which is replaced by the parameter's value with its YAML type preserved.
+var roleParamRegex = regexp.MustCompile(`^\[\[\s*\.([a-zA-Z_][a-zA-Z0-9_]*)\s*\]\]$`)
+
+// statusReferenceRegex matches a _gxo.tasks.<name>. reference, capturing the
+// task name.
+var statusReferenceRegex = regexp.MustCompile(`_gxo\.tasks\.([A-Za-z0-9_-]+)\.`)
+
+// role is a parameterized list of tasks defined in an imported file.
+type role struct {
+	name string
+	file string
+	node *yaml.Node
+	// params maps each parameter to its default value, or to nil if the
+	// parameter is required.
+	params map[string]*yaml.Node
+	tasks  *yaml.Node
+}
+
+// roleExpander replaces the 'use' entries of a playbook with the tasks of the
+// roles they name.
+type roleExpander struct {
+	roles     map[string]*role
+	useCounts map[string]int
+	// origins describes where each expanded task was defined, and files gives
+	// the role file it was defined in.
+	origins map[*yaml.Node]string
+	files   map[*yaml.Node]string
+}
+
+// expandRoles loads the role files listed in the playbook's 'imports' and
+// expands every '- use: <role>' entry into the role's tasks. Role files are
+// resolved against the directory of filePathHint. A role is defined as:
+//
+//	roles:
+//	  deploy_service:
+//	    params:
+//	      name:        # required
+//	      replicas: 1  # default
+//	    tasks:
+//	      - name: deploy
+//	        type: exec
+//	        params:
+//	          command: "deploy [[ .name ]] --replicas [[ .replicas ]]"
+//
+// and used as '- use: deploy_service' with 'with: {name: api}'. Parameters are
+// substituted with [[ ]] delimiters, leaving {{ }} templates to the run. Task
+// names are prefixed with the entry's 'name', or the role's name, and
+// references between the role's tasks are updated accordingly.
+//
+// It returns the expanded document, with the origin of every expanded task
+// keyed by its field path (e.g. "tasks.3" or "tasks.3.block.0") and the
+// position of every field. A playbook without imports or 'use' entries is
+// returned unchanged.
+func expandRoles(playbookYAML []byte, filePathHint string) (*playbookSource, error) {
+	source := &playbookSource{yaml: playbookYAML, positions: make(positionIndex)}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(playbookYAML, &doc); err != nil || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		// Malformed documents are reported by the regular loading steps.
+		return source, nil
+	}
+	root := doc.Content[0]
+	importsNode := mappingValue(root, importsKey)
+	if importsNode == nil && !containsUse(root) {
+		source.positions.indexPositions(&doc, "", filePathHint, nil)
+		return source, nil
+	}
+
+	expander := &roleExpander{
+		roles:     make(map[string]*role),
+		useCounts: make(map[string]int),
+		origins:   make(map[*yaml.Node]string),
+		files:     make(map[*yaml.Node]string),
+	}
+	if importsNode != nil {
+		if importsNode.Kind != yaml.SequenceNode {
+			return nil, positionError(filePathHint, importsNode, "'imports' must be a list of file paths")
+		}
+		for _, item := range importsNode.Content {
+			if item.Kind != yaml.ScalarNode || item.Value == "" {
+				return nil, positionError(filePathHint, item, "'imports' entries must be file paths")
+			}
+			importPath := item.Value
+			if !filepath.IsAbs(importPath) {
+				importPath = filepath.Join(filepath.Dir(filePathHint), importPath)
+			}
+			if err := expander.loadRoleFile(importPath, filePathHint, item); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, section := range taskSectionOrder {
+		if tasks := mappingValue(root, section); tasks != nil && tasks.Kind == yaml.SequenceNode {
+			if err := expander.expandTaskList(tasks, "", filePathHint, nil); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, gxoerrors.NewConfigError(fmt.Sprintf("failed to encode playbook '%s' after expanding roles", filePathHint), err)
+	}
+
+	source.yaml = buf.Bytes()
+	source.expanded = true
+	source.origins = make(map[string]string)
+	for _, section := range taskSectionOrder {
+		if tasks := mappingValue(root, section); tasks != nil {
+			expander.collectOrigins(tasks, section, source.origins)
+		}
+	}
+	source.positions.indexPositions(&doc, "", filePathHint, expander.files)
+	return source, nil
+}
+
+// loadRoleFile loads the roles defined in path, imported from importedBy.
+func (e *roleExpander) loadRoleFile(path, importedBy string, importNode *yaml.Node) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return positionError(importedBy, importNode, fmt.Sprintf("failed to read imported file '%s': %v", path, err))
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return yamlValidationError(err, path, true, fmt.Sprintf("failed to parse imported file '%s'", path))
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return gxoerrors.NewValidationErrorAt(gxoerrors.Position{File: path}, "", fmt.Sprintf("imported file '%s' must be a mapping with a '%s' key", path, rolesKey), nil)
+	}
+	root := doc.Content[0]
+	for i := 0; i < len(root.Content); i += 2 {
+		if key := root.Content[i]; key.Value != rolesKey {
+			return positionError(path, key, fmt.Sprintf("unsupported key '%s' in imported file (allowed: %s)", key.Value, rolesKey))
+		}
+	}
+	rolesNode := mappingValue(root, rolesKey)
+	if rolesNode == nil || rolesNode.Kind != yaml.MappingNode {
+		return positionError(path, root, fmt.Sprintf("'%s' must map role names to their definitions", rolesKey))
+	}
+
+	for i := 0; i < len(rolesNode.Content); i += 2 {
+		nameNode, defNode := rolesNode.Content[i], rolesNode.Content[i+1]
+		if !identifierRegex.MatchString(nameNode.Value) {
+			return positionError(path, nameNode, fmt.Sprintf("role name '%s' is not a valid identifier", nameNode.Value))
+		}
+		if existing, exists := e.roles[nameNode.Value]; exists {
+			return positionError(path, nameNode, fmt.Sprintf("role '%s' is already defined at %s:%d", nameNode.Value, existing.file, existing.node.Line))
+		}
+		if defNode.Kind != yaml.MappingNode {
+			return positionError(path, defNode, fmt.Sprintf("role '%s' must be a mapping with 'params' and 'tasks'", nameNode.Value))
+		}
+		r := &role{name: nameNode.Value, file: path, node: nameNode, params: make(map[string]*yaml.Node)}
+		for j := 0; j < len(defNode.Content); j += 2 {
+			key, value := defNode.Content[j], defNode.Content[j+1]
+			switch key.Value {
+			case "params":
+				if value.Kind != yaml.MappingNode {
+					return positionError(path, value, fmt.Sprintf("role '%s': 'params' must map parameter names to default values", r.name))
+				}
+				for k := 0; k < len(value.Content); k += 2 {
+					paramName, defaultValue := value.Content[k], value.Content[k+1]
+					if !identifierRegex.MatchString(paramName.Value) || paramName.Value == RolePrefixParam {
+						return positionError(path, paramName, fmt.Sprintf("role '%s': invalid parameter name '%s'", r.name, paramName.Value))
+					}
+					if defaultValue.Tag == "!!null" {
+						defaultValue = nil
+					}
+					r.params[paramName.Value] = defaultValue
+				}
+			case "tasks":
+				if value.Kind != yaml.SequenceNode || len(value.Content) == 0 {
+					return positionError(path, value, fmt.Sprintf("role '%s': 'tasks' must be a non-empty list", r.name))
+				}
+				r.tasks = value
+			default:
+				return positionError(path, key, fmt.Sprintf("role '%s': unsupported key '%s' (allowed: params, tasks)", r.name, key.Value))
+			}
+		}
+		if r.tasks == nil {
+			return positionError(path, nameNode, fmt.Sprintf("role '%s' has no 'tasks'", r.name))
+		}
+		e.roles[r.name] = r
+	}
+	return nil
+}
+
+// expandTaskList replaces the 'use' entries of a task list, and of the lists
+// nested in its blocks, with the tasks of their roles. file is the file the
+// list was defined in, and stack the roles being expanded.
+func (e *roleExpander) expandTaskList(tasks *yaml.Node, parentPrefix, file string, stack []string) error {
+	expanded := make([]*yaml.Node, 0, len(tasks.Content))
+	for _, item := range tasks.Content {
+		if item.Kind == yaml.MappingNode && mappingValue(item, useKey) != nil {
+			roleTasks, err := e.expandUse(item, parentPrefix, file, stack)
+			if err != nil {
+				return err
+			}
+			expanded = append(expanded, roleTasks...)
+			continue
+		}
+		for _, key := range nestedTaskListKeys {
+			if nested := mappingValue(item, key); nested != nil && nested.Kind == yaml.SequenceNode {
+				if err := e.expandTaskList(nested, parentPrefix, file, stack); err != nil {
+					return err
+				}
+			}
+		}
+		expanded = append(expanded, item)
+	}
+	tasks.Content = expanded
+	return nil
+}
+
+// expandUse returns the tasks of the role named by a 'use' entry.
+func (e *roleExpander) expandUse(entry *yaml.Node, parentPrefix, file string, stack []string) ([]*yaml.Node, error) {
+	var roleName, name string
+	var with *yaml.Node
+	for i := 0; i < len(entry.Content); i += 2 {
+		key, value := entry.Content[i], entry.Content[i+1]
+		switch key.Value {
+		case useKey:
+			roleName = value.Value
+		case "name":
+			name = value.Value
+		case withKey:
+			if value.Kind != yaml.MappingNode {
+				return nil, positionError(file, value, "'with' must map role parameters to values")
+			}
+			with = value
+		default:
+			return nil, positionError(file, key, fmt.Sprintf("unsupported key '%s' in 'use' entry (allowed: use, name, with)", key.Value))
+		}
+	}
+
+	r, exists := e.roles[roleName]
+	if !exists {
+		known := make([]string, 0, len(e.roles))
+		for knownName := range e.roles {
+			known = append(known, knownName)
+		}
+		sort.Strings(known)
+		if len(known) == 0 {
+			return nil, positionError(file, entry, fmt.Sprintf("unknown role '%s' (no roles are imported)", roleName))
+		}
+		return nil, positionError(file, entry, fmt.Sprintf("unknown role '%s' (imported roles: %s)", roleName, strings.Join(known, ", ")))
+	}
+	for i, active := range stack {
+		if active == roleName {
+			return nil, positionError(file, entry, fmt.Sprintf("role cycle detected: %s -> %s", strings.Join(stack[i:], " -> "), roleName))
+		}
+	}
+
+	prefix := name
+	if prefix == "" {
+		prefix = roleName
+		// Unnamed uses of the same role are numbered to keep task names unique.
+		e.useCounts[roleName]++
+		if count := e.useCounts[roleName]; count > 1 {
+			prefix = fmt.Sprintf("%s_%d", roleName, count)
+		}
+	}
+	if parentPrefix != "" {
+		prefix = parentPrefix + "_" + prefix
+	}
+
+	values := make(map[string]*yaml.Node, len(r.params)+1)
+	if with != nil {
+		for i := 0; i < len(with.Content); i += 2 {
+			key, value := with.Content[i], with.Content[i+1]
+			if _, declared := r.params[key.Value]; !declared {
+				return nil, positionError(file, key, fmt.Sprintf("role '%s' has no parameter '%s'", roleName, key.Value))
+			}
+			values[key.Value] = value
+		}
+	}
+	var missing []string
+	for param, defaultValue := range r.params {
+		if _, given := values[param]; given {
+			continue
+		}
+		if defaultValue == nil {
+			missing = append(missing, param)
+			continue
+		}
+		values[param] = defaultValue
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, positionError(file, entry, fmt.Sprintf("role '%s' requires parameter(s): %s", roleName, strings.Join(missing, ", ")))
+	}
+	values[RolePrefixParam] = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: prefix}
+
+	data := make(map[string]interface{}, len(values))
+	for param, value := range values {
+		var decoded interface{}
+		if err := value.Decode(&decoded); err != nil {
+			return nil, positionError(file, value, fmt.Sprintf("invalid value for role parameter '%s': %v", param, err))
+		}
+		data[param] = decoded
+	}
+
+	tasks := copyNode(r.tasks)
+	if err := substituteRoleParams(tasks, values, data, r.file); err != nil {
+		return nil, err
+	}
+	localNames := make(map[string]struct{})
+	collectTaskNames(tasks, localNames)
+	prefixTaskNames(tasks, prefix, localNames)
+	e.recordOrigins(tasks, r.file, fmt.Sprintf("role '%s' used at %s:%d", roleName, file, entry.Line))
+
+	if err := e.expandTaskList(tasks, prefix, r.file, append(append([]string{}, stack...), roleName)); err != nil {
+		return nil, err
+	}
+	return tasks.Content, nil
+}
+
+// substituteRoleParams replaces the [[ ]] references to role parameters in
+// every scalar of node. A scalar made of a single reference takes the
+// parameter's value, including its type; others are rendered as text.
+func substituteRoleParams(node *yaml.Node, values map[string]*yaml.Node, data map[string]interface{}, file string) error {
+	for i, child := range node.Content {
+		if child.Kind == yaml.ScalarNode && strings.Contains(child.Value, "[[") {
+			if match := roleParamRegex.FindStringSubmatch(strings.TrimSpace(child.Value)); match != nil {
+				value, exists := values[match[1]]
+				if !exists {
+					return positionError(file, child, fmt.Sprintf("unknown role parameter '%s'", match[1]))
+				}
+				replacement := copyNode(value)
+				replacement.Line, replacement.Column = child.Line, child.Column
+				node.Content[i] = replacement
+				continue
+			}
+			tmpl, err := template.New("role").Delims("[[", "]]").Option("missingkey=error").Parse(child.Value)
+			if err != nil {
+				return positionError(file, child, fmt.Sprintf("invalid role parameter reference: %v", err))
+			}
+			var rendered strings.Builder
+			if err := tmpl.Execute(&rendered, data); err != nil {
+				return positionError(file, child, fmt.Sprintf("failed to substitute role parameters: %v", err))
+			}
+			child.Value = rendered.String()
+			child.Tag = "!!str"
+			continue
+		}
+		if err := substituteRoleParams(child, values, data, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectTaskNames adds the names of the tasks of a list, and of the lists
+// nested in their blocks, to names. 'use' entries are skipped: their tasks are
+// named when they are expanded.
+func collectTaskNames(tasks *yaml.Node, names map[string]struct{}) {
+	for _, task := range tasks.Content {
+		if task.Kind != yaml.MappingNode || mappingValue(task, useKey) != nil {
+			continue
+		}
+		if name := mappingValue(task, "name"); name != nil && name.Value != "" {
+			names[name.Value] = struct{}{}
+		}
+		for _, key := range nestedTaskListKeys {
+			if nested := mappingValue(task, key); nested != nil {
+				collectTaskNames(nested, names)
+			}
+		}
+	}
+}
+
+// prefixTaskNames prefixes the names of the tasks of a list, and updates the
+// references to them in 'depends_on', 'stream_inputs' and _gxo.tasks templates.
+func prefixTaskNames(tasks *yaml.Node, prefix string, localNames map[string]struct{}) {
+	for _, task := range tasks.Content {
+		if task.Kind != yaml.MappingNode || mappingValue(task, useKey) != nil {
+			continue
+		}
+		for i := 0; i < len(task.Content); i += 2 {
+			key, value := task.Content[i], task.Content[i+1]
+			switch key.Value {
+			case "name":
+				if value.Value != "" {
+					value.Value = prefix + "_" + value.Value
+				}
+			case "depends_on", "stream_inputs":
+				for _, target := range value.Content {
+					if _, isLocal := localNames[target.Value]; isLocal {
+						target.Value = prefix + "_" + target.Value
+					}
+				}
+			case "block", "rescue", "always":
+				prefixTaskNames(value, prefix, localNames)
+			default:
+				prefixStatusReferences(value, prefix, localNames)
+			}
+		}
+	}
+}
+
+// prefixStatusReferences updates the _gxo.tasks.<name> references to the
+// role's tasks in the scalars of node.
+func prefixStatusReferences(node *yaml.Node, prefix string, localNames map[string]struct{}) {
+	if node.Kind == yaml.ScalarNode {
+		if !strings.Contains(node.Value, "_gxo.tasks.") {
+			return
+		}
+		// Every reference is rewritten once, so that a prefixed name is never
+		// prefixed again when it is also the name of one of the role's tasks.
+		node.Value = statusReferenceRegex.ReplaceAllStringFunc(node.Value, func(reference string) string {
+			name := statusReferenceRegex.FindStringSubmatch(reference)[1]
+			if _, local := localNames[name]; !local {
+				return reference
+			}
+			return "_gxo.tasks." + prefix + "_" + name + "."
+		})
+		return
+	}
+	for _, child := range node.Content {
+		prefixStatusReferences(child, prefix, localNames)
+	}
+}
+
+// recordOrigins remembers where the tasks of a list, and of the lists nested
+// in their blocks, were defined: their line in file, and the use of the role.
+func (e *roleExpander) recordOrigins(tasks *yaml.Node, file, usage string) {
+	for _, task := range tasks.Content {
+		if task.Kind != yaml.MappingNode || mappingValue(task, useKey) != nil {
+			continue
+		}
+		e.origins[task] = fmt.Sprintf("%s:%d (%s)", file, task.Line, usage)
+		e.files[task] = file
+		for _, key := range nestedTaskListKeys {
+			if nested := mappingValue(task, key); nested != nil {
+				e.recordOrigins(nested, file, usage)
+			}
+		}
+	}
+}
+
+// collectOrigins maps the field path of every expanded task in an expanded
+// task list to its origin.
+func (e *roleExpander) collectOrigins(tasks *yaml.Node, path string, origins map[string]string) {
+	for i, task := range tasks.Content {
+		taskPath := fmt.Sprintf("%s.%d", path, i)
+		if origin, expanded := e.origins[task]; expanded {
+			origins[taskPath] = origin
+		}
+		for _, key := range nestedTaskListKeys {
+			if nested := mappingValue(task, key); nested != nil {
+				e.collectOrigins(nested, taskPath+"."+key, origins)
+			}
+		}
+	}
+}
+
+// assignTaskOrigins sets the Origin of the tasks of a list, and of the lists
+// nested in their blocks, from the origins keyed by field path.
+func assignTaskOrigins(tasks []Task, path string, origins map[string]string) {
+	for i := range tasks {
+		task := &tasks[i]
+		taskPath := fmt.Sprintf("%s.%d", path, i)
+		task.Origin = origins[taskPath]
+		assignTaskOrigins(task.Block, taskPath+".block", origins)
+		assignTaskOrigins(task.Rescue, taskPath+".rescue", origins)
+		assignTaskOrigins(task.Always, taskPath+".always", origins)
+	}
+}
+
+// originOfField returns the origin of the expanded task a schema field path
+// belongs to, or "" if the field is not part of an expanded task.
+func originOfField(field string, origins map[string]string) string {
+	longest := ""
+	for path := range origins {
+		if (field == path || strings.HasPrefix(field, path+".")) && len(path) > len(longest) {
+			longest = path
+		}
+	}
+	if longest == "" {
+		return ""
+	}
+	return origins[longest]
+}
+
+// containsUse reports whether any task list of the playbook has a 'use' entry.
+func containsUse(root *yaml.Node) bool {
+	var inList func(tasks *yaml.Node) bool
+	inList = func(tasks *yaml.Node) bool {
+		if tasks == nil || tasks.Kind != yaml.SequenceNode {
+			return false
+		}
+		for _, task := range tasks.Content {
+			if task.Kind != yaml.MappingNode {
+				continue
+			}
+			if mappingValue(task, useKey) != nil {
+				return true
+			}
+			for _, key := range nestedTaskListKeys {
+				if inList(mappingValue(task, key)) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	for _, section := range taskSectionOrder {
+		if inList(mappingValue(root, section)) {
+			return true
+		}
+	}
+	return false
+}
+
+// mappingValue returns the value of key in a mapping node, or nil.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// copyNode returns a deep copy of a YAML node.
+func copyNode(node *yaml.Node) *yaml.Node {
+	copied := *node
+	if len(node.Content) > 0 {
+		copied.Content = make([]*yaml.Node, len(node.Content))
+		for i, child := range node.Content {
+			copied.Content[i] = copyNode(child)
+		}
+	}
+	return &copied
+}
+
+// positionError returns a validation error pointing to a node of a file.
+func positionError(file string, node *yaml.Node, message string) error {
+	return gxoerrors.NewValidationErrorAt(gxoerrors.Position{File: file, Line: node.Line, Column: node.Column}, "", message, nil)
+}
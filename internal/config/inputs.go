@@ -0,0 +1,256 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	gxoerrors "github.com/gxo-labs/gxo/pkg/gxo/v1/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Types an input can be declared with.
+const (
+	InputTypeString = "string"
+	InputTypeInt    = "int"
+	InputTypeBool   = "bool"
+	InputTypeList   = "list"
+	InputTypeMap    = "map"
+)
+
+// Input declares a value the playbook expects to be given when it is run.
+// Input values are available to templates like the playbook's 'vars'.
+type Input struct {
+	// Name is the key of the input in the playbook's 'inputs'.
+	Name        string        `yaml:"-" json:"name"`
+	Type        string        `yaml:"type" json:"type"`
+	Default     interface{}   `yaml:"default,omitempty" json:"default,omitempty"`
+	Required    bool          `yaml:"required,omitempty" json:"required,omitempty"`
+	Enum        []interface{} `yaml:"enum,omitempty" json:"enum,omitempty"`
+	Description string        `yaml:"description,omitempty" json:"description,omitempty"`
+}
+
+// String describes the input in one line, e.g.
+// "env (string, required): Target environment. One of: dev, prod".
+func (i Input) String() string {
+	attributes := []string{i.Type}
+	if i.Required {
+		attributes = append(attributes, "required")
+	} else if i.Default != nil {
+		attributes = append(attributes, fmt.Sprintf("default: %v", i.Default))
+	}
+	var details []string
+	if i.Description != "" {
+		details = append(details, strings.TrimSuffix(i.Description, "."))
+	}
+	if len(i.Enum) > 0 {
+		details = append(details, "One of: "+formatInputEnum(i.Enum))
+	}
+	line := fmt.Sprintf("%s (%s)", i.Name, strings.Join(attributes, ", "))
+	if len(details) > 0 {
+		line += ": " + strings.Join(details, ". ")
+	}
+	return line
+}
+
+// DeclaredInputs returns the playbook's inputs ordered by name.
+func (p *Playbook) DeclaredInputs() []Input {
+	inputs := make([]Input, 0, len(p.Inputs))
+	for _, name := range p.inputNames() {
+		input := p.Inputs[name]
+		input.Name = name
+		inputs = append(inputs, input)
+	}
+	return inputs
+}
+
+// inputNames returns the names of the playbook's inputs in order.
+func (p *Playbook) inputNames() []string {
+	names := make([]string, 0, len(p.Inputs))
+	for name := range p.Inputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveInputs checks the supplied values against the playbook's declared
+// inputs and returns the value of each input: the supplied one converted to
+// the declared type, else its default. Inputs that are neither supplied nor
+// have a default are left out. All problems are reported in one error.
+func (p *Playbook) ResolveInputs(supplied map[string]interface{}) (map[string]interface{}, error) {
+	var errs []error
+	suppliedNames := make([]string, 0, len(supplied))
+	for name := range supplied {
+		suppliedNames = append(suppliedNames, name)
+	}
+	sort.Strings(suppliedNames)
+	for _, name := range suppliedNames {
+		if _, declared := p.Inputs[name]; !declared {
+			errs = append(errs, inputError(name, fmt.Sprintf("unknown input '%s'%s", name, declaredInputsHint(p))))
+		}
+	}
+
+	values := make(map[string]interface{}, len(p.Inputs))
+	for _, name := range p.inputNames() {
+		input := p.Inputs[name]
+		value, isSupplied := supplied[name]
+		if !isSupplied {
+			if input.Required {
+				errs = append(errs, inputError(name, fmt.Sprintf("input '%s' is required but was not supplied", name)))
+			} else if input.Default != nil {
+				// Defaults are checked when the playbook is loaded.
+				values[name], _ = convertInputValue(input.Type, input.Default)
+			}
+			continue
+		}
+		converted, err := convertInputValue(input.Type, value)
+		if err != nil {
+			errs = append(errs, inputError(name, fmt.Sprintf("input '%s': %v", name, err)))
+			continue
+		}
+		if len(input.Enum) > 0 && !inputEnumContains(input, converted) {
+			errs = append(errs, inputError(name, fmt.Sprintf("input '%s': value %v is not one of: %s", name, converted, formatInputEnum(input.Enum))))
+			continue
+		}
+		values[name] = converted
+	}
+
+	if len(errs) > 0 {
+		return nil, combineValidationErrors(fmt.Sprintf("playbook '%s' has %d invalid input(s)", p.Name, len(errs)), errs)
+	}
+	return values, nil
+}
+
+// ReadInputValuesFile reads input values from a YAML file mapping input names
+// to values.
+func ReadInputValuesFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, gxoerrors.NewConfigError(fmt.Sprintf("failed to read input values file '%s'", path), err)
+	}
+	values := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, gxoerrors.NewConfigError(fmt.Sprintf("input values file '%s' must be a YAML map of input names to values", path), err)
+	}
+	return values, nil
+}
+
+// inputError returns a validation error about the value of an input.
+func inputError(name, message string) *gxoerrors.ValidationError {
+	return gxoerrors.NewValidationErrorAt(gxoerrors.Position{}, "inputs."+name, message, nil)
+}
+
+// declaredInputsHint lists the playbook's inputs for error messages.
+func declaredInputsHint(p *Playbook) string {
+	if len(p.Inputs) == 0 {
+		return " (the playbook declares no 'inputs')"
+	}
+	return fmt.Sprintf(" (declared: %s)", strings.Join(p.inputNames(), ", "))
+}
+
+// convertInputValue returns value as the given input type. Strings, such as
+// the values given on the command line, are parsed into the other types;
+// lists and maps are parsed as YAML.
+func convertInputValue(inputType string, value interface{}) (interface{}, error) {
+	text, isString := value.(string)
+	switch inputType {
+	case InputTypeString:
+		if isString {
+			return text, nil
+		}
+	case InputTypeInt:
+		if isString {
+			parsed, err := strconv.Atoi(strings.TrimSpace(text))
+			if err != nil {
+				return nil, fmt.Errorf("expected an int, got %q", text)
+			}
+			return parsed, nil
+		}
+		switch number := value.(type) {
+		case int:
+			return number, nil
+		case int64:
+			return int(number), nil
+		case float64:
+			if number == float64(int(number)) {
+				return int(number), nil
+			}
+		}
+	case InputTypeBool:
+		if isString {
+			parsed, err := strconv.ParseBool(strings.TrimSpace(text))
+			if err != nil {
+				return nil, fmt.Errorf("expected a bool, got %q", text)
+			}
+			return parsed, nil
+		}
+		if flag, ok := value.(bool); ok {
+			return flag, nil
+		}
+	case InputTypeList, InputTypeMap:
+		if isString {
+			var parsed interface{}
+			if err := yaml.Unmarshal([]byte(text), &parsed); err != nil {
+				return nil, fmt.Errorf("expected a %s, got %q: %v", inputType, text, err)
+			}
+			value = parsed
+		}
+		if converted, ok := normalizeInputCollection(inputType, value); ok {
+			return converted, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown input type '%s'", inputType)
+	}
+	return nil, fmt.Errorf("expected a %s, got %v (%T)", inputType, value, value)
+}
+
+// normalizeInputCollection returns a list as []interface{} and a map as
+// map[string]interface{}, the types templates see for values parsed from YAML.
+func normalizeInputCollection(inputType string, value interface{}) (interface{}, bool) {
+	if value == nil {
+		return nil, false
+	}
+	v := reflect.ValueOf(value)
+	switch {
+	case inputType == InputTypeList && (v.Kind() == reflect.Slice || v.Kind() == reflect.Array):
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			list[i] = v.Index(i).Interface()
+		}
+		return list, true
+	case inputType == InputTypeMap && v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		converted := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			converted[iter.Key().String()] = iter.Value().Interface()
+		}
+		return converted, true
+	default:
+		return nil, false
+	}
+}
+
+// inputEnumContains reports whether a converted value is one of the input's
+// allowed values.
+func inputEnumContains(input Input, value interface{}) bool {
+	for _, allowed := range input.Enum {
+		convertedAllowed, err := convertInputValue(input.Type, allowed)
+		if err == nil && reflect.DeepEqual(convertedAllowed, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatInputEnum lists the allowed values of an input.
+func formatInputEnum(enum []interface{}) string {
+	values := make([]string, len(enum))
+	for i, value := range enum {
+		values[i] = fmt.Sprint(value)
+	}
+	return strings.Join(values, ", ")
+}
@@ -23,55 +23,60 @@ func LoadPlaybook(playbookYAML []byte, filePathHint string) (*Playbook, error) {
 		return nil, gxoerrors.NewConfigError("playbook content cannot be empty", nil)
 	}
 
+	// Step 0: Expand the roles imported by the playbook into its task lists,
+	// and index where each field is defined so errors can point to it.
+	source, err := expandRoles(playbookYAML, filePathHint)
+	if err != nil {
+		return nil, err
+	}
+
 	// Step 1: Validate against the JSON Schema for basic structure and types.
-	if err := ValidateWithSchema(playbookYAML); err != nil {
+	if err := validateWithSchema(source, filePathHint); err != nil {
 		return nil, gxoerrors.NewConfigError(fmt.Sprintf("playbook '%s' failed schema validation", filePathHint), err)
 	}
 
 	// Step 2: Unmarshal into Go struct using strict decoding to catch unknown fields.
 	var playbook Playbook
-	if err := yamlUnmarshalStrict(playbookYAML, &playbook); err != nil {
-		return nil, gxoerrors.NewConfigError(fmt.Sprintf("failed to parse playbook YAML '%s'", filePathHint), err)
+	if err := yamlUnmarshalStrict(source.yaml, &playbook); err != nil {
+		return nil, gxoerrors.NewConfigError(fmt.Sprintf("failed to parse playbook YAML '%s'", filePathHint), yamlValidationError(err, filePathHint, !source.expanded, "invalid playbook structure"))
 	}
 	playbook.FilePath = filePathHint
+	playbook.positions = source.positions
+	for _, section := range taskSectionOrder {
+		assignTaskOrigins(playbook.SectionTasks(section), section, source.origins)
+	}
 
 	// Step 3: Check Schema Version Compatibility.
 	if playbook.SchemaVersion == "" {
-		return nil, gxoerrors.NewValidationError(fmt.Sprintf("playbook '%s' is missing required 'schemaVersion' field", filePathHint), nil)
+		return nil, playbook.validationErrorAt("schemaVersion", fmt.Sprintf("playbook '%s' is missing required 'schemaVersion' field", filePathHint))
 	}
 	playbookSemVer := playbook.SchemaVersion
 	if !strings.HasPrefix(playbookSemVer, "v") {
 		playbookSemVer = "v" + playbookSemVer
 	}
 	if !semver.IsValid(playbookSemVer) {
-		return nil, gxoerrors.NewValidationError(fmt.Sprintf("playbook '%s' has invalid 'schemaVersion' format: '%s'", filePathHint, playbook.SchemaVersion), nil)
+		return nil, playbook.validationErrorAt("schemaVersion", fmt.Sprintf("playbook '%s' has invalid 'schemaVersion' format: '%s'", filePathHint, playbook.SchemaVersion))
 	}
 
 	// Check if the major version of the playbook schema matches the engine's supported major version.
 	if semver.Major(playbookSemVer) != SupportedSchemaVersionConstraint {
-		return nil, gxoerrors.NewValidationError(
+		return nil, playbook.validationErrorAt("schemaVersion",
 			fmt.Sprintf("playbook '%s' schemaVersion '%s' is not compatible with engine requirement '%s'",
 				filePathHint, playbook.SchemaVersion, SupportedSchemaVersionConstraint),
-			nil,
 		)
 	}
 
-	// Step 4: Perform detailed logical validation on the Go struct.
+	// Step 4: Assign internal IDs to tasks. Logical validation uses them to
+	// build the dependency graph.
+	assignInternalTaskIDs(&playbook)
+
+	// Step 5: Perform detailed logical validation on the Go struct.
 	validationErrs := ValidatePlaybookStructure(&playbook)
 	if len(validationErrs) > 0 {
 		// Combine multiple validation errors into a single, clear message.
-		var errorMessages []string
-		for _, vErr := range validationErrs {
-			errorMessages = append(errorMessages, vErr.Error())
-		}
-		combinedMessage := fmt.Sprintf("playbook '%s' has %d validation error(s):\n- %s",
-			filePathHint, len(errorMessages), strings.Join(errorMessages, "\n- "))
-		return nil, gxoerrors.NewValidationError(combinedMessage, validationErrs[0])
+		return nil, combineValidationErrors(fmt.Sprintf("playbook '%s' has %d validation error(s)", filePathHint, len(validationErrs)), validationErrs)
 	}
 
-	// Step 5: Assign internal IDs to tasks after all validation has passed.
-	assignInternalTaskIDs(&playbook)
-
 	return &playbook, nil
 }
 
@@ -94,15 +99,32 @@ func LoadPlaybookFromFile(filePath string) (*Playbook, error) {
 // assignInternalTaskIDs assigns a unique InternalID to each task. This ID is used
 // for all internal engine operations, such as DAG construction and state tracking.
 // It prefers the user-defined `name` but generates a stable, index-based ID if `name` is absent.
+// Tasks in the 'on_failure' and 'finally' sections get a section-specific prefix.
 func assignInternalTaskIDs(playbook *Playbook) {
-	for i := range playbook.Tasks {
-		task := &playbook.Tasks[i]
+	for _, section := range taskSectionOrder {
+		prefix := "__task_idx_"
+		if section != SectionTasks {
+			prefix = fmt.Sprintf("__%s_idx_", section)
+		}
+		assignTaskListIDs(playbook.SectionTasks(section), prefix)
+	}
+}
+
+// assignTaskListIDs assigns IDs to a list of tasks and, recursively, to the
+// children of any blocks. Unnamed children are identified by their position
+// within the enclosing block.
+func assignTaskListIDs(tasks []Task, prefix string) {
+	for i := range tasks {
+		task := &tasks[i]
 		if task.Name != "" {
 			task.InternalID = task.Name
 		} else {
 			// Use a prefix that is guaranteed not to clash with user-defined names.
-			task.InternalID = fmt.Sprintf("__task_idx_%d", i)
+			task.InternalID = fmt.Sprintf("%s%d", prefix, i)
 		}
+		assignTaskListIDs(task.Block, task.InternalID+"__block_idx_")
+		assignTaskListIDs(task.Rescue, task.InternalID+"__rescue_idx_")
+		assignTaskListIDs(task.Always, task.InternalID+"__always_idx_")
 	}
 }
 
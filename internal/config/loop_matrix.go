@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Reserved keys of a 'loop_matrix'. Every other key is an axis.
+const (
+	LoopMatrixInclude = "include"
+	LoopMatrixExclude = "exclude"
+)
+
+// LoopMatrix runs a task once per combination of the values of its axes, like
+// a CI build matrix. Each combination is a map from axis name to value, bound
+// to the loop variable.
+type LoopMatrix struct {
+	// Axes lists the axes in the order they are written. The first axis
+	// varies slowest.
+	Axes []MatrixAxis
+	// Exclude removes the combinations matching all the keys of an entry.
+	Exclude []map[string]interface{}
+	// Include extends the combinations matching all the axis values of an
+	// entry with its other keys. An entry matching no combination is added
+	// as a combination of its own. Exclude is applied first.
+	Include []map[string]interface{}
+}
+
+// MatrixAxis is one dimension of a loop matrix.
+type MatrixAxis struct {
+	Name string
+	// Values is a list, or a template string resolving to one.
+	Values interface{}
+}
+
+// UnmarshalYAML decodes a 'loop_matrix' mapping, keeping the order of its
+// axes.
+func (m *LoopMatrix) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: 'loop_matrix' must be a map of axis names to lists of values", value.Line)
+	}
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		key, entry := value.Content[i].Value, value.Content[i+1]
+		switch key {
+		case LoopMatrixInclude:
+			if err := entry.Decode(&m.Include); err != nil {
+				return fmt.Errorf("line %d: 'loop_matrix.include' must be a list of maps: %w", entry.Line, err)
+			}
+		case LoopMatrixExclude:
+			if err := entry.Decode(&m.Exclude); err != nil {
+				return fmt.Errorf("line %d: 'loop_matrix.exclude' must be a list of maps: %w", entry.Line, err)
+			}
+		default:
+			var values interface{}
+			if err := entry.Decode(&values); err != nil {
+				return fmt.Errorf("line %d: invalid values for 'loop_matrix' axis '%s': %w", entry.Line, key, err)
+			}
+			m.Axes = append(m.Axes, MatrixAxis{Name: key, Values: values})
+		}
+	}
+	return nil
+}
+
+// AxisNames returns the names of the matrix's axes in order.
+func (m *LoopMatrix) AxisNames() []string {
+	names := make([]string, len(m.Axes))
+	for i, axis := range m.Axes {
+		names[i] = axis.Name
+	}
+	return names
+}
+
+// Templates returns the axes given as template strings.
+func (m *LoopMatrix) Templates() []string {
+	var templates []string
+	for _, axis := range m.Axes {
+		if values, ok := axis.Values.(string); ok && values != "" {
+			templates = append(templates, values)
+		}
+	}
+	return templates
+}
@@ -28,6 +28,20 @@ type StatePolicy struct {
 	AccessMode StateAccessMode `yaml:"access_mode,omitempty" json:"access_mode,omitempty"`
 }
 
+// NamedPolicy is a reusable bundle of execution policies, defined in the
+// playbook's 'policies' map and referenced by tasks with 'policy_ref'. A
+// task's own settings take precedence over those of the policy it references,
+// which take precedence over the playbook-level policies.
+type NamedPolicy struct {
+	Retry   *RetryConfig `yaml:"retry,omitempty"`
+	Timeout string       `yaml:"timeout,omitempty"`
+	// TaskPolicy corresponds to a task's 'policy' block.
+	TaskPolicy  *TaskPolicy  `yaml:"task_policy,omitempty"`
+	StatePolicy *StatePolicy `yaml:"state_policy,omitempty"`
+	// ChannelPolicy applies to the channels the task streams records to.
+	ChannelPolicy *ChannelPolicy `yaml:"channel_policy,omitempty"`
+}
+
 // StallPolicy defines the parameters for the engine's stall detection mechanism.
 // This policy is configured programmatically and not via playbook YAML.
 type StallPolicy struct {
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	gxoerrors "github.com/gxo-labs/gxo/pkg/gxo/v1/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// yamlLineRegex matches the line number yaml.v3 prefixes its messages with.
+var yamlLineRegex = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// playbookSource is a playbook document ready to be decoded, with what is
+// needed to point problems back to the files they come from.
+type playbookSource struct {
+	yaml []byte
+	// expanded reports whether roles were expanded into the document, in
+	// which case its lines no longer match the playbook file.
+	expanded bool
+	// origins describes where the tasks expanded from roles were defined,
+	// keyed by field path.
+	origins   map[string]string
+	positions positionIndex
+}
+
+// positionIndex maps the field paths of a playbook, e.g. "tasks.3.retry", to
+// where they are defined.
+type positionIndex map[string]gxoerrors.Position
+
+// indexPositions adds the positions of node and its descendants to the index.
+// Mapping entries are located at their key, list items at the item. files
+// gives the file of the nodes that were defined outside of file, such as the
+// tasks expanded from roles.
+func (index positionIndex) indexPositions(node *yaml.Node, path, file string, files map[*yaml.Node]string) {
+	if nodeFile, exists := files[node]; exists {
+		file = nodeFile
+	}
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			index.indexPositions(child, path, file, files)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			childPath := joinFieldPath(path, key.Value)
+			index[childPath] = gxoerrors.Position{File: file, Line: key.Line, Column: key.Column}
+			index.indexPositions(value, childPath, file, files)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			childPath := joinFieldPath(path, strconv.Itoa(i))
+			itemFile := file
+			if nodeFile, exists := files[item]; exists {
+				itemFile = nodeFile
+			}
+			index[childPath] = gxoerrors.Position{File: itemFile, Line: item.Line, Column: item.Column}
+			index.indexPositions(item, childPath, file, files)
+		}
+	}
+}
+
+// lookup returns the position of a field, or of its closest enclosing field
+// with a known position. It falls back to the file itself.
+func (index positionIndex) lookup(field, file string) gxoerrors.Position {
+	for path := field; path != ""; {
+		if position, exists := index[path]; exists {
+			return position
+		}
+		lastDot := strings.LastIndex(path, ".")
+		if lastDot < 0 {
+			break
+		}
+		path = path[:lastDot]
+	}
+	return gxoerrors.Position{File: file}
+}
+
+// joinFieldPath appends a key or an index to a field path.
+func joinFieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// positionOf returns where a field of the playbook, e.g. "tasks.3.retry", is
+// defined, falling back to its closest enclosing field.
+func (p *Playbook) positionOf(field string) gxoerrors.Position {
+	return p.positions.lookup(field, p.FilePath)
+}
+
+// validationErrorAt returns a validation error located at a field of the
+// playbook.
+func (p *Playbook) validationErrorAt(field, message string) *gxoerrors.ValidationError {
+	return gxoerrors.NewValidationErrorAt(p.positionOf(field), field, message, nil)
+}
+
+// yamlValidationError turns a YAML parsing error into a validation error
+// listing each problem at its line of file. withLines is false when the
+// parsed document does not have the lines of file.
+func yamlValidationError(err error, file string, withLines bool, message string) *gxoerrors.ValidationError {
+	var messages []string
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	} else {
+		messages = []string{err.Error()}
+	}
+
+	combined := gxoerrors.NewValidationErrorAt(gxoerrors.Position{File: file}, "", message, err)
+	for _, msg := range messages {
+		position := gxoerrors.Position{File: file}
+		if match := yamlLineRegex.FindStringSubmatch(strings.TrimSpace(msg)); match != nil {
+			if withLines {
+				position.Line, _ = strconv.Atoi(match[1])
+			}
+			msg = match[2]
+		}
+		combined.Errors = append(combined.Errors, gxoerrors.NewValidationErrorAt(position, "", msg, nil))
+	}
+	return combined
+}
+
+// Diagnostic is a problem found in a playbook, located in its source file.
+type Diagnostic struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// String formats the diagnostic as "file:line:col: message", the format
+// understood by editors and CI annotations.
+func (d Diagnostic) String() string {
+	position := gxoerrors.Position{File: d.File, Line: d.Line, Column: d.Column}
+	if !position.IsKnown() {
+		return d.Message
+	}
+	return fmt.Sprintf("%s: %s", position, d.Message)
+}
+
+// Diagnostics lists the individual problems reported by an error returned by
+// LoadPlaybook, ordered by position. Errors without a known position are
+// reported against filePathHint.
+func Diagnostics(err error, filePathHint string) []Diagnostic {
+	if err == nil {
+		return nil
+	}
+	var validationErr *gxoerrors.ValidationError
+	if !errors.As(err, &validationErr) {
+		return []Diagnostic{{File: filePathHint, Message: err.Error()}}
+	}
+	problems := validationErr.Errors
+	if len(problems) == 0 {
+		problems = []*gxoerrors.ValidationError{validationErr}
+	}
+	diagnostics := make([]Diagnostic, 0, len(problems))
+	for _, problem := range problems {
+		diagnostic := Diagnostic{
+			File:    problem.Position.File,
+			Line:    problem.Position.Line,
+			Column:  problem.Position.Column,
+			Field:   problem.Field,
+			Message: problem.Message,
+		}
+		if diagnostic.File == "" {
+			diagnostic.File = filePathHint
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].File != diagnostics[j].File {
+			return diagnostics[i].File < diagnostics[j].File
+		}
+		if diagnostics[i].Line != diagnostics[j].Line {
+			return diagnostics[i].Line < diagnostics[j].Line
+		}
+		return diagnostics[i].Column < diagnostics[j].Column
+	})
+	return diagnostics
+}
+
+// combineValidationErrors returns a validation error reporting several
+// problems under a summary message.
+func combineValidationErrors(message string, errs []error) *gxoerrors.ValidationError {
+	var errorMessages []string
+	var problems []*gxoerrors.ValidationError
+	for _, err := range errs {
+		errorMessages = append(errorMessages, err.Error())
+		var validationErr *gxoerrors.ValidationError
+		if !errors.As(err, &validationErr) {
+			validationErr = gxoerrors.NewValidationError(err.Error(), err)
+		}
+		problems = append(problems, validationErr)
+	}
+	combined := gxoerrors.NewValidationError(fmt.Sprintf("%s:\n- %s", message, strings.Join(errorMessages, "\n- ")), errs[0])
+	combined.Errors = problems
+	return combined
+}
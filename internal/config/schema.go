@@ -3,6 +3,7 @@ package config
 import (
 	_ "embed" // Required for //go:embed directive
 	"fmt"
+	"strings"
 	"sync"
 
 	// Import public error types used
@@ -58,6 +59,15 @@ func loadSchema() (*gojsonschema.Schema, error) {
 // ValidateWithSchema validates the given YAML document bytes against the embedded GXO v1.0.0 schema.
 // It handles YAML-to-JSON conversion required by the validator.
 func ValidateWithSchema(documentYAML []byte) error {
+	return validateWithSchema(&playbookSource{yaml: documentYAML}, "")
+}
+
+// validateWithSchema validates a playbook document against the schema. Each
+// failure is located in file using the source's position index, and failures
+// in the tasks expanded from roles mention the role file and line they come
+// from.
+func validateWithSchema(source *playbookSource, file string) error {
+	documentYAML := source.yaml
 	// Load (or retrieve cached) compiled schema.
 	schema, err := loadSchema()
 	if err != nil {
@@ -72,7 +82,7 @@ func ValidateWithSchema(documentYAML []byte) error {
 	// Note: We don't use strict unmarshalling here as we only need the structure
 	// for validation, not necessarily conforming to the specific Playbook struct fields yet.
 	if err := yaml.Unmarshal(documentYAML, &jsonData); err != nil {
-		return gxoerrors.NewConfigError("failed to parse playbook YAML for schema validation", err)
+		return gxoerrors.NewConfigError("failed to parse playbook YAML for schema validation", yamlValidationError(err, file, !source.expanded, "invalid YAML"))
 	}
 
 	// Create a document loader for the validator from the parsed Go data structure.
@@ -89,6 +99,7 @@ func ValidateWithSchema(documentYAML []byte) error {
 	if !result.Valid() {
 		// If invalid, construct a detailed error message listing validation failures.
 		errMsg := "Playbook failed JSON schema validation:"
+		var problems []*gxoerrors.ValidationError
 		// Iterate through the validation errors reported by the library.
 		for _, desc := range result.Errors() {
 			// Try to get a meaningful field path from the error context.
@@ -97,11 +108,28 @@ func ValidateWithSchema(documentYAML []byte) error {
 				// Use context path for root-level errors or when field is empty.
 				field = desc.Context().String()
 			}
+			message := fmt.Sprintf("Field '%s': %s", field, desc.Description())
+			if origin := originOfField(field, source.origins); origin != "" {
+				message += fmt.Sprintf(" (from %s)", origin)
+			}
+			fieldPath := strings.TrimPrefix(strings.TrimPrefix(field, "(root)"), ".")
+			if property, ok := desc.Details()["property"].(string); ok && desc.Type() == "additional_property_not_allowed" {
+				// Point to the unknown key itself rather than its parent.
+				fieldPath = joinFieldPath(fieldPath, property)
+			}
+			problem := gxoerrors.NewValidationErrorAt(source.positions.lookup(fieldPath, file), fieldPath, message, nil)
+			problems = append(problems, problem)
 			// Append the specific validation failure message.
-			errMsg += fmt.Sprintf("\n  - Field '%s': %s", field, desc.Description())
+			if problem.Position.IsKnown() {
+				errMsg += fmt.Sprintf("\n  - %s: %s", problem.Position, message)
+			} else {
+				errMsg += fmt.Sprintf("\n  - %s", message)
+			}
 		}
 		// Return a structured ValidationError.
-		return gxoerrors.NewValidationError(errMsg, nil)
+		combined := gxoerrors.NewValidationError(errMsg, nil)
+		combined.Errors = problems
+		return combined
 	}
 
 	// Document is valid according to the schema.
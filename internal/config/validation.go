@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,6 +18,76 @@ var identifierRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
 // Pre-compiled regex for validating task names. Allows for more readable names than standard identifiers.
 var taskNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
 
+// taskSectionOrder lists the task sections in the order the engine runs them.
+var taskSectionOrder = []string{SectionTasks, SectionOnFailure, SectionFinally}
+
+// taskReference records a reference from one task to another by name, so that
+// cross-section references can be checked once all task names are known.
+type taskReference struct {
+	from    string
+	section string
+	target  string
+	stream  bool
+	// dependsOn marks references made through 'depends_on'.
+	dependsOn bool
+	// field is the path of the field making the reference.
+	field string
+}
+
+// taskEntry pairs a task with the name used for it in validation messages
+// and its field path, e.g. "tasks.2.rescue.0".
+type taskEntry struct {
+	task        *Task
+	displayName string
+	field       string
+}
+
+// fieldOf returns the path of one of the task's fields, or of the task itself
+// if name is empty.
+func (e taskEntry) fieldOf(name string) string {
+	if name == "" {
+		return e.field
+	}
+	return joinFieldPath(e.field, name)
+}
+
+// collectTaskEntries lists the tasks and, depth-first, the children of any
+// blocks, naming nested tasks after their position, e.g.
+// "task 2 ('deploy') > rescue 0 ('rollback')". field is the path of the list.
+func collectTaskEntries(tasks []Task, kind, parentDisplayName, field string) []taskEntry {
+	var entries []taskEntry
+	for i := range tasks {
+		task := &tasks[i]
+		taskField := fmt.Sprintf("%s.%d", field, i)
+		displayName := fmt.Sprintf("%s %d", kind, i)
+		if parentDisplayName != "" {
+			displayName = fmt.Sprintf("%s > %s", parentDisplayName, displayName)
+		}
+		if task.Name != "" {
+			displayName = fmt.Sprintf("%s ('%s')", displayName, task.Name)
+		}
+		entryName := displayName
+		if task.Origin != "" {
+			entryName = fmt.Sprintf("%s from %s", displayName, task.Origin)
+		}
+		entries = append(entries, taskEntry{task: task, displayName: entryName, field: taskField})
+		entries = append(entries, collectTaskEntries(task.Block, "block", displayName, taskField+".block")...)
+		entries = append(entries, collectTaskEntries(task.Rescue, "rescue", displayName, taskField+".rescue")...)
+		entries = append(entries, collectTaskEntries(task.Always, "always", displayName, taskField+".always")...)
+	}
+	return entries
+}
+
+// sectionRank returns the position of a section in taskSectionOrder.
+func sectionRank(section string) int {
+	for i, s := range taskSectionOrder {
+		if s == section {
+			return i
+		}
+	}
+	return len(taskSectionOrder)
+}
+
 // ValidatePlaybookStructure performs a comprehensive logical validation of the parsed Playbook struct.
 // It checks for cross-field consistency, valid references, and other rules that cannot be
 // fully expressed in JSON Schema alone. It returns a slice of all validation errors found.
@@ -23,143 +95,234 @@ func ValidatePlaybookStructure(p *Playbook) []error {
 	var errs []error
 
 	if len(p.Tasks) == 0 {
-		errs = append(errs, gxoerrors.NewValidationError("playbook must contain at least one task in 'tasks' list", nil))
+		errs = append(errs, p.validationErrorAt(SectionTasks, "playbook must contain at least one task in 'tasks' list"))
 	}
 
 	// Validate global policies at the playbook level.
 	if p.ChannelPolicy != nil {
 		if p.ChannelPolicy.BufferSize != nil && *p.ChannelPolicy.BufferSize < 0 {
-			errs = append(errs, gxoerrors.NewValidationError("global channel_policy buffer_size cannot be negative", nil))
+			errs = append(errs, p.validationErrorAt("channel_policy.buffer_size", "global channel_policy buffer_size cannot be negative"))
 		}
 	}
 	if p.StatePolicy != nil {
 		if p.StatePolicy.AccessMode != "" && p.StatePolicy.AccessMode != StateAccessDeepCopy && p.StatePolicy.AccessMode != StateAccessUnsafeDirectReference {
-			errs = append(errs, gxoerrors.NewValidationError(fmt.Sprintf("global state_policy has invalid access_mode: '%s'", p.StatePolicy.AccessMode), nil))
+			errs = append(errs, p.validationErrorAt("state_policy.access_mode", fmt.Sprintf("global state_policy has invalid access_mode: '%s'", p.StatePolicy.AccessMode)))
+		}
+	}
+
+	for name := range p.Outputs {
+		if !identifierRegex.MatchString(name) {
+			errs = append(errs, p.validationErrorAt("outputs."+name, fmt.Sprintf("output name '%s' is not a valid identifier", name)))
 		}
 	}
 
-	taskNames := make(map[string]bool)
+	for _, input := range p.DeclaredInputs() {
+		errs = append(errs, validateInput(p, input)...)
+	}
+
+	errs = append(errs, validateEnvironment(p, p.Environment, "environment", "playbook")...)
+
+	policyNames := make([]string, 0, len(p.Policies))
+	for name := range p.Policies {
+		policyNames = append(policyNames, name)
+	}
+	sort.Strings(policyNames)
+	for _, name := range policyNames {
+		policy := p.Policies[name]
+		errs = append(errs, validateNamedPolicy(p, name, &policy)...)
+	}
+
+	taskSections := make(map[string]string)
+	includeTasks := make(map[string]struct{})
+	var references []taskReference
 	registeredVars := make(map[string]string)
-	requiredTaskNames := make(map[string]struct{})
+	// requiredTaskNames maps each referenced task name to the field of its
+	// first reference.
+	requiredTaskNames := make(map[string]string)
+	requireTask := func(name, field string) {
+		if _, seen := requiredTaskNames[name]; !seen {
+			requiredTaskNames[name] = field
+		}
+	}
+	// taskFields maps task IDs to their field paths.
+	taskFields := make(map[string]string)
 	// Create a dummy renderer to access the variable extraction logic.
 	// We pass nil for dependencies because they are not needed for parsing variable names.
 	dummyRenderer := template.NewGoRenderer(nil, nil, nil)
 
-	for i := range p.Tasks {
-		task := &p.Tasks[i] // Use a pointer to the task in the slice
-		taskIdx := i
-		taskDisplayName := fmt.Sprintf("task %d", taskIdx)
-		if task.Name != "" {
-			taskDisplayName = fmt.Sprintf("task %d ('%s')", taskIdx, task.Name)
+	for _, section := range taskSectionOrder {
+		displayPrefix := "task"
+		if section != SectionTasks {
+			displayPrefix = section + " task"
 		}
+		for _, entry := range collectTaskEntries(p.SectionTasks(section), displayPrefix, "", section) {
+			task := entry.task
+			taskDisplayName := entry.displayName
+			taskFields[task.InternalID] = entry.field
 
-		if task.Name != "" {
-			if !taskNameRegex.MatchString(task.Name) {
-				errs = append(errs, gxoerrors.NewValidationError(fmt.Sprintf("%s: name contains invalid characters (allowed: alphanumeric, underscore, hyphen)", taskDisplayName), nil))
+			if task.Name != "" {
+				if !taskNameRegex.MatchString(task.Name) {
+					errs = append(errs, p.validationErrorAt(entry.fieldOf("name"), fmt.Sprintf("%s: name contains invalid characters (allowed: alphanumeric, underscore, hyphen)", taskDisplayName)))
+				}
+				if _, exists := taskSections[task.Name]; exists {
+					errs = append(errs, p.validationErrorAt(entry.fieldOf("name"), fmt.Sprintf("%s: duplicate task name found", taskDisplayName)))
+				}
+				taskSections[task.Name] = section
 			}
-			if _, exists := taskNames[task.Name]; exists {
-				errs = append(errs, gxoerrors.NewValidationError(fmt.Sprintf("%s: duplicate task name found", taskDisplayName), nil))
+
+			if task.IsBlock() {
+				if task.Type != "" {
+					errs = append(errs, p.validationErrorAt(entry.fieldOf("type"), fmt.Sprintf("%s: 'type' cannot be combined with 'block'", taskDisplayName)))
+				}
+				if len(task.Params) > 0 || task.Register != "" || len(task.StreamInputs) > 0 || task.HasLoop() || task.LoopControl != nil || task.TriggerRule != "" || task.Until != "" || task.UntilControl != nil || task.FailedWhen != "" || task.ChangedWhen != "" || task.Cache != nil {
+					errs = append(errs, p.validationErrorAt(entry.field, fmt.Sprintf("%s: a block cannot use 'params', 'register', 'stream_inputs', 'loop', 'loop_matrix', 'loop_control', 'trigger_rule', 'until', 'until_control', 'failed_when', 'changed_when' or 'cache'", taskDisplayName)))
+				}
+			} else {
+				if len(task.Rescue) > 0 || len(task.Always) > 0 {
+					errs = append(errs, p.validationErrorAt(entry.field, fmt.Sprintf("%s: 'rescue' and 'always' require 'block'", taskDisplayName)))
+				}
+				if task.Type == "" {
+					errs = append(errs, p.validationErrorAt(entry.field, fmt.Sprintf("%s: 'type' is required", taskDisplayName)))
+				}
 			}
-			taskNames[task.Name] = true
-		}
 
-		if task.Type == "" {
-			errs = append(errs, gxoerrors.NewValidationError(fmt.Sprintf("%s: 'type' is required", taskDisplayName), nil))
-		}
+			if task.IsInclude() {
+				errs = append(errs, validateIncludeTask(p, entry)...)
+				if task.Name != "" {
+					includeTasks[task.Name] = struct{}{}
+				}
+			}
 
-		// Validate task-specific state policy.
-		if task.StatePolicy != nil {
-			if task.StatePolicy.AccessMode != "" && task.StatePolicy.AccessMode != StateAccessDeepCopy && task.StatePolicy.AccessMode != StateAccessUnsafeDirectReference {
-				errs = append(errs, gxoerrors.NewValidationError(fmt.Sprintf("%s: state_policy has invalid access_mode: '%s'", taskDisplayName, task.StatePolicy.AccessMode), nil))
+			switch task.TriggerRule {
+			case "", TriggerRuleAllSuccess, TriggerRuleAllDone, TriggerRuleOneFailed, TriggerRuleOneSuccess, TriggerRuleNoneFailed:
+			default:
+				errs = append(errs, p.validationErrorAt(entry.fieldOf("trigger_rule"), fmt.Sprintf("%s: invalid trigger_rule '%s' (allowed: %s, %s, %s, %s, %s)", taskDisplayName, task.TriggerRule,
+					TriggerRuleAllSuccess, TriggerRuleAllDone, TriggerRuleOneFailed, TriggerRuleOneSuccess, TriggerRuleNoneFailed)))
 			}
-		}
 
-		if task.Register != "" {
-			if !identifierRegex.MatchString(task.Register) {
-				errs = append(errs, gxoerrors.NewValidationError(fmt.Sprintf("%s: 'register' key '%s' is not a valid identifier", taskDisplayName, task.Register), nil))
+			// Validate task-specific state policy.
+			if task.StatePolicy != nil {
+				if task.StatePolicy.AccessMode != "" && task.StatePolicy.AccessMode != StateAccessDeepCopy && task.StatePolicy.AccessMode != StateAccessUnsafeDirectReference {
+					errs = append(errs, p.validationErrorAt(entry.fieldOf("state_policy.access_mode"), fmt.Sprintf("%s: state_policy has invalid access_mode: '%s'", taskDisplayName, task.StatePolicy.AccessMode)))
+				}
 			}
-			if regTaskName, exists := registeredVars[task.Register]; exists {
-				errs = append(errs, gxoerrors.NewValidationError(fmt.Sprintf("%s: 'register' key '%s' is already used by task '%s'", taskDisplayName, task.Register, regTaskName), nil))
-			} else {
-				registeredVars[task.Register] = task.Name
+
+			if task.Register != "" {
+				if !identifierRegex.MatchString(task.Register) {
+					errs = append(errs, p.validationErrorAt(entry.fieldOf("register"), fmt.Sprintf("%s: 'register' key '%s' is not a valid identifier", taskDisplayName, task.Register)))
+				}
+				if regTaskName, exists := registeredVars[task.Register]; exists {
+					errs = append(errs, p.validationErrorAt(entry.fieldOf("register"), fmt.Sprintf("%s: 'register' key '%s' is already used by task '%s'", taskDisplayName, task.Register, regTaskName)))
+				} else {
+					registeredVars[task.Register] = task.Name
+				}
+				if task.Name == "" {
+					errs = append(errs, p.validationErrorAt(entry.fieldOf("register"), fmt.Sprintf("%s: 'name' is required when 'register' is used", taskDisplayName)))
+				}
 			}
-			if task.Name == "" {
-				errs = append(errs, gxoerrors.NewValidationError(fmt.Sprintf("%s: 'name' is required when 'register' is used", taskDisplayName), nil))
+
+			for _, streamInputTarget := range task.StreamInputs {
+				if !taskNameRegex.MatchString(streamInputTarget) {
+					errs = append(errs, p.validationErrorAt(entry.fieldOf("stream_inputs"), fmt.Sprintf("%s: 'stream_inputs' target '%s' contains invalid characters", taskDisplayName, streamInputTarget)))
+				}
+				if task.Name != "" && streamInputTarget == task.Name {
+					errs = append(errs, p.validationErrorAt(entry.fieldOf("stream_inputs"), fmt.Sprintf("%s: 'stream_inputs' cannot target itself", taskDisplayName)))
+				}
+				requireTask(streamInputTarget, entry.fieldOf("stream_inputs"))
+				references = append(references, taskReference{from: taskDisplayName, field: entry.fieldOf("stream_inputs"), section: section, target: streamInputTarget, stream: true})
 			}
-		}
 
-		for _, streamInputTarget := range task.StreamInputs {
-			if !taskNameRegex.MatchString(streamInputTarget) {
-				errs = append(errs, gxoerrors.NewValidationError(fmt.Sprintf("%s: 'stream_inputs' target '%s' contains invalid characters", taskDisplayName, streamInputTarget), nil))
+			for _, dependency := range task.DependsOn {
+				if !taskNameRegex.MatchString(dependency) {
+					errs = append(errs, p.validationErrorAt(entry.fieldOf("depends_on"), fmt.Sprintf("%s: 'depends_on' target '%s' contains invalid characters", taskDisplayName, dependency)))
+				}
+				if task.Name != "" && dependency == task.Name {
+					errs = append(errs, p.validationErrorAt(entry.fieldOf("depends_on"), fmt.Sprintf("%s: 'depends_on' cannot target itself", taskDisplayName)))
+				}
+				requireTask(dependency, entry.fieldOf("depends_on"))
+				references = append(references, taskReference{from: taskDisplayName, field: entry.fieldOf("depends_on"), section: section, target: dependency, dependsOn: true})
 			}
-			if task.Name != "" && streamInputTarget == task.Name {
-				errs = append(errs, gxoerrors.NewValidationError(fmt.Sprintf("%s: 'stream_inputs' cannot target itself", taskDisplayName), nil))
+
+			// Validate loop_control configuration.
+			if task.LoopControl != nil {
+				if task.LoopControl.LoopVar != "" && !identifierRegex.MatchString(task.LoopControl.LoopVar) {
+					errs = append(errs, p.validationErrorAt(entry.fieldOf("loop_control.loop_var"), fmt.Sprintf("%s: 'loop_control.loop_var' ('%s') is not a valid identifier", taskDisplayName, task.LoopControl.LoopVar)))
+				}
+				if task.LoopControl.Parallel < 0 {
+					errs = append(errs, p.validationErrorAt(entry.fieldOf("loop_control.parallel"), fmt.Sprintf("%s: 'loop_control.parallel' cannot be negative", taskDisplayName)))
+				}
 			}
-			requiredTaskNames[streamInputTarget] = struct{}{}
-		}
 
-		// Validate loop_control configuration.
-		if task.LoopControl != nil {
-			if task.LoopControl.LoopVar != "" && !identifierRegex.MatchString(task.LoopControl.LoopVar) {
-				errs = append(errs, gxoerrors.NewValidationError(fmt.Sprintf("%s: 'loop_control.loop_var' ('%s') is not a valid identifier", taskDisplayName, task.LoopControl.LoopVar), nil))
+			if task.LoopMatrix != nil {
+				if task.Loop != nil {
+					errs = append(errs, p.validationErrorAt(entry.fieldOf("loop_matrix"), fmt.Sprintf("%s: 'loop' and 'loop_matrix' cannot be combined", taskDisplayName)))
+				}
+				errs = append(errs, validateLoopMatrix(p, task.LoopMatrix, entry.fieldOf("loop_matrix"), taskDisplayName)...)
 			}
-			if task.LoopControl.Parallel < 0 {
-				errs = append(errs, gxoerrors.NewValidationError(fmt.Sprintf("%s: 'loop_control.parallel' cannot be negative", taskDisplayName), nil))
+
+			if task.UntilControl != nil {
+				if task.Until == "" {
+					errs = append(errs, p.validationErrorAt(entry.fieldOf("until_control"), fmt.Sprintf("%s: 'until_control' requires 'until'", taskDisplayName)))
+				}
+				errs = append(errs, validateUntilControl(p, task.UntilControl, entry.fieldOf("until_control"), taskDisplayName)...)
 			}
-		}
 
-		// Validate retry configuration.
-		if task.Retry != nil {
-			if task.Retry.Attempts < 1 {
-				errs = append(errs, gxoerrors.NewValidationError(fmt.Sprintf("%s: 'retry.attempts' must be at least 1", taskDisplayName), nil))
+			if task.Cache != nil {
+				errs = append(errs, validateCache(p, task, entry.fieldOf("cache"), taskDisplayName)...)
 			}
-			var baseDelay time.Duration
-			var delayErr error
-			if task.Retry.Delay != "" {
-				baseDelay, delayErr = time.ParseDuration(task.Retry.Delay)
-				if delayErr != nil {
-					errs = append(errs, gxoerrors.NewValidationError(fmt.Sprintf("%s: invalid format for 'retry.delay': %v", taskDisplayName, delayErr), nil))
-				} else if baseDelay < 0 {
-					errs = append(errs, gxoerrors.NewValidationError(fmt.Sprintf("%s: 'retry.delay' cannot be negative", taskDisplayName), nil))
-				}
+
+			varNames := make([]string, 0, len(task.Vars))
+			for name := range task.Vars {
+				varNames = append(varNames, name)
 			}
-			if task.Retry.MaxDelay != "" {
-				maxDelay, maxDelayErr := time.ParseDuration(task.Retry.MaxDelay)
-				if maxDelayErr != nil {
-					errs = append(errs, gxoerrors.NewValidationError(fmt.Sprintf("%s: invalid format for 'retry.max_delay': %v", taskDisplayName, maxDelayErr), nil))
-				} else if maxDelay > 0 && delayErr == nil && maxDelay < baseDelay {
-					errs = append(errs, gxoerrors.NewValidationError(fmt.Sprintf("%s: 'retry.max_delay' (%v) cannot be less than 'retry.delay' (%v)", taskDisplayName, maxDelay, baseDelay), nil))
+			sort.Strings(varNames)
+			for _, name := range varNames {
+				if !identifierRegex.MatchString(name) {
+					errs = append(errs, p.validationErrorAt(joinFieldPath(entry.fieldOf("vars"), name), fmt.Sprintf("%s: task variable '%s' is not a valid identifier", taskDisplayName, name)))
 				}
 			}
-		}
+			errs = append(errs, validateEnvironment(p, task.Environment, entry.fieldOf("environment"), taskDisplayName)...)
 
-		if task.Timeout != "" {
-			if _, timeoutErr := time.ParseDuration(task.Timeout); timeoutErr != nil {
-				errs = append(errs, gxoerrors.NewValidationError(fmt.Sprintf("%s: invalid format for 'timeout': %v", taskDisplayName, timeoutErr), nil))
+			// Validate retry configuration.
+			if task.Retry != nil {
+				errs = append(errs, validateRetry(p, task.Retry, entry.fieldOf("retry"), taskDisplayName)...)
 			}
-		}
 
-		// Scan all templated fields for variable references to build dependencies.
-		templatesToScan := collectTemplatesToScan(task)
-		for _, tmplStr := range templatesToScan {
-			vars, extractErr := dummyRenderer.ExtractVariables(tmplStr)
-			if extractErr != nil {
-				errs = append(errs, gxoerrors.NewValidationError(fmt.Sprintf("%s: error parsing template [%s]: %v", taskDisplayName, tmplStr, extractErr), extractErr))
-				continue
+			if task.PolicyRef != "" {
+				if _, exists := p.Policies[task.PolicyRef]; !exists {
+					errs = append(errs, p.validationErrorAt(entry.fieldOf("policy_ref"), fmt.Sprintf("%s: 'policy_ref' references unknown policy '%s'%s", taskDisplayName, task.PolicyRef, definedPoliciesHint(p))))
+				}
 			}
-			for _, fullVarPath := range vars {
-				if strings.HasPrefix(fullVarPath, template.GxoStateKeyPrefix+".tasks.") && strings.HasSuffix(fullVarPath, ".status") {
-					parts := strings.Split(fullVarPath, ".")
-					if len(parts) == 4 {
-						referencedTaskName := parts[2]
-						requiredTaskNames[referencedTaskName] = struct{}{}
-						if task.Name != "" && referencedTaskName == task.Name {
-							errs = append(errs, gxoerrors.NewValidationError(fmt.Sprintf("%s: task cannot depend on its own status via template ('%s')", taskDisplayName, fullVarPath), nil))
+
+			if task.Timeout != "" {
+				if _, timeoutErr := time.ParseDuration(task.Timeout); timeoutErr != nil {
+					errs = append(errs, p.validationErrorAt(entry.fieldOf("timeout"), fmt.Sprintf("%s: invalid format for 'timeout': %v", taskDisplayName, timeoutErr)))
+				}
+			}
+
+			// Scan all templated fields for variable references to build dependencies.
+			templatesToScan := collectTemplatesToScan(task)
+			for _, tmplStr := range templatesToScan {
+				vars, extractErr := dummyRenderer.ExtractVariables(tmplStr)
+				if extractErr != nil {
+					errs = append(errs, gxoerrors.NewValidationErrorAt(p.positionOf(entry.field), entry.field, fmt.Sprintf("%s: error parsing template [%s]: %v", taskDisplayName, tmplStr, extractErr), extractErr))
+					continue
+				}
+				for _, fullVarPath := range vars {
+					if strings.HasPrefix(fullVarPath, template.GxoStateKeyPrefix+".tasks.") && strings.HasSuffix(fullVarPath, ".status") {
+						parts := strings.Split(fullVarPath, ".")
+						if len(parts) == 4 {
+							referencedTaskName := parts[2]
+							requireTask(referencedTaskName, entry.field)
+							references = append(references, taskReference{from: taskDisplayName, field: entry.field, section: section, target: referencedTaskName})
+							if task.Name != "" && referencedTaskName == task.Name {
+								errs = append(errs, p.validationErrorAt(entry.field, fmt.Sprintf("%s: task cannot depend on its own status via template ('%s')", taskDisplayName, fullVarPath)))
+							}
+						}
+					} else if regTaskName, isRegistered := registeredVars[fullVarPath]; isRegistered {
+						if task.Name != "" && regTaskName == task.Name {
+							errs = append(errs, p.validationErrorAt(entry.field, fmt.Sprintf("%s: task cannot depend on its own registered variable via template ('%s')", taskDisplayName, fullVarPath)))
 						}
-					}
-				} else if regTaskName, isRegistered := registeredVars[fullVarPath]; isRegistered {
-					if task.Name != "" && regTaskName == task.Name {
-						errs = append(errs, gxoerrors.NewValidationError(fmt.Sprintf("%s: task cannot depend on its own registered variable via template ('%s')", taskDisplayName, fullVarPath), nil))
 					}
 				}
 			}
@@ -167,24 +330,323 @@ func ValidatePlaybookStructure(p *Playbook) []error {
 	}
 
 	// Final check: ensure all referenced tasks actually exist.
-	for reqName := range requiredTaskNames {
-		if _, exists := taskNames[reqName]; !exists {
-			errs = append(errs, gxoerrors.NewValidationError(fmt.Sprintf("playbook validation failed: task name '%s' is referenced by another task but is not defined", reqName), nil))
+	for reqName, field := range requiredTaskNames {
+		if _, exists := taskSections[reqName]; !exists {
+			errs = append(errs, p.validationErrorAt(field, fmt.Sprintf("playbook validation failed: task name '%s' is referenced by another task but is not defined", reqName)))
+		}
+	}
+
+	// Each section runs as its own DAG after the previous one has finished, so
+	// streams cannot cross sections and status references can only look back.
+	for _, ref := range references {
+		if _, isInclude := includeTasks[ref.target]; isInclude && ref.stream {
+			errs = append(errs, p.validationErrorAt(ref.field, fmt.Sprintf("%s: 'stream_inputs' target '%s' includes a playbook and produces no stream", ref.from, ref.target)))
+		}
+		targetSection, exists := taskSections[ref.target]
+		if !exists || targetSection == ref.section {
+			continue
+		}
+		if ref.stream {
+			errs = append(errs, p.validationErrorAt(ref.field, fmt.Sprintf("%s: 'stream_inputs' target '%s' must be in the same section ('%s'), but it is in '%s'", ref.from, ref.target, ref.section, targetSection)))
+		} else if ref.dependsOn && sectionRank(targetSection) > sectionRank(ref.section) {
+			errs = append(errs, p.validationErrorAt(ref.field, fmt.Sprintf("%s: cannot depend on task '%s' in the later '%s' section", ref.from, ref.target, targetSection)))
+		} else if sectionRank(targetSection) > sectionRank(ref.section) {
+			errs = append(errs, p.validationErrorAt(ref.field, fmt.Sprintf("%s: cannot reference the status of task '%s' in the later '%s' section", ref.from, ref.target, targetSection)))
+		}
+	}
+
+	for _, section := range taskSectionOrder {
+		for _, cycle := range FindCycles(DependencyEdges(p, section)) {
+			errs = append(errs, p.validationErrorAt(taskFields[cycle[0].To], fmt.Sprintf("cycle detected in task dependencies: %s", FormatCycle(cycle))))
+		}
+	}
+
+	return errs
+}
+
+// validateLoopMatrix checks the axes and the include and exclude entries of a
+// loop matrix.
+func validateLoopMatrix(p *Playbook, matrix *LoopMatrix, field, displayName string) []error {
+	var errs []error
+	if len(matrix.Axes) == 0 && len(matrix.Include) == 0 {
+		errs = append(errs, p.validationErrorAt(field, fmt.Sprintf("%s: 'loop_matrix' needs at least one axis or 'include' entry", displayName)))
+	}
+	axes := make(map[string]bool, len(matrix.Axes))
+	for _, axis := range matrix.Axes {
+		axes[axis.Name] = true
+		axisField := joinFieldPath(field, axis.Name)
+		if !identifierRegex.MatchString(axis.Name) {
+			errs = append(errs, p.validationErrorAt(axisField, fmt.Sprintf("%s: 'loop_matrix' axis '%s' is not a valid identifier", displayName, axis.Name)))
+		}
+		switch values := axis.Values.(type) {
+		case string:
+			if !strings.Contains(values, "{{") {
+				errs = append(errs, p.validationErrorAt(axisField, fmt.Sprintf("%s: 'loop_matrix' axis '%s' must be a list, or a template resolving to one", displayName, axis.Name)))
+			}
+		case []interface{}:
+			if len(values) == 0 {
+				errs = append(errs, p.validationErrorAt(axisField, fmt.Sprintf("%s: 'loop_matrix' axis '%s' has no values", displayName, axis.Name)))
+			}
+		default:
+			errs = append(errs, p.validationErrorAt(axisField, fmt.Sprintf("%s: 'loop_matrix' axis '%s' must be a list, or a template resolving to one", displayName, axis.Name)))
+		}
+	}
+	for i, exclude := range matrix.Exclude {
+		excludeField := joinFieldPath(joinFieldPath(field, LoopMatrixExclude), strconv.Itoa(i))
+		if len(exclude) == 0 {
+			errs = append(errs, p.validationErrorAt(excludeField, fmt.Sprintf("%s: 'loop_matrix.exclude' entry %d is empty", displayName, i)))
+		}
+		keys := make([]string, 0, len(exclude))
+		for key := range exclude {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if !axes[key] {
+				errs = append(errs, p.validationErrorAt(excludeField, fmt.Sprintf("%s: 'loop_matrix.exclude' entry %d references unknown axis '%s'", displayName, i, key)))
+			}
+		}
+	}
+	for i, include := range matrix.Include {
+		if len(include) == 0 {
+			errs = append(errs, p.validationErrorAt(joinFieldPath(joinFieldPath(field, LoopMatrixInclude), strconv.Itoa(i)), fmt.Sprintf("%s: 'loop_matrix.include' entry %d is empty", displayName, i)))
+		}
+	}
+	return errs
+}
+
+// validateEnvironment checks the names of the environment variables found at
+// field.
+func validateEnvironment(p *Playbook, environment map[string]string, field, displayName string) []error {
+	names := make([]string, 0, len(environment))
+	for name := range environment {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var errs []error
+	for _, name := range names {
+		if !identifierRegex.MatchString(name) {
+			errs = append(errs, p.validationErrorAt(joinFieldPath(field, name), fmt.Sprintf("%s: environment variable name '%s' is not valid", displayName, name)))
+		}
+	}
+	return errs
+}
+
+// validateUntilControl checks the polling settings of an 'until' condition.
+func validateUntilControl(p *Playbook, control *UntilControlConfig, field, displayName string) []error {
+	var errs []error
+	if control.MaxAttempts < 0 {
+		errs = append(errs, p.validationErrorAt(field+".max_attempts", fmt.Sprintf("%s: 'until_control.max_attempts' cannot be negative", displayName)))
+	}
+	durations := []struct{ key, value string }{{"delay", control.Delay}, {"timeout", control.Timeout}}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		if duration, err := time.ParseDuration(d.value); err != nil {
+			errs = append(errs, p.validationErrorAt(field+"."+d.key, fmt.Sprintf("%s: invalid format for 'until_control.%s': %v", displayName, d.key, err)))
+		} else if duration < 0 {
+			errs = append(errs, p.validationErrorAt(field+"."+d.key, fmt.Sprintf("%s: 'until_control.%s' cannot be negative", displayName, d.key)))
+		}
+	}
+	return errs
+}
+
+// validateCache checks the cache settings of a task. A task streaming data
+// cannot be cached, since skipping its module would leave the stream empty.
+func validateCache(p *Playbook, task *Task, field, displayName string) []error {
+	var errs []error
+	if len(task.StreamInputs) > 0 {
+		errs = append(errs, p.validationErrorAt(field, fmt.Sprintf("%s: 'cache' cannot be combined with 'stream_inputs'", displayName)))
+	}
+	if task.Cache.TTL != "" {
+		if ttl, err := time.ParseDuration(task.Cache.TTL); err != nil {
+			errs = append(errs, p.validationErrorAt(field+".ttl", fmt.Sprintf("%s: invalid format for 'cache.ttl': %v", displayName, err)))
+		} else if ttl < 0 {
+			errs = append(errs, p.validationErrorAt(field+".ttl", fmt.Sprintf("%s: 'cache.ttl' cannot be negative", displayName)))
+		}
+	}
+	for i, path := range task.Cache.InputsFiles {
+		if strings.TrimSpace(path) == "" {
+			errs = append(errs, p.validationErrorAt(fmt.Sprintf("%s.inputs_files.%d", field, i), fmt.Sprintf("%s: 'cache.inputs_files' entries cannot be empty", displayName)))
+		}
+	}
+	return errs
+}
+
+// validateRetry checks a retry configuration found at field.
+func validateRetry(p *Playbook, retry *RetryConfig, field, displayName string) []error {
+	var errs []error
+	if retry.Attempts < 1 {
+		errs = append(errs, p.validationErrorAt(field+".attempts", fmt.Sprintf("%s: 'retry.attempts' must be at least 1", displayName)))
+	}
+	var baseDelay time.Duration
+	var delayErr error
+	if retry.Delay != "" {
+		baseDelay, delayErr = time.ParseDuration(retry.Delay)
+		if delayErr != nil {
+			errs = append(errs, p.validationErrorAt(field+".delay", fmt.Sprintf("%s: invalid format for 'retry.delay': %v", displayName, delayErr)))
+		} else if baseDelay < 0 {
+			errs = append(errs, p.validationErrorAt(field+".delay", fmt.Sprintf("%s: 'retry.delay' cannot be negative", displayName)))
+		}
+	}
+	if retry.MaxDelay != "" {
+		maxDelay, maxDelayErr := time.ParseDuration(retry.MaxDelay)
+		if maxDelayErr != nil {
+			errs = append(errs, p.validationErrorAt(field+".max_delay", fmt.Sprintf("%s: invalid format for 'retry.max_delay': %v", displayName, maxDelayErr)))
+		} else if maxDelay > 0 && delayErr == nil && maxDelay < baseDelay {
+			errs = append(errs, p.validationErrorAt(field+".max_delay", fmt.Sprintf("%s: 'retry.max_delay' (%v) cannot be less than 'retry.delay' (%v)", displayName, maxDelay, baseDelay)))
+		}
+	}
+	return errs
+}
+
+// validateNamedPolicy checks an entry of the playbook's 'policies'.
+func validateNamedPolicy(p *Playbook, name string, policy *NamedPolicy) []error {
+	var errs []error
+	field := "policies." + name
+	displayName := fmt.Sprintf("policy '%s'", name)
+	if !identifierRegex.MatchString(name) {
+		errs = append(errs, p.validationErrorAt(field, fmt.Sprintf("%s: name is not a valid identifier", displayName)))
+	}
+	if policy.Retry != nil {
+		errs = append(errs, validateRetry(p, policy.Retry, field+".retry", displayName)...)
+	}
+	if policy.Timeout != "" {
+		if _, timeoutErr := time.ParseDuration(policy.Timeout); timeoutErr != nil {
+			errs = append(errs, p.validationErrorAt(field+".timeout", fmt.Sprintf("%s: invalid format for 'timeout': %v", displayName, timeoutErr)))
 		}
 	}
+	if policy.StatePolicy != nil && policy.StatePolicy.AccessMode != "" && policy.StatePolicy.AccessMode != StateAccessDeepCopy && policy.StatePolicy.AccessMode != StateAccessUnsafeDirectReference {
+		errs = append(errs, p.validationErrorAt(field+".state_policy.access_mode", fmt.Sprintf("%s: state_policy has invalid access_mode: '%s'", displayName, policy.StatePolicy.AccessMode)))
+	}
+	if policy.ChannelPolicy != nil && policy.ChannelPolicy.BufferSize != nil && *policy.ChannelPolicy.BufferSize < 0 {
+		errs = append(errs, p.validationErrorAt(field+".channel_policy.buffer_size", fmt.Sprintf("%s: channel_policy buffer_size cannot be negative", displayName)))
+	}
+	return errs
+}
 
+// validateInput checks a declaration of the playbook's 'inputs'.
+func validateInput(p *Playbook, input Input) []error {
+	var errs []error
+	field := "inputs." + input.Name
+	displayName := fmt.Sprintf("input '%s'", input.Name)
+	if !identifierRegex.MatchString(input.Name) {
+		errs = append(errs, p.validationErrorAt(field, fmt.Sprintf("%s: name is not a valid identifier", displayName)))
+	}
+	if _, isVar := p.Vars[input.Name]; isVar {
+		errs = append(errs, p.validationErrorAt(field, fmt.Sprintf("%s: name is already used by a playbook var", displayName)))
+	}
+	switch input.Type {
+	case InputTypeString, InputTypeInt, InputTypeBool, InputTypeList, InputTypeMap:
+	default:
+		errs = append(errs, p.validationErrorAt(field+".type", fmt.Sprintf("%s: invalid type '%s' (allowed: %s, %s, %s, %s, %s)", displayName, input.Type, InputTypeString, InputTypeInt, InputTypeBool, InputTypeList, InputTypeMap)))
+		return errs
+	}
+	if input.Required && input.Default != nil {
+		errs = append(errs, p.validationErrorAt(field+".default", fmt.Sprintf("%s: a required input cannot have a 'default'", displayName)))
+	}
+	if len(input.Enum) > 0 {
+		if input.Type == InputTypeList || input.Type == InputTypeMap {
+			errs = append(errs, p.validationErrorAt(field+".enum", fmt.Sprintf("%s: 'enum' is not supported for %s inputs", displayName, input.Type)))
+		}
+		for i, allowed := range input.Enum {
+			if _, err := convertInputValue(input.Type, allowed); err != nil {
+				errs = append(errs, p.validationErrorAt(fmt.Sprintf("%s.enum.%d", field, i), fmt.Sprintf("%s: invalid 'enum' value: %v", displayName, err)))
+			}
+		}
+	}
+	if input.Default != nil {
+		converted, err := convertInputValue(input.Type, input.Default)
+		if err != nil {
+			errs = append(errs, p.validationErrorAt(field+".default", fmt.Sprintf("%s: invalid 'default': %v", displayName, err)))
+		} else if len(input.Enum) > 0 && !inputEnumContains(input, converted) {
+			errs = append(errs, p.validationErrorAt(field+".default", fmt.Sprintf("%s: 'default' %v is not one of: %s", displayName, converted, formatInputEnum(input.Enum))))
+		}
+	}
 	return errs
 }
 
+// definedPoliciesHint lists the playbook's named policies for error messages.
+func definedPoliciesHint(p *Playbook) string {
+	if len(p.Policies) == 0 {
+		return " (the playbook defines no 'policies')"
+	}
+	names := make([]string, 0, len(p.Policies))
+	for name := range p.Policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Sprintf(" (defined: %s)", strings.Join(names, ", "))
+}
+
+// validateIncludeTask checks the params of an include_playbook task.
+func validateIncludeTask(p *Playbook, entry taskEntry) []error {
+	task, taskDisplayName := entry.task, entry.displayName
+	var errs []error
+	if path, ok := task.Params[IncludeParamPath].(string); !ok || path == "" {
+		errs = append(errs, p.validationErrorAt(entry.fieldOf("params.path"), fmt.Sprintf("%s: '%s' requires a non-empty string 'params.%s'", taskDisplayName, IncludePlaybookType, IncludeParamPath)))
+	}
+	if vars, exists := task.Params[IncludeParamVars]; exists {
+		if _, ok := vars.(map[string]interface{}); !ok {
+			errs = append(errs, p.validationErrorAt(entry.fieldOf("params.vars"), fmt.Sprintf("%s: 'params.%s' must be a map", taskDisplayName, IncludeParamVars)))
+		}
+	}
+	for key := range task.Params {
+		if key != IncludeParamPath && key != IncludeParamVars {
+			errs = append(errs, p.validationErrorAt(entry.fieldOf("params."+key), fmt.Sprintf("%s: unsupported param '%s' for '%s' (allowed: %s, %s)", taskDisplayName, key, IncludePlaybookType, IncludeParamPath, IncludeParamVars)))
+		}
+	}
+	if len(task.StreamInputs) > 0 {
+		errs = append(errs, p.validationErrorAt(entry.fieldOf("stream_inputs"), fmt.Sprintf("%s: '%s' cannot use 'stream_inputs'", taskDisplayName, IncludePlaybookType)))
+	}
+	return errs
+}
+
+// TemplatesIn returns the template strings found in a value, descending into
+// maps and slices.
+func TemplatesIn(value interface{}) []string {
+	var templates []string
+	switch v := value.(type) {
+	case string:
+		if strings.Contains(v, "{{") && strings.Contains(v, "}}") {
+			templates = append(templates, v)
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			templates = append(templates, TemplatesIn(item)...)
+		}
+	case []interface{}:
+		for _, item := range v {
+			templates = append(templates, TemplatesIn(item)...)
+		}
+	}
+	return templates
+}
+
 // collectTemplatesToScan gathers all string fields from a task that may contain templates.
 func collectTemplatesToScan(task *Task) []string {
-	templates := []string{}
-	if task.When != "" {
-		templates = append(templates, task.When)
-	}
+	templates := task.WhenConditions()
 	if loopStr, ok := task.Loop.(string); ok && loopStr != "" {
 		templates = append(templates, loopStr)
 	}
+	if task.LoopMatrix != nil {
+		templates = append(templates, task.LoopMatrix.Templates()...)
+	}
+	for _, condition := range []string{task.Until, task.FailedWhen, task.ChangedWhen} {
+		if condition != "" {
+			templates = append(templates, condition)
+		}
+	}
+	if task.Cache != nil {
+		templates = append(templates, task.Cache.Templates()...)
+	}
+	templates = append(templates, TemplatesIn(task.Vars)...)
+	for _, value := range task.Environment {
+		if strings.Contains(value, "{{") {
+			templates = append(templates, value)
+		}
+	}
 	for _, paramValue := range task.Params {
 		if strValue, ok := paramValue.(string); ok {
 			if strings.Contains(strValue, "{{") && strings.Contains(strValue, "}}") {
@@ -192,5 +654,9 @@ func collectTemplatesToScan(task *Task) []string {
 			}
 		}
 	}
+	// The variables passed to an included playbook are rendered in full.
+	if task.IsInclude() {
+		templates = append(templates, TemplatesIn(task.Params[IncludeParamVars])...)
+	}
 	return templates
 }
\ No newline at end of file
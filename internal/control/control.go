@@ -0,0 +1,159 @@
+// Package control lets one gxo process control a run executing in another.
+// The executing process serves its run handle on a unix socket named after
+// the run ID; every connection carries a single JSON request and response.
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	gxo "github.com/gxo-labs/gxo/pkg/gxo/v1"
+	gxolog "github.com/gxo-labs/gxo/pkg/gxo/v1/log"
+)
+
+// Actions understood by the server.
+const (
+	ActionStatus     = "status"
+	ActionPause      = "pause"
+	ActionResume     = "resume"
+	ActionCancelTask = "cancel_task"
+)
+
+// requestTimeout bounds how long a single request may take.
+const requestTimeout = 10 * time.Second
+
+// Request is a command for a run.
+type Request struct {
+	Action string `json:"action"`
+	// Task is the name or ID of the task to cancel for ActionCancelTask.
+	Task string `json:"task,omitempty"`
+}
+
+// Response is the outcome of a request. Status is the run's status after the
+// request was applied.
+type Response struct {
+	Error  string         `json:"error,omitempty"`
+	Status *gxo.RunStatus `json:"status,omitempty"`
+}
+
+// SocketPath returns the path of the control socket of a run.
+func SocketPath(dir, runID string) string {
+	return filepath.Join(dir, runID+".sock")
+}
+
+// Server serves a run handle on a control socket.
+type Server struct {
+	listener net.Listener
+	path     string
+	handle   gxo.RunHandle
+	log      gxolog.Logger
+}
+
+// Listen creates the control socket for the run in dir. Call Serve to handle
+// requests and Close to remove the socket.
+func Listen(dir string, handle gxo.RunHandle, log gxolog.Logger) (*Server, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create control directory '%s': %w", dir, err)
+	}
+	path := SocketPath(dir, handle.RunID())
+	// A socket left behind by a crashed process would make Listen fail.
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove stale control socket '%s': %w", path, err)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on control socket '%s': %w", path, err)
+	}
+	return &Server{listener: listener, path: path, handle: handle, log: log}, nil
+}
+
+// Path returns the path of the control socket.
+func (s *Server) Path() string {
+	return s.path
+}
+
+// Serve handles requests until the server is closed.
+func (s *Server) Serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				s.log.Warnf("Control socket stopped accepting connections: %v", err)
+			}
+			return
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// Close stops the server and removes its socket.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	if removeErr := os.Remove(s.path); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) && err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(requestTimeout))
+
+	var req Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		s.log.Warnf("Invalid control request: %v", err)
+		_ = json.NewEncoder(conn).Encode(Response{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+	s.log.Debugf("Control request: %s %s", req.Action, req.Task)
+
+	var resp Response
+	switch req.Action {
+	case ActionStatus:
+	case ActionPause:
+		s.handle.Pause()
+	case ActionResume:
+		s.handle.Resume()
+	case ActionCancelTask:
+		if req.Task == "" {
+			resp.Error = "a task name is required to cancel a task"
+		} else if err := s.handle.CancelTask(req.Task); err != nil {
+			resp.Error = err.Error()
+		}
+	default:
+		resp.Error = fmt.Sprintf("unknown action '%s'", req.Action)
+	}
+	status := s.handle.Status()
+	resp.Status = &status
+	if err := json.NewEncoder(conn).Encode(resp); err != nil {
+		s.log.Warnf("Failed to send control response: %v", err)
+	}
+}
+
+// Send sends a request to the control socket at socketPath and returns the
+// response. A request the run rejected is returned as an error.
+func Send(socketPath string, req Request) (*Response, error) {
+	conn, err := net.DialTimeout("unix", socketPath, requestTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to control socket '%s' (is the run still executing?): %w", socketPath, err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(requestTimeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("failed to send control request: %w", err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read control response: %w", err)
+	}
+	if resp.Error != "" {
+		return &resp, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
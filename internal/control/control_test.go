@@ -0,0 +1,104 @@
+package control_test
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/gxo-labs/gxo/internal/control"
+	"github.com/gxo-labs/gxo/internal/logger"
+	gxo "github.com/gxo-labs/gxo/pkg/gxo/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeHandle records the control calls made on a run.
+type fakeHandle struct {
+	mu        sync.Mutex
+	paused    bool
+	cancelled []string
+}
+
+func (h *fakeHandle) RunID() string                       { return "run-1" }
+func (h *fakeHandle) Wait() (*gxo.ExecutionReport, error) { return nil, nil }
+func (h *fakeHandle) Cancel()                             {}
+
+func (h *fakeHandle) Pause() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.paused = true
+}
+
+func (h *fakeHandle) Resume() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.paused = false
+}
+
+func (h *fakeHandle) CancelTask(name string) error {
+	if name != "running" {
+		return errors.New("task '" + name + "' is not running")
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cancelled = append(h.cancelled, name)
+	return nil
+}
+
+func (h *fakeHandle) Status() gxo.RunStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	state := gxo.RunStateRunning
+	if h.paused {
+		state = gxo.RunStatePaused
+	}
+	return gxo.RunStatus{RunID: "run-1", PlaybookName: "pb", State: state}
+}
+
+func TestControl_ServerAppliesRequests(t *testing.T) {
+	dir := t.TempDir()
+	handle := &fakeHandle{}
+	server, err := control.Listen(dir, handle, logger.NewLogger("error", "text", io.Discard))
+	require.NoError(t, err)
+	go server.Serve()
+	defer server.Close()
+
+	socketPath := control.SocketPath(dir, "run-1")
+	assert.Equal(t, socketPath, server.Path())
+
+	resp, err := control.Send(socketPath, control.Request{Action: control.ActionPause})
+	require.NoError(t, err)
+	require.NotNil(t, resp.Status)
+	assert.Equal(t, gxo.RunStatePaused, resp.Status.State)
+
+	resp, err = control.Send(socketPath, control.Request{Action: control.ActionResume})
+	require.NoError(t, err)
+	assert.Equal(t, gxo.RunStateRunning, resp.Status.State)
+
+	resp, err = control.Send(socketPath, control.Request{Action: control.ActionStatus})
+	require.NoError(t, err)
+	assert.Equal(t, "pb", resp.Status.PlaybookName)
+
+	_, err = control.Send(socketPath, control.Request{Action: control.ActionCancelTask, Task: "running"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"running"}, handle.cancelled)
+
+	_, err = control.Send(socketPath, control.Request{Action: control.ActionCancelTask, Task: "idle"})
+	assert.EqualError(t, err, "task 'idle' is not running")
+
+	_, err = control.Send(socketPath, control.Request{Action: "reboot"})
+	assert.EqualError(t, err, "unknown action 'reboot'")
+}
+
+func TestControl_CloseRemovesSocket(t *testing.T) {
+	dir := t.TempDir()
+	server, err := control.Listen(dir, &fakeHandle{}, logger.NewLogger("error", "text", io.Discard))
+	require.NoError(t, err)
+	go server.Serve()
+	require.NoError(t, server.Close())
+
+	_, err = control.Send(control.SocketPath(dir, "run-1"), control.Request{Action: control.ActionStatus})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is the run still executing?")
+}
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	gxoerrors "github.com/gxo-labs/gxo/pkg/gxo/v1/errors"
+)
+
+// firstFailedMember returns the first of the nodes that failed without
+// 'ignore_errors'. The caller must hold statusMu.
+func (r *playbookRun) firstFailedMember(nodes []*Node) *Node {
+	for _, node := range nodes {
+		if r.taskStatuses[node.ID] == StatusFailed && !node.Task.IgnoreErrors {
+			return node
+		}
+	}
+	return nil
+}
+
+// completeBlock finishes a block once all of its members have finished. The
+// block fails if its body failed and no rescue tasks recovered from it, or if
+// an always task failed; it is skipped if all of its members were skipped.
+func (r *playbookRun) completeBlock(ctx context.Context, node *Node, fatalErrChan chan<- error) {
+	r.statusMu.RLock()
+	status := StatusCompleted
+	cause := r.firstFailedMember(node.BlockBody)
+	if cause != nil && len(node.BlockRescue) > 0 && r.firstFailedMember(node.BlockRescue) == nil {
+		cause = nil // The rescue tasks recovered from the failure.
+	}
+	if cause == nil {
+		cause = r.firstFailedMember(node.BlockAlways)
+	}
+	if cause != nil {
+		status = StatusFailed
+	} else {
+		allSkipped, upstreamFailed := true, false
+		for _, members := range [][]*Node{node.BlockBody, node.BlockRescue, node.BlockAlways} {
+			for _, member := range members {
+				if r.taskStatuses[member.ID] != StatusSkipped {
+					allSkipped = false
+				}
+				upstreamFailed = upstreamFailed || member.upstreamFailed.Load()
+			}
+		}
+		if allSkipped {
+			status = StatusSkipped
+			node.upstreamFailed.Store(upstreamFailed)
+		}
+	}
+	r.statusMu.RUnlock()
+
+	var blockErr error
+	switch status {
+	case StatusFailed:
+		if cause.blockCause != nil {
+			cause = cause.blockCause
+		}
+		node.blockCause = cause
+		r.errorsMu.Lock()
+		causeErr := r.taskErrors[cause.ID]
+		r.errorsMu.Unlock()
+		if causeErr == nil {
+			causeErr = fmt.Errorf("task '%s' failed", cause.DisplayName())
+		}
+		blockErr = fmt.Errorf("block '%s' failed: %w", node.DisplayName(), causeErr)
+		if node.Block == nil && !node.Task.IgnoreErrors {
+			r.recordFirstFailure(cause, causeErr)
+		}
+		r.log.Errorf("Block '%s' failed because task '%s' failed.", node.DisplayName(), cause.DisplayName())
+	case StatusSkipped:
+		blockErr = gxoerrors.NewSkippedError("all tasks in the block were skipped")
+	default:
+		r.log.Infof("Block '%s' completed.", node.DisplayName())
+	}
+	r.handleTaskCompletion(ctx, node.ID, status, blockErr, fatalErrChan, false)
+}
+
+// isFailureHandled reports whether a failed task is enclosed in a block that
+// still completed, i.e. whose rescue tasks recovered from the failure. The
+// caller must hold statusMu.
+func (r *playbookRun) isFailureHandled(taskID string) bool {
+	node, exists := r.nodesByID[taskID]
+	if !exists {
+		return false
+	}
+	for block := node.Block; block != nil; block = block.Block {
+		if r.taskStatuses[block.ID] == StatusCompleted {
+			return true
+		}
+	}
+	return false
+}
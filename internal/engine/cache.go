@@ -0,0 +1,200 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/gxo-labs/gxo/internal/config"
+	"github.com/gxo-labs/gxo/internal/module"
+	"github.com/gxo-labs/gxo/internal/secrets"
+	intTemplate "github.com/gxo-labs/gxo/internal/template"
+	"github.com/gxo-labs/gxo/internal/util"
+	gxo "github.com/gxo-labs/gxo/pkg/gxo/v1"
+	gxocache "github.com/gxo-labs/gxo/pkg/gxo/v1/cache"
+	gxolog "github.com/gxo-labs/gxo/pkg/gxo/v1/log"
+)
+
+// cacheCount tallies the cache lookups of a task.
+type cacheCount struct {
+	hits   int
+	misses int
+}
+
+// cacheIdentity is everything a cached execution depends on. Its JSON
+// encoding, whose map keys are sorted, is hashed into the cache key.
+type cacheIdentity struct {
+	ModuleType  string                 `json:"module_type"`
+	Params      map[string]interface{} `json:"params"`
+	Environment map[string]string      `json:"environment,omitempty"`
+	Key         string                 `json:"key,omitempty"`
+	InputsFiles map[string]string      `json:"inputs_files,omitempty"`
+}
+
+// cacheEnabled reports whether an execution of task uses the cache. Dry runs
+// and tasks streaming data to others never do.
+func (r *TaskRunner) cacheEnabled(ctx context.Context, task *config.Task) bool {
+	if task.Cache == nil || r.cacheStore == nil {
+		return false
+	}
+	if dryRun, _ := ctx.Value(module.DryRunKey{}).(bool); dryRun {
+		return false
+	}
+	_, isProducer := r.channelManager.GetOutputManagedChannels(task.InternalID)
+	return !isProducer
+}
+
+// computeCacheKey returns the hex-encoded SHA-256 hash identifying an
+// execution of task with the given rendered params and environment, the
+// rendered and merged environment variables passed to the module. The task's
+// 'cache.key' and 'cache.inputs_files' are rendered against data; the input
+// files are hashed by content.
+func (r *TaskRunner) computeCacheKey(task *config.Task, params map[string]interface{}, environment map[string]string, data map[string]interface{}, renderer intTemplate.Renderer) (string, error) {
+	identity := cacheIdentity{ModuleType: task.Type, Params: params, Environment: environment}
+	if task.Cache.Key != "" {
+		key, err := renderer.Render(task.Cache.Key, data)
+		if err != nil {
+			return "", fmt.Errorf("failed to render 'cache.key': %w", err)
+		}
+		identity.Key = key
+	}
+	for _, pathTemplate := range task.Cache.InputsFiles {
+		path, err := renderer.Render(pathTemplate, data)
+		if err != nil {
+			return "", fmt.Errorf("failed to render 'cache.inputs_files' entry '%s': %w", pathTemplate, err)
+		}
+		resolved := path
+		if r.resolvePath != nil {
+			resolved = r.resolvePath(path)
+		}
+		digest, err := hashFile(resolved)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash cache input file '%s': %w", path, err)
+		}
+		if identity.InputsFiles == nil {
+			identity.InputsFiles = make(map[string]string, len(task.Cache.InputsFiles))
+		}
+		identity.InputsFiles[path] = digest
+	}
+
+	encoded, err := json.Marshal(identity)
+	if err != nil {
+		return "", fmt.Errorf("params cannot be encoded: %w", err)
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// hashFile returns the hex-encoded SHA-256 hash of the contents of a file.
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// lookupCache returns the summary cached for key and true when a fresh entry
+// exists. A missing or stale entry, or a run with gxo.NoCacheKey set, is a
+// miss. The store failing is logged and treated as a miss, so that a broken
+// cache never fails a run.
+func (r *TaskRunner) lookupCache(ctx context.Context, task *config.Task, key string, taskLogger gxolog.Logger) (interface{}, bool) {
+	hit := false
+	defer func() {
+		if r.recordCacheLookup != nil {
+			r.recordCacheLookup(task, hit)
+		}
+	}()
+
+	if noCache, _ := ctx.Value(gxo.NoCacheKey{}).(bool); noCache {
+		taskLogger.Debugf("Cache bypassed, executing task (key %s)", key)
+		return nil, false
+	}
+	entry, err := r.cacheStore.Get(key)
+	if err != nil {
+		if !errors.Is(err, gxocache.ErrNotFound) {
+			taskLogger.Warnf("Failed to read cache entry, executing task: %v", err)
+		} else {
+			taskLogger.Debugf("Cache miss (key %s)", key)
+		}
+		return nil, false
+	}
+	if ttl := task.GetCacheTTL(); ttl > 0 && time.Since(entry.CreatedAt) > ttl {
+		taskLogger.Debugf("Cache entry from %s is older than %v, executing task (key %s)", entry.CreatedAt.Format(time.RFC3339), ttl, key)
+		return nil, false
+	}
+
+	hit = true
+	taskLogger.Infof("Restored result cached at %s, skipping module execution (key %s)", entry.CreatedAt.Format(time.RFC3339), key)
+	return util.DeepCopy(entry.Summary), true
+}
+
+// storeCache caches the summary of a successful execution under key, with the
+// secrets resolved by the task redacted. Failing to store it is only logged.
+func (r *TaskRunner) storeCache(task *config.Task, key string, summary interface{}, secretTracker *secrets.SecretTracker, taskLogger gxolog.Logger) {
+	redactedSummary, _ := intTemplate.RedactTrackedSecrets(summary, secretTracker)
+	entry := &gxocache.Entry{
+		Key:        key,
+		TaskID:     task.InternalID,
+		ModuleType: task.Type,
+		Summary:    redactedSummary,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err := r.cacheStore.Put(entry); err != nil {
+		taskLogger.Warnf("Failed to cache task result: %v", err)
+	}
+}
+
+// recordCacheLookup counts a hit or a miss of a task's cache in the run's
+// report and in the engine's metrics.
+func (r *playbookRun) recordCacheLookup(task *config.Task, hit bool) {
+	r.cacheMu.Lock()
+	count, exists := r.cacheCounts[task.InternalID]
+	if !exists {
+		count = &cacheCount{}
+		r.cacheCounts[task.InternalID] = count
+	}
+	if hit {
+		count.hits++
+	} else {
+		count.misses++
+	}
+	r.cacheMu.Unlock()
+
+	pbName := ""
+	if r.playbook != nil {
+		pbName = r.playbook.Name
+	}
+	taskName := task.InternalID
+	if task.Name != "" {
+		taskName = task.Name
+	}
+	counter := r.taskCacheMissCounter
+	if hit {
+		counter = r.taskCacheHitCounter
+	}
+	if counter != nil {
+		counter.WithLabelValues(pbName, taskName, task.Type).Inc()
+	}
+}
+
+// cacheLookups returns the cache hits and misses of a task.
+func (r *playbookRun) cacheLookups(taskID string) (hits, misses int) {
+	r.cacheMu.Lock()
+	defer r.cacheMu.Unlock()
+	if count, exists := r.cacheCounts[taskID]; exists {
+		return count.hits, count.misses
+	}
+	return 0, 0
+}
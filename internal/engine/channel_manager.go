@@ -127,8 +127,17 @@ func (cm *ChannelManager) CreateChannels(dag *DAG) error {
 			producerWg.Add(1)
 			cm.consumerToProducerWGs[consumerID] = append(cm.consumerToProducerWGs[consumerID], producerWg)
 
-			// Create the physical channel with the effective policy.
-			policy := cm.defaultPolicy // Start with the default. (Future: could be overridden by task config).
+			// Create the physical channel with the effective policy: the
+			// default, overridden by the producer's resolved channel policy.
+			policy := cm.defaultPolicy
+			if producerNode.ChannelPolicy != nil {
+				if producerNode.ChannelPolicy.BufferSize != nil {
+					policy.BufferSize = producerNode.ChannelPolicy.BufferSize
+				}
+				if producerNode.ChannelPolicy.OverflowStrategy != "" {
+					policy.OverflowStrategy = producerNode.ChannelPolicy.OverflowStrategy
+				}
+			}
 			bufferSize := *policy.BufferSize
 			if bufferSize < 0 {
 				bufferSize = 0
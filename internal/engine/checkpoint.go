@@ -0,0 +1,206 @@
+package engine
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/gxo-labs/gxo/internal/config"
+	gxo "github.com/gxo-labs/gxo/pkg/gxo/v1"
+	"github.com/gxo-labs/gxo/pkg/gxo/v1/checkpoint"
+	gxoerrors "github.com/gxo-labs/gxo/pkg/gxo/v1/errors"
+)
+
+// newRunID generates a sortable, human-readable run identifier such as
+// "20250102T150405Z-1a2b3c4d".
+func newRunID() string {
+	var suffix [4]byte
+	_, _ = rand.Read(suffix[:])
+	return fmt.Sprintf("%s-%s", time.Now().UTC().Format("20060102T150405Z"), hex.EncodeToString(suffix[:]))
+}
+
+// ResumeRun reloads the checkpoint for runID and continues that run. Registered
+// results, task statuses and vars are restored from the checkpoint; tasks that
+// previously Completed or were Skipped are marked as done, and only Failed,
+// Pending or interrupted tasks are dispatched again.
+func (e *Engine) ResumeRun(ctx context.Context, runID string) (*gxo.ExecutionReport, error) {
+	run, err := e.resumeRun(ctx, runID, true)
+	if err != nil {
+		return nil, err
+	}
+	return run.Wait()
+}
+
+// StartResumeRun is like ResumeRun, but returns a handle to the resumed run
+// without waiting for it to finish. Like a run started with StartPlaybook, the
+// resumed run does not copy its final state to the configured store.
+func (e *Engine) StartResumeRun(ctx context.Context, runID string) (gxo.RunHandle, error) {
+	run, err := e.resumeRun(ctx, runID, false)
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// resumeRun reloads the checkpoint for runID and starts continuing that run.
+func (e *Engine) resumeRun(ctx context.Context, runID string, publish bool) (*playbookRun, error) {
+	if e.checkpointStore == nil {
+		return nil, gxoerrors.NewConfigError("cannot resume run: no checkpoint store configured", nil)
+	}
+	cp, err := e.checkpointStore.Load(runID)
+	if err != nil {
+		return nil, gxoerrors.NewConfigError(fmt.Sprintf("failed to load checkpoint for run '%s'", runID), err)
+	}
+	e.log.Infof("Resuming run %s of playbook '%s' from checkpoint (last updated %s).", cp.RunID, cp.PlaybookName, cp.UpdatedAt.Format(time.RFC3339))
+	if _, hasPath := ctx.Value(gxo.PlaybookPathKey{}).(string); !hasPath && cp.PlaybookPath != "" {
+		ctx = context.WithValue(ctx, gxo.PlaybookPathKey{}, cp.PlaybookPath)
+	}
+	return e.startRun(ctx, []byte(cp.PlaybookYAML), cp.RunID, cp, publish)
+}
+
+// restoreFromCheckpoint marks the tasks that finished in a previous attempt of
+// this run as done and releases their dependents. It returns the nodes that
+// are ready to be dispatched.
+func (r *playbookRun) restoreFromCheckpoint(cp *checkpoint.Checkpoint) []*Node {
+	done := make(map[string]TaskStatus)
+	for id, rawStatus := range cp.TaskStatuses {
+		if _, exists := r.dag.Nodes[id]; !exists {
+			// Tasks of the on_failure and finally sections always run again.
+			r.log.Debugf("Checkpoint status for task %s is not part of the main DAG. Ignoring.", id)
+			continue
+		}
+		status := TaskStatus(rawStatus)
+		if status == StatusCompleted || status == StatusSkipped {
+			done[id] = status
+		}
+	}
+
+	// A task runs again when one of these rules requires it. Running a task
+	// again may require others to run again, so the rules are applied until
+	// none of them removes a task from done.
+	for changed := true; changed; {
+		changed = false
+		for id, node := range r.dag.Nodes {
+			if _, isDone := done[id]; isDone {
+				if r.mustRunAgain(node, done) {
+					delete(done, id)
+					changed = true
+				}
+				continue
+			}
+			// Streamed records are not checkpointed, so every task connected
+			// by a stream edge to a task that must run again has to run again
+			// too.
+			for producerID := range node.StreamDependsOn {
+				if _, producerDone := done[producerID]; producerDone {
+					r.log.Infof("Task %s will run again because its stream consumer %s must run again.", producerID, id)
+					delete(done, producerID)
+					changed = true
+				}
+			}
+			for consumerID, consumer := range node.RequiredBy {
+				if _, isStreamDep := consumer.StreamDependsOn[id]; !isStreamDep {
+					continue
+				}
+				if _, consumerDone := done[consumerID]; consumerDone {
+					r.log.Infof("Task %s will run again because its stream producer %s must run again.", consumerID, id)
+					delete(done, consumerID)
+					changed = true
+				}
+			}
+		}
+	}
+
+	r.statusMu.Lock()
+	for id, status := range done {
+		node := r.dag.Nodes[id]
+		r.taskStatuses[id] = status
+		if writeErr := r.writeTaskStatus(context.Background(), id, status); writeErr != nil {
+			r.log.Errorf("Failed to write restored status for task %s: %v", id, writeErr)
+		}
+		r.completedTasks.Add(1)
+		for _, dependent := range node.RequiredBy {
+			if _, isStreamDep := dependent.StreamDependsOn[id]; isStreamDep {
+				dependent.StreamDepsRemaining.Add(-1)
+			}
+			if _, isStateDep := dependent.StateDependsOn[id]; isStateDep {
+				dependent.StateDepsRemaining.Add(-1)
+			}
+		}
+	}
+
+	ready := make([]*Node, 0)
+	for id, node := range r.dag.Nodes {
+		if r.taskStatuses[id] == StatusPending && r.isTaskReady(node) {
+			ready = append(ready, node)
+		}
+	}
+	r.statusMu.Unlock()
+	return ready
+}
+
+// mustRunAgain reports whether a task that finished in a previous attempt
+// must run again because of tasks that are not done. A task whose dependency
+// runs again runs again too, so that its trigger rule and templates see the
+// new outcome. A block that did not finish runs again as a whole, so that its
+// rescue and always tasks see the outcome of its body.
+func (r *playbookRun) mustRunAgain(node *Node, done map[string]TaskStatus) bool {
+	for depID := range node.StateDependsOn {
+		if _, depDone := done[depID]; !depDone {
+			return true
+		}
+	}
+	for block := node.Block; block != nil; block = block.Block {
+		if _, blockDone := done[block.ID]; !blockDone {
+			return true
+		}
+	}
+	return false
+}
+
+// saveCheckpoint persists the current progress of the run to the configured
+// checkpoint store. Failures are logged but never fail the run itself.
+func (r *playbookRun) saveCheckpoint() {
+	// An included playbook is restarted with its include task on resume.
+	if r.checkpointStore == nil || r.parent != nil || r.runID == "" || r.playbook == nil || r.dag == nil {
+		return
+	}
+	r.checkpointMu.Lock()
+	defer r.checkpointMu.Unlock()
+
+	cp := &checkpoint.Checkpoint{
+		RunID:        r.runID,
+		PlaybookName: r.playbook.Name,
+		PlaybookPath: r.playbook.FilePath,
+		PlaybookYAML: string(r.playbookYAML),
+		Vars:         r.initialVars,
+		Registered:   make(map[string]interface{}),
+		TaskStatuses: make(map[string]string),
+		CreatedAt:    r.runStartTime,
+		UpdatedAt:    time.Now(),
+	}
+
+	r.statusMu.RLock()
+	for id, status := range r.taskStatuses {
+		if r.haltedTasks[id] {
+			continue
+		}
+		cp.TaskStatuses[id] = string(status)
+	}
+	r.statusMu.RUnlock()
+
+	for _, task := range config.FlattenTasks(r.playbook.Tasks) {
+		if task.Register == "" {
+			continue
+		}
+		if value, exists := r.stateManager.Get(task.Register); exists {
+			cp.Registered[task.Register] = value
+		}
+	}
+
+	if err := r.checkpointStore.Save(cp); err != nil {
+		r.log.Warnf("Failed to save checkpoint for run %s: %v", r.runID, err)
+	}
+}
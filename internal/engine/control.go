@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gxo-labs/gxo/pkg/gxo/v1/events"
+)
+
+// errTaskCancelled is the error of a task cancelled with CancelTask. Unlike a
+// cancelled run, it fails only that task: its dependents are released so that
+// their trigger rules decide whether they run.
+var errTaskCancelled = errors.New("task cancelled")
+
+// Pause stops the run from starting new tasks. Tasks already running finish
+// normally, and ready tasks wait until Resume is called.
+func (r *playbookRun) Pause() {
+	if r.paused.CompareAndSwap(false, true) {
+		r.log.Infof("Run %s paused. Running tasks will finish; no new task will start.", r.runID)
+		r.notifyPauseChanged(events.RunPaused)
+	}
+}
+
+// Resume lets a paused run start tasks again.
+func (r *playbookRun) Resume() {
+	if r.paused.CompareAndSwap(true, false) {
+		r.log.Infof("Run %s resumed.", r.runID)
+		r.notifyPauseChanged(events.RunResumed)
+	}
+}
+
+// notifyPauseChanged wakes up the scheduling loop and emits the event for the
+// transition.
+func (r *playbookRun) notifyPauseChanged(eventType events.EventType) {
+	select {
+	case r.pauseChanged <- struct{}{}:
+	default:
+	}
+	r.eventBus.Emit(events.Event{
+		Type:      eventType,
+		Timestamp: time.Now(),
+		Payload:   map[string]interface{}{"run_id": r.runID},
+	})
+}
+
+// CancelTask cancels a running task, given its name or ID, without cancelling
+// the rest of the run. The task fails once its module has returned.
+func (r *playbookRun) CancelTask(name string) error {
+	r.statusMu.RLock()
+	var node *Node
+	for id, candidate := range r.nodesByID {
+		if id == name || candidate.Task.Name == name {
+			node = candidate
+			break
+		}
+	}
+	r.statusMu.RUnlock()
+	if node == nil {
+		return fmt.Errorf("task '%s' not found in run %s", name, r.runID)
+	}
+
+	r.cancelMu.Lock()
+	cancel, running := r.taskCancels[node.ID]
+	if running {
+		r.cancelledTasks[node.ID] = true
+	}
+	r.cancelMu.Unlock()
+	if !running {
+		return fmt.Errorf("task '%s' is not running", name)
+	}
+
+	r.log.Warnf("Cancelling task %s ('%s') on request.", node.ID, node.DisplayName())
+	cancel()
+	r.eventBus.Emit(events.Event{
+		Type:      events.TaskCancelled,
+		Timestamp: time.Now(),
+		TaskName:  node.Task.Name,
+		TaskID:    node.ID,
+		Payload:   map[string]interface{}{"run_id": r.runID, "task_id": node.ID, "task_name": node.Task.Name},
+	})
+	return nil
+}
+
+// registerTaskCancel records the cancel func of a task that started running.
+func (r *playbookRun) registerTaskCancel(taskID string, cancel context.CancelFunc) {
+	r.cancelMu.Lock()
+	defer r.cancelMu.Unlock()
+	r.taskCancels[taskID] = cancel
+}
+
+// unregisterTaskCancel forgets the cancel func of a task that finished running,
+// and reports whether the task was cancelled with CancelTask.
+func (r *playbookRun) unregisterTaskCancel(taskID string) bool {
+	r.cancelMu.Lock()
+	defer r.cancelMu.Unlock()
+	delete(r.taskCancels, taskID)
+	cancelled := r.cancelledTasks[taskID]
+	delete(r.cancelledTasks, taskID)
+	return cancelled
+}
@@ -23,26 +23,98 @@ const (
 	StatusSkipped   TaskStatus = "Skipped"
 )
 
+// NodeRole describes the part a node plays within its enclosing block.
+type NodeRole string
+
+const (
+	RoleTask   NodeRole = "task"   // A task in a 'tasks' list or a block body.
+	RoleRescue NodeRole = "rescue" // A task in a block's 'rescue' list.
+	RoleAlways NodeRole = "always" // A task in a block's 'always' list.
+)
+
 // Node represents a single task within the execution graph (DAG).
 // It holds the task configuration, its dependencies, and its resolved policies.
 type Node struct {
 	Task *config.Task
 	ID   string
+	// Section is the playbook section the task belongs to ("tasks",
+	// "on_failure" or "finally").
+	Section string
+	// Role is the part the task plays within its enclosing block.
+	Role NodeRole
+	// Block is the node of the innermost block enclosing the task, or nil.
+	Block *Node
+	// BlockBody, BlockRescue and BlockAlways hold the direct members of a
+	// block. They are only set on block nodes, which run no module: they
+	// finish once all members have finished and carry the block's status.
+	BlockBody   []*Node
+	BlockRescue []*Node
+	BlockAlways []*Node
 
 	// Dependency tracking
 	StreamDependsOn map[string]*Node
 	StateDependsOn  map[string]*Node
 	RequiredBy      map[string]*Node
+	// ExplicitDependsOn holds the StateDependsOn entries declared through
+	// 'depends_on', as opposed to those inferred from templates.
+	ExplicitDependsOn map[string]*Node
+	// blockDeps marks the StateDependsOn entries that only order the parts of
+	// a block (body, rescue, always) rather than carry data.
+	blockDeps map[string]bool
 
 	// Counters for dependency resolution
 	StreamDepsRemaining atomic.Int32
 	StateDepsRemaining  atomic.Int32
 
 	Status atomic.Value
+	// upstreamFailed is set when the task was skipped because a dependency
+	// failed, so that its own dependents are not released either.
+	upstreamFailed atomic.Bool
+	// blockCause is the member task whose failure failed the block. It is
+	// only set on failed block nodes.
+	blockCause *Node
 
 	// Resolved policies for this specific task
 	TaskPolicy  *config.TaskPolicy
 	StatePolicy *config.StatePolicy
+	// ChannelPolicy is the policy of the channels the task streams records
+	// to, or nil to use the engine's default.
+	ChannelPolicy *config.ChannelPolicy
+}
+
+// IsBlock reports whether the node represents a block of tasks.
+func (n *Node) IsBlock() bool {
+	return n.Task != nil && n.Task.IsBlock()
+}
+
+// IsBlockDependency reports whether the node's dependency on the given task
+// only orders the parts of a block rather than carrying data.
+func (n *Node) IsBlockDependency(taskID string) bool {
+	return n.blockDeps[taskID]
+}
+
+// DisplayName returns the task's name, or its ID if it is unnamed.
+func (n *Node) DisplayName() string {
+	if n.Task != nil && n.Task.Name != "" {
+		return n.Task.Name
+	}
+	return n.ID
+}
+
+// TakesWorker reports whether running the node occupies a worker slot. Blocks
+// run no module, and an included playbook's tasks take their own slots.
+func (n *Node) TakesWorker() bool {
+	return !n.IsBlock() && (n.Task == nil || !n.Task.IsInclude())
+}
+
+// isInside reports whether the node is nested, at any depth, in the block.
+func (n *Node) isInside(block *Node) bool {
+	for b := n.Block; b != nil; b = b.Block {
+		if b == block {
+			return true
+		}
+	}
+	return false
 }
 
 // DAG represents the entire Directed Acyclic Graph for a playbook.
@@ -57,8 +129,21 @@ func BuildDAG(
 	stateReader state.StateReader,
 	renderer template.Renderer,
 ) (*DAG, []*Node, error) {
+	return BuildSectionDAG(playbook, config.SectionTasks, stateReader, renderer)
+}
 
-	tasks := playbook.Tasks
+// BuildSectionDAG constructs the execution graph for a single playbook section.
+// Sections run one after another, so only dependencies between tasks of the
+// same section become edges; references to tasks of earlier sections are plain
+// state reads of tasks that have already finished.
+func BuildSectionDAG(
+	playbook *config.Playbook,
+	section string,
+	stateReader state.StateReader,
+	renderer template.Renderer,
+) (*DAG, []*Node, error) {
+
+	tasks := playbook.SectionTasks(section)
 	if len(tasks) == 0 {
 		return &DAG{Nodes: make(map[string]*Node)}, []*Node{}, nil
 	}
@@ -67,110 +152,262 @@ func BuildDAG(
 		Nodes: make(map[string]*Node, len(tasks)),
 	}
 
-	nameToTaskID := make(map[string]string)
-	registeredVarsToTaskID := make(map[string]string)
+	builder := &dagBuilder{
+		playbook:               playbook,
+		section:                section,
+		dag:                    dag,
+		nameToTaskID:           make(map[string]string),
+		registeredVarsToTaskID: make(map[string]string),
+	}
+	nameToTaskID := builder.nameToTaskID
+	registeredVarsToTaskID := builder.registeredVarsToTaskID
 
-	// Pass 1: Create nodes and resolve policies for each task.
-	for i := range tasks {
-		task := &tasks[i]
-		if task.InternalID == "" {
-			return nil, nil, fmt.Errorf("internal error: task at index %d has no InternalID during DAG build", i)
+	// Pass 1: Create nodes and resolve policies for each task, flattening
+	// blocks into their member tasks.
+	if _, err := builder.addTasks(tasks, nil, RoleTask); err != nil {
+		return nil, nil, err
+	}
+
+	// Pass 2: Add dependency edges based on stream inputs and template variables.
+	for _, node := range dag.Nodes {
+		for _, producerName := range node.Task.StreamInputs {
+			if _, exists := nameToTaskID[producerName]; !exists {
+				return nil, nil, gxoerrors.NewConfigError(fmt.Sprintf("task '%s' has stream_inputs referencing undefined task '%s'", node.Task.Name, producerName), nil)
+			}
 		}
 
-		// --- Policy Resolution ---
-		resolvedTaskPolicy := &config.TaskPolicy{SkipOnNoInput: new(bool)}
-		*resolvedTaskPolicy.SkipOnNoInput = false
-		resolvedStatePolicy := &config.StatePolicy{AccessMode: config.StateAccessDeepCopy}
+		for _, dependencyName := range node.Task.DependsOn {
+			dependencyID, exists := nameToTaskID[dependencyName]
+			if !exists {
+				// Tasks of earlier sections have already finished.
+				if playbookDeclaresTask(playbook, dependencyName) {
+					continue
+				}
+				return nil, nil, gxoerrors.NewConfigError(fmt.Sprintf("task '%s' has depends_on referencing undefined task '%s'", node.Task.Name, dependencyName), nil)
+			}
+			node.ExplicitDependsOn[dependencyID] = dag.Nodes[dependencyID]
+		}
 
-		if playbook.TaskPolicy != nil && playbook.TaskPolicy.SkipOnNoInput != nil {
-			resolvedTaskPolicy.SkipOnNoInput = playbook.TaskPolicy.SkipOnNoInput
+		for _, edge := range config.TaskDependencies(node.Task, nameToTaskID, registeredVarsToTaskID, renderer) {
+			if edge.Kind == config.EdgeStream {
+				addStreamEdge(dag, edge.From, node.ID)
+			} else {
+				addStateEdge(dag, edge.From, node.ID)
+			}
 		}
-		if playbook.StatePolicy != nil && playbook.StatePolicy.AccessMode != "" {
-			resolvedStatePolicy.AccessMode = playbook.StatePolicy.AccessMode
+	}
+
+	// Pass 3: Initialize dependency counters and find initial ready nodes.
+	initialReadyNodes := make([]*Node, 0)
+	for _, node := range dag.Nodes {
+		node.StreamDepsRemaining.Store(int32(len(node.StreamDependsOn)))
+		node.StateDepsRemaining.Store(int32(len(node.StateDependsOn)))
+		if node.StreamDepsRemaining.Load() == 0 && node.StateDepsRemaining.Load() == 0 {
+			initialReadyNodes = append(initialReadyNodes, node)
 		}
+	}
+
+	if err := detectCycle(dag); err != nil {
+		return nil, nil, err
+	}
 
-		if task.Policy != nil && task.Policy.SkipOnNoInput != nil {
-			resolvedTaskPolicy.SkipOnNoInput = task.Policy.SkipOnNoInput
+	return dag, initialReadyNodes, nil
+}
+
+// dagBuilder holds the state shared while the tasks of a section, including
+// the members of nested blocks, are turned into nodes.
+type dagBuilder struct {
+	playbook               *config.Playbook
+	section                string
+	dag                    *DAG
+	nameToTaskID           map[string]string
+	registeredVarsToTaskID map[string]string
+}
+
+// addTasks creates a node for each task and, for blocks, for all of their
+// members. Members of a block inherit its directives, and are ordered so that
+// the rescue tasks run after the body, the always tasks after both, and the
+// block node itself last. It returns the nodes created for the listed tasks.
+func (b *dagBuilder) addTasks(tasks []config.Task, enclosing *Node, role NodeRole) ([]*Node, error) {
+	nodes := make([]*Node, 0, len(tasks))
+	for i := range tasks {
+		task := &tasks[i]
+		if task.InternalID == "" {
+			return nil, fmt.Errorf("internal error: task at index %d has no InternalID during DAG build", i)
 		}
-		if task.StatePolicy != nil && task.StatePolicy.AccessMode != "" {
-			resolvedStatePolicy.AccessMode = task.StatePolicy.AccessMode
+		// The task's named policy applies before the block's directives, so
+		// that it takes precedence over them.
+		if task.PolicyRef != "" {
+			resolved, err := applyNamedPolicy(b.playbook, *task)
+			if err != nil {
+				return nil, err
+			}
+			task = &resolved
+		}
+		if enclosing != nil {
+			inherited := inheritBlockDirectives(*task, enclosing.Task)
+			task = &inherited
+		} else if b.playbook != nil && len(b.playbook.Environment) > 0 {
+			withEnvironment := *task
+			withEnvironment.Environment = mergeEnvironment(b.playbook.Environment, task.Environment)
+			task = &withEnvironment
 		}
 
+		taskPolicy, statePolicy, channelPolicy := resolvePolicies(b.playbook, task)
 		node := &Node{
-			Task:            task,
-			ID:              task.InternalID,
-			StreamDependsOn: make(map[string]*Node),
-			StateDependsOn:  make(map[string]*Node),
-			RequiredBy:      make(map[string]*Node),
-			TaskPolicy:      resolvedTaskPolicy,
-			StatePolicy:     resolvedStatePolicy,
+			Task:              task,
+			ID:                task.InternalID,
+			Section:           b.section,
+			Role:              role,
+			Block:             enclosing,
+			StreamDependsOn:   make(map[string]*Node),
+			StateDependsOn:    make(map[string]*Node),
+			RequiredBy:        make(map[string]*Node),
+			ExplicitDependsOn: make(map[string]*Node),
+			blockDeps:         make(map[string]bool),
+			TaskPolicy:        taskPolicy,
+			StatePolicy:       statePolicy,
+			ChannelPolicy:     channelPolicy,
 		}
 		node.Status.Store(StatusPending)
-		dag.Nodes[task.InternalID] = node
+		b.dag.Nodes[task.InternalID] = node
 
 		if task.Name != "" {
-			nameToTaskID[task.Name] = task.InternalID
+			b.nameToTaskID[task.Name] = task.InternalID
 		}
 		if task.Register != "" {
-			registeredVarsToTaskID[task.Register] = task.InternalID
+			b.registeredVarsToTaskID[task.Register] = task.InternalID
 		}
-	}
 
-	// Pass 2: Add dependency edges based on stream inputs and template variables.
-	for _, node := range dag.Nodes {
-		for _, producerName := range node.Task.StreamInputs {
-			producerID, exists := nameToTaskID[producerName]
-			if !exists {
-				return nil, nil, gxoerrors.NewConfigError(fmt.Sprintf("task '%s' has stream_inputs referencing undefined task '%s'", node.Task.Name, producerName), nil)
+		if task.IsBlock() {
+			var err error
+			if node.BlockBody, err = b.addTasks(task.Block, node, RoleTask); err != nil {
+				return nil, err
 			}
-			addStreamEdge(dag, producerID, node.ID)
-		}
-
-		templatesToScan := collectTemplatesToScan(node.Task)
-		for _, tmplStr := range templatesToScan {
-			if tmplStr == "" {
-				continue
+			if node.BlockRescue, err = b.addTasks(task.Rescue, node, RoleRescue); err != nil {
+				return nil, err
 			}
-			vars, err := renderer.ExtractVariables(tmplStr)
-			if err != nil {
-				continue
+			if node.BlockAlways, err = b.addTasks(task.Always, node, RoleAlways); err != nil {
+				return nil, err
 			}
+			addBlockEdges(b.dag, node.BlockBody, node.BlockRescue)
+			addBlockEdges(b.dag, append(node.BlockBody, node.BlockRescue...), node.BlockAlways)
+			addBlockEdges(b.dag, append(append(node.BlockBody, node.BlockRescue...), node.BlockAlways...), []*Node{node})
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
 
-			for _, fullVarPath := range vars {
-				var producerID string
-				var exists bool
-				if strings.HasPrefix(fullVarPath, template.GxoStateKeyPrefix+".tasks.") {
-					parts := strings.Split(fullVarPath, ".")
-					if len(parts) == 4 {
-						producerName := parts[2]
-						producerID, exists = nameToTaskID[producerName]
-					}
-				} else {
-					producerID, exists = registeredVarsToTaskID[fullVarPath]
-				}
+// inheritBlockDirectives returns a copy of a block member with the block's
+// directives applied. The member's own settings take precedence, except for
+// 'when', which is combined, and 'ignore_errors', which either may set. Vars
+// and environment variables are merged, the member's overriding the block's.
+func inheritBlockDirectives(member config.Task, block *config.Task) config.Task {
+	member.InheritedWhen = append(append([]string{}, block.InheritedWhen...), block.When)
+	member.DependsOn = append(append([]string{}, block.DependsOn...), member.DependsOn...)
+	member.Vars = mergeVars(block.Vars, member.Vars)
+	member.Environment = mergeEnvironment(block.Environment, member.Environment)
+	if member.Retry == nil {
+		member.Retry = block.Retry
+	}
+	if member.Timeout == "" {
+		member.Timeout = block.Timeout
+	}
+	member.IgnoreErrors = member.IgnoreErrors || block.IgnoreErrors
+	if member.Policy == nil {
+		member.Policy = block.Policy
+	}
+	if member.StatePolicy == nil {
+		member.StatePolicy = block.StatePolicy
+	}
+	if member.PolicyRef == "" {
+		member.PolicyRef = block.PolicyRef
+	}
+	return member
+}
 
-				if exists && producerID != "" && producerID != node.ID {
-					addStateEdge(dag, producerID, node.ID)
-				}
+// applyNamedPolicy returns a copy of a task with the settings of the policy it
+// references applied where the task does not set them itself.
+func applyNamedPolicy(playbook *config.Playbook, task config.Task) (config.Task, error) {
+	policy, exists := playbook.Policies[task.PolicyRef]
+	if !exists {
+		return task, fmt.Errorf("task '%s' references unknown policy '%s'", task.InternalID, task.PolicyRef)
+	}
+	if task.Retry == nil {
+		task.Retry = policy.Retry
+	}
+	if task.Timeout == "" {
+		task.Timeout = policy.Timeout
+	}
+	if task.Policy == nil {
+		task.Policy = policy.TaskPolicy
+	}
+	if task.StatePolicy == nil {
+		task.StatePolicy = policy.StatePolicy
+	}
+	return task, nil
+}
+
+// playbookDeclaresTask reports whether a task with the given name exists in
+// any section of the playbook.
+func playbookDeclaresTask(playbook *config.Playbook, name string) bool {
+	for _, section := range []string{config.SectionTasks, config.SectionOnFailure, config.SectionFinally} {
+		for _, task := range config.FlattenTasks(playbook.SectionTasks(section)) {
+			if task.Name == name {
+				return true
 			}
 		}
 	}
+	return false
+}
 
-	// Pass 3: Initialize dependency counters and find initial ready nodes.
-	initialReadyNodes := make([]*Node, 0)
-	for _, node := range dag.Nodes {
-		node.StreamDepsRemaining.Store(int32(len(node.StreamDependsOn)))
-		node.StateDepsRemaining.Store(int32(len(node.StateDependsOn)))
-		if node.StreamDepsRemaining.Load() == 0 && node.StateDepsRemaining.Load() == 0 {
-			initialReadyNodes = append(initialReadyNodes, node)
-		}
+// resolvePolicies merges the playbook-level and task-level policies. The
+// channel policy of the task's named policy overrides the playbook's.
+func resolvePolicies(playbook *config.Playbook, task *config.Task) (*config.TaskPolicy, *config.StatePolicy, *config.ChannelPolicy) {
+	resolvedTaskPolicy := &config.TaskPolicy{SkipOnNoInput: new(bool)}
+	*resolvedTaskPolicy.SkipOnNoInput = false
+	resolvedStatePolicy := &config.StatePolicy{AccessMode: config.StateAccessDeepCopy}
+
+	if playbook.TaskPolicy != nil && playbook.TaskPolicy.SkipOnNoInput != nil {
+		resolvedTaskPolicy.SkipOnNoInput = playbook.TaskPolicy.SkipOnNoInput
+	}
+	if playbook.StatePolicy != nil && playbook.StatePolicy.AccessMode != "" {
+		resolvedStatePolicy.AccessMode = playbook.StatePolicy.AccessMode
 	}
 
-	if err := detectCycle(dag); err != nil {
-		return nil, nil, err
+	if task.Policy != nil && task.Policy.SkipOnNoInput != nil {
+		resolvedTaskPolicy.SkipOnNoInput = task.Policy.SkipOnNoInput
+	}
+	if task.StatePolicy != nil && task.StatePolicy.AccessMode != "" {
+		resolvedStatePolicy.AccessMode = task.StatePolicy.AccessMode
 	}
 
-	return dag, initialReadyNodes, nil
+	resolvedChannelPolicy := playbook.ChannelPolicy
+	if policy, exists := playbook.Policies[task.PolicyRef]; exists && policy.ChannelPolicy != nil {
+		merged := config.ChannelPolicy{BufferSize: policy.ChannelPolicy.BufferSize, OverflowStrategy: policy.ChannelPolicy.OverflowStrategy}
+		if resolvedChannelPolicy != nil {
+			if merged.BufferSize == nil {
+				merged.BufferSize = resolvedChannelPolicy.BufferSize
+			}
+			if merged.OverflowStrategy == "" {
+				merged.OverflowStrategy = resolvedChannelPolicy.OverflowStrategy
+			}
+		}
+		resolvedChannelPolicy = &merged
+	}
+	return resolvedTaskPolicy, resolvedStatePolicy, resolvedChannelPolicy
+}
+
+// addBlockEdges makes every consumer wait for every producer, marking the
+// edges as ordering-only so failures of the producers do not skip them.
+func addBlockEdges(dag *DAG, producers, consumers []*Node) {
+	for _, consumer := range consumers {
+		for _, producer := range producers {
+			addStateEdge(dag, producer.ID, consumer.ID)
+			consumer.blockDeps[producer.ID] = true
+		}
+	}
 }
 
 func addStreamEdge(dag *DAG, producerID, consumerID string) {
@@ -191,57 +428,30 @@ func addStateEdge(dag *DAG, producerID, consumerID string) {
 	}
 }
 
+// detectCycle reports every cycle among the tasks, with the kind of every
+// edge on it.
 func detectCycle(dag *DAG) error {
-	path := make(map[string]bool)
-	visited := make(map[string]bool)
-
-	for id := range dag.Nodes {
-		if !visited[id] {
-			if hasCycleDFS(dag, id, path, visited) {
-				// The cycle error message will be implicitly more useful now,
-				// as the DAG is correctly built.
-				return gxoerrors.NewConfigError("cycle detected in task dependencies", nil)
-			}
-		}
-	}
-	return nil
-}
-
-func hasCycleDFS(dag *DAG, nodeID string, path map[string]bool, visited map[string]bool) bool {
-	node := dag.Nodes[nodeID]
-	path[nodeID] = true
-	visited[nodeID] = true
-
-	for dependentID := range node.RequiredBy {
-		if path[dependentID] {
-			return true
+	var edges []config.DependencyEdge
+	for _, node := range dag.Nodes {
+		for _, producer := range node.StreamDependsOn {
+			edges = append(edges, config.DependencyEdge{From: producer.DisplayName(), To: node.DisplayName(), Kind: config.EdgeStream})
 		}
-		if !visited[dependentID] {
-			if hasCycleDFS(dag, dependentID, path, visited) {
-				return true
+		for producerID, producer := range node.StateDependsOn {
+			kind := config.EdgeState
+			if node.blockDeps[producerID] {
+				kind = config.EdgeBlock
 			}
+			edges = append(edges, config.DependencyEdge{From: producer.DisplayName(), To: node.DisplayName(), Kind: kind})
 		}
 	}
 
-	path[nodeID] = false
-	return false
-}
-
-// collectTemplatesToScan now correctly includes the 'when' clause.
-func collectTemplatesToScan(task *config.Task) []string {
-	templates := []string{}
-	if task.When != "" {
-		templates = append(templates, task.When)
+	cycles := config.FindCycles(edges)
+	if len(cycles) == 0 {
+		return nil
 	}
-	if loopStr, ok := task.Loop.(string); ok && loopStr != "" {
-		templates = append(templates, loopStr)
+	formatted := make([]string, len(cycles))
+	for i, cycle := range cycles {
+		formatted[i] = config.FormatCycle(cycle)
 	}
-	for _, paramValue := range task.Params {
-		if strValue, ok := paramValue.(string); ok {
-			if strings.Contains(strValue, "{{") && strings.Contains(strValue, "}}") {
-				templates = append(templates, strValue)
-			}
-		}
-	}
-	return templates
-}
\ No newline at end of file
+	return gxoerrors.NewConfigError(fmt.Sprintf("cycle detected in task dependencies: %s", strings.Join(formatted, "; ")), nil)
+}
@@ -8,10 +8,11 @@ import (
 	"runtime"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	gxo "github.com/gxo-labs/gxo/pkg/gxo/v1"
+	"github.com/gxo-labs/gxo/pkg/gxo/v1/cache"
+	"github.com/gxo-labs/gxo/pkg/gxo/v1/checkpoint"
 	"github.com/gxo-labs/gxo/pkg/gxo/v1/events"
 	gxoerrors "github.com/gxo-labs/gxo/pkg/gxo/v1/errors"
 	gxolog "github.com/gxo-labs/gxo/pkg/gxo/v1/log"
@@ -27,7 +28,6 @@ import (
 	"github.com/gxo-labs/gxo/internal/module"
 	"github.com/gxo-labs/gxo/internal/retry"
 	intSecrets "github.com/gxo-labs/gxo/internal/secrets"
-	intState "github.com/gxo-labs/gxo/internal/state"
 	"github.com/gxo-labs/gxo/internal/template"
 	intTracing "github.com/gxo-labs/gxo/internal/tracing"
 
@@ -42,9 +42,13 @@ const (
 	tracerName             = "gxo-engine"
 )
 
-// Engine is the core orchestration component of GXO.
+// Engine is the core orchestration component of GXO. It holds the providers
+// and configuration shared by all runs; the state of each run is kept in its
+// own playbookRun, so that an engine can execute several playbooks at once.
 type Engine struct {
 	// Core Services & Providers
+	// stateManager is the store configured with SetStateStore. It is nil if
+	// none was configured, in which case every run gets its own in-memory store.
 	stateManager    gxov1state.Store
 	secretsProvider secrets.Provider
 	eventBus        events.Bus
@@ -52,10 +56,10 @@ type Engine struct {
 	metricsProvider metrics.RegistryProvider
 	tracerProvider  gxotracing.TracerProvider
 	log             gxolog.Logger
-	channelManager  *ChannelManager
 	retryHelper     *retry.Helper
-	taskRunner      *TaskRunner
 	hooks           []module.ExecutionHook
+	checkpointStore checkpoint.Store
+	cacheStore      cache.Store
 
 	// Configuration & Policies
 	workerPoolSize        int
@@ -65,25 +69,21 @@ type Engine struct {
 	redactedKeywordsSlice []string
 	stallPolicy           *config.StallPolicy
 
-	// Runtime State
-	workQueue        chan string
-	dag              *DAG
-	totalTasks       int32
-	completedTasks   atomic.Int32
-	activeWorkers    atomic.Int32
-	readyChan        chan string
-	taskStatuses     map[string]TaskStatus
-	statusMu         sync.RWMutex
-	taskTimings      map[string]taskTiming
-	timingsMu        sync.RWMutex
-	taskErrors       map[string]error
-	errorsMu         sync.Mutex
+	// Shared Runtime State
+	// workerSlots bounds the number of tasks executing at once across all runs.
+	workerSlots chan struct{}
+	// publishMu serializes copying the final state of runs to the top level
+	// of the configured stateManager, so that each copy is made as a whole.
+	publishMu sync.Mutex
 
 	// Metrics Collectors
 	playbookCounter        *prometheus.CounterVec
 	playbookDuration       prometheus.Histogram
 	taskDuration           *prometheus.HistogramVec
 	taskCounter            *prometheus.CounterVec
+	taskChangedCounter     *prometheus.CounterVec
+	taskCacheHitCounter    *prometheus.CounterVec
+	taskCacheMissCounter   *prometheus.CounterVec
 	activeWorkersGauge     prometheus.Gauge
 	secretsAccessEvents    prometheus.Counter
 	secretsRedactedCounter prometheus.Counter
@@ -103,9 +103,6 @@ func NewEngine(log gxolog.Logger, opts ...gxo.EngineOption) (*Engine, error) {
 
 	e := &Engine{
 		log:              log,
-		taskStatuses:     make(map[string]TaskStatus),
-		taskTimings:      make(map[string]taskTiming),
-		taskErrors:       make(map[string]error),
 		hooks:            []module.ExecutionHook{},
 		workerPoolSize:   runtime.NumCPU(),
 		redactedKeywords: make(map[string]struct{}),
@@ -123,8 +120,7 @@ func NewEngine(log gxolog.Logger, opts ...gxo.EngineOption) (*Engine, error) {
 	}
 
 	if e.stateManager == nil {
-		e.log.Warnf("No state store provided, using default in-memory store.")
-		e.stateManager = intState.NewMemoryStateStore()
+		e.log.Debugf("No state store provided, each run will use its own in-memory store.")
 	}
 	if e.secretsProvider == nil {
 		e.log.Warnf("No secrets provider provided, using default environment provider.")
@@ -155,26 +151,13 @@ func NewEngine(log gxolog.Logger, opts ...gxo.EngineOption) (*Engine, error) {
 		_ = e.SetRedactedKeywords(e.redactedKeywordsSlice)
 	}
 
-	e.channelManager = NewChannelManager(e.defaultChannelPolicy)
 	e.retryHelper = retry.NewHelper(e.log)
 	e.retryHelper.SetRedactedKeywords(e.redactedKeywords)
-
-	e.taskRunner = NewTaskRunner(
-		e.stateManager,
-		e.pluginRegistry,
-		e.log,
-		e.channelManager,
-		e.retryHelper,
-		e.eventBus,
-		e.hooks,
-		e.secretsProvider,
-		e.tracerProvider,
-		e.redactedKeywords,
-		e.defaultTimeout,
-	)
+	if e.workerSlots == nil {
+		e.workerSlots = make(chan struct{}, e.workerPoolSize)
+	}
 
 	e.initMetrics()
-	e.taskRunner.secretsRedactedCounter = e.secretsRedactedCounter
 
 	return e, nil
 }
@@ -217,6 +200,24 @@ func (e *Engine) initMetrics() {
 	)
 	reg.MustRegister(e.taskCounter)
 
+	e.taskChangedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "gxo_task_changed_total", Help: "Total number of completed task executions that reported a change."},
+		[]string{"playbook_name", "task_name", "task_type"},
+	)
+	reg.MustRegister(e.taskChangedCounter)
+
+	e.taskCacheHitCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "gxo_task_cache_hits_total", Help: "Total number of task executions whose summary was restored from the cache."},
+		[]string{"playbook_name", "task_name", "task_type"},
+	)
+	reg.MustRegister(e.taskCacheHitCounter)
+
+	e.taskCacheMissCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "gxo_task_cache_misses_total", Help: "Total number of executions of tasks with a cache that ran their module."},
+		[]string{"playbook_name", "task_name", "task_type"},
+	)
+	reg.MustRegister(e.taskCacheMissCounter)
+
 	e.activeWorkersGauge = prometheus.NewGauge(
 		prometheus.GaugeOpts{Name: "gxo_engine_active_workers", Help: "Number of currently active task execution workers."},
 	)
@@ -251,12 +252,45 @@ func (e *Engine) initMetrics() {
 	e.log.Debugf("Prometheus metrics initialized and registered.")
 }
 
-func (e *Engine) RunPlaybook(ctx context.Context, playbookYAML []byte) (finalReport *gxo.ExecutionReport, finalErr error) {
-	tracer := e.tracerProvider.GetTracer(tracerName)
+// RunPlaybook executes a playbook from its raw YAML content under a freshly
+// generated run ID, and waits for the run to finish. Like a run started with
+// StartPlaybook, it keeps its state apart from concurrent runs; once it
+// finished, its final state is copied to the top level of the configured
+// state store.
+func (e *Engine) RunPlaybook(ctx context.Context, playbookYAML []byte) (*gxo.ExecutionReport, error) {
+	run, err := e.startRun(ctx, playbookYAML, newRunID(), nil, true)
+	if err != nil {
+		return nil, err
+	}
+	return run.Wait()
+}
+
+// StartPlaybook starts executing a playbook from its raw YAML content under a
+// freshly generated run ID, and returns without waiting for it to finish.
+// Errors loading or executing the playbook are reported by the handle's Wait.
+// The run keeps its state in its own namespace of the configured state store.
+func (e *Engine) StartPlaybook(ctx context.Context, playbookYAML []byte) (gxo.RunHandle, error) {
+	run, err := e.startRun(ctx, playbookYAML, newRunID(), nil, false)
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// executeRun performs a full playbook run. When resumeFrom is non-nil, the run
+// continues from that checkpoint instead of starting from scratch.
+func (r *playbookRun) executeRun(ctx context.Context, resumeFrom *checkpoint.Checkpoint) (finalReport *gxo.ExecutionReport, finalErr error) {
+	tracer := r.tracerProvider.GetTracer(tracerName)
 	runCtx, span := tracer.Start(ctx, "gxo.playbook.run")
 	defer span.End()
 
 	startTime := time.Now()
+	runID := r.runID
+	r.runStartTime = startTime
+	if resumeFrom != nil && !resumeFrom.CreatedAt.IsZero() {
+		r.runStartTime = resumeFrom.CreatedAt
+	}
+	span.SetAttributes(attribute.String("gxo.run.id", runID))
 	var playbook *config.Playbook
 	var loadErr error
 
@@ -269,13 +303,13 @@ func (e *Engine) RunPlaybook(ctx context.Context, playbookYAML []byte) (finalRep
 		}
 
 		if finalReport == nil {
-			finalReport = e.generateReport(pbName, startTime, endTime, finalErr)
+			finalReport = r.generateReport(pbName, startTime, endTime, finalErr)
 		} else {
 			finalReport.StartTime = startTime
 			finalReport.EndTime = endTime
 			finalReport.Duration = duration
 			if finalReport.Error == "" && finalErr != nil {
-				finalReport.Error = template.RedactSecretsInError(finalErr, e.redactedKeywords).Error()
+				finalReport.Error = template.RedactSecretsInError(finalErr, r.redactedKeywords).Error()
 				finalReport.OverallStatus = "Failed"
 			}
 		}
@@ -287,11 +321,11 @@ func (e *Engine) RunPlaybook(ctx context.Context, playbookYAML []byte) (finalRep
 			status = finalReport.OverallStatus
 		}
 
-		if e.playbookDuration != nil {
-			e.playbookDuration.Observe(duration.Seconds())
+		if r.playbookDuration != nil {
+			r.playbookDuration.Observe(duration.Seconds())
 		}
-		if e.playbookCounter != nil {
-			e.playbookCounter.WithLabelValues(pbName, status).Inc()
+		if r.playbookCounter != nil {
+			r.playbookCounter.WithLabelValues(pbName, status).Inc()
 		}
 
 		span.SetAttributes(
@@ -302,115 +336,204 @@ func (e *Engine) RunPlaybook(ctx context.Context, playbookYAML []byte) (finalRep
 			attribute.Int("gxo.playbook.completed_tasks", finalReport.CompletedTasks),
 			attribute.Int("gxo.playbook.failed_tasks", finalReport.FailedTasks),
 			attribute.Int("gxo.playbook.skipped_tasks", finalReport.SkippedTasks),
+			attribute.Int("gxo.playbook.changed_tasks", finalReport.ChangedTasks),
+			attribute.Int("gxo.playbook.cache_hits", finalReport.CacheHits),
 		)
 		if finalErr != nil {
-			intTracing.RecordErrorWithContext(span, finalErr, e.redactedKeywords)
+			intTracing.RecordErrorWithContext(span, finalErr, r.redactedKeywords)
 		} else {
 			span.SetStatus(codes.Ok, "")
 		}
 
-		e.emitFinalEvents(finalReport)
-		e.log.Infof("Playbook execution finished.")
+		r.emitFinalEvents(finalReport)
+		r.log.Infof("Playbook execution finished.")
 	}()
 
-	playbook, loadErr = config.LoadPlaybook(playbookYAML, "playbook.yaml")
+	if r.preloaded != nil {
+		playbook = r.preloaded
+	} else {
+		playbookPath := "playbook.yaml"
+		if path, ok := ctx.Value(gxo.PlaybookPathKey{}).(string); ok && path != "" {
+			playbookPath = path
+		}
+		playbook, loadErr = config.LoadPlaybook(r.playbookYAML, playbookPath)
+	}
 	if loadErr != nil {
-		e.log.Errorf("Failed to load or validate playbook: %v", loadErr)
+		r.log.Errorf("Failed to load or validate playbook: %v", loadErr)
 		finalErr = loadErr
-		intTracing.RecordErrorWithContext(span, finalErr, e.redactedKeywords)
+		intTracing.RecordErrorWithContext(span, finalErr, r.redactedKeywords)
 		span.SetStatus(codes.Error, "Playbook load/validation failed")
 		return nil, finalErr
 	}
-	e.log.Infof("Starting playbook execution: %s (Schema: %s)", playbook.Name, playbook.SchemaVersion)
+	r.statusMu.Lock()
+	r.playbook = playbook
+	r.statusMu.Unlock()
+	r.log.Infof("Starting playbook execution: %s (Schema: %s, Run ID: %s)", playbook.Name, playbook.SchemaVersion, runID)
 	span.SetAttributes(attribute.String("gxo.playbook.name", playbook.Name))
 
-	e.eventBus.Emit(events.Event{Type: events.PlaybookStart, Timestamp: startTime, PlaybookName: playbook.Name, Payload: map[string]interface{}{"playbook_name": playbook.Name}})
-
-	e.taskStatuses = make(map[string]TaskStatus)
-	e.taskTimings = make(map[string]taskTiming)
-	e.taskErrors = make(map[string]error)
-	e.completedTasks.Store(0)
-	e.activeWorkers.Store(0)
+	r.eventBus.Emit(events.Event{Type: events.PlaybookStart, Timestamp: startTime, PlaybookName: playbook.Name, Payload: map[string]interface{}{"playbook_name": playbook.Name, "run_id": runID}})
 
 	runCtx, cancelRun := context.WithCancel(runCtx)
 	defer cancelRun()
 
-	if err := e.stateManager.Load(playbook.Vars); err != nil {
-		e.log.Errorf("Failed to load initial playbook variables: %v", err)
+	initialVars := playbook.Vars
+	if resumeFrom != nil {
+		initialVars = resumeFrom.Vars
+	}
+	if len(r.includeVars) > 0 {
+		initialVars = mergeVars(initialVars, r.includeVars)
+	}
+	if resumeFrom == nil {
+		inputs, inputErr := r.resolveInputs(ctx, playbook)
+		if inputErr != nil {
+			r.log.Errorf("Invalid playbook inputs: %v", inputErr)
+			finalErr = inputErr
+			intTracing.RecordErrorWithContext(span, finalErr, r.redactedKeywords)
+			span.SetStatus(codes.Error, "Playbook input validation failed")
+			return nil, finalErr
+		}
+		if len(inputs) > 0 {
+			initialVars = mergeVars(initialVars, inputs)
+		}
+	}
+	r.initialVars = initialVars
+	if err := r.stateManager.Load(initialVars); err != nil {
+		r.log.Errorf("Failed to load initial playbook variables: %v", err)
 		finalErr = fmt.Errorf("failed to load initial vars: %w", err)
-		intTracing.RecordErrorWithContext(span, finalErr, e.redactedKeywords)
+		intTracing.RecordErrorWithContext(span, finalErr, r.redactedKeywords)
 		return nil, finalErr
 	}
-	e.log.Debugf("Loaded initial variables into state.")
+	r.log.Debugf("Loaded initial variables into state.")
+	if resumeFrom != nil {
+		for key, value := range resumeFrom.Registered {
+			if err := r.stateManager.Set(key, value); err != nil {
+				finalErr = fmt.Errorf("failed to restore registered value '%s' from checkpoint: %w", key, err)
+				intTracing.RecordErrorWithContext(span, finalErr, r.redactedKeywords)
+				return nil, finalErr
+			}
+		}
+		r.log.Debugf("Restored %d registered values from checkpoint.", len(resumeFrom.Registered))
+	}
 
-	e.log.Infof("Building execution DAG...")
-	dummyRendererForDAG := template.NewGoRenderer(e.secretsProvider, e.eventBus, nil)
+	r.log.Infof("Building execution DAG...")
+	dummyRendererForDAG := template.NewGoRenderer(r.secretsProvider, r.eventBus, nil)
 	var initialReadyNodes []*Node
 	var buildDagErr error
-	e.dag, initialReadyNodes, buildDagErr = BuildDAG(playbook, e.stateManager, dummyRendererForDAG)
+	r.dag, initialReadyNodes, buildDagErr = BuildDAG(playbook, r.stateManager, dummyRendererForDAG)
 	if buildDagErr != nil {
-		e.log.Errorf("Failed to build DAG: %v", buildDagErr)
+		r.log.Errorf("Failed to build DAG: %v", buildDagErr)
 		finalErr = fmt.Errorf("failed to build DAG: %w", buildDagErr)
-		intTracing.RecordErrorWithContext(span, finalErr, e.redactedKeywords)
+		intTracing.RecordErrorWithContext(span, finalErr, r.redactedKeywords)
 		return nil, finalErr
 	}
-	e.totalTasks = int32(len(e.dag.Nodes))
-	if e.totalTasks == 0 {
-		e.log.Infof("Playbook has no tasks to execute.")
+	r.totalTasks = int32(len(r.dag.Nodes))
+	if r.totalTasks == 0 {
+		r.log.Infof("Playbook has no tasks to execute.")
 		span.SetAttributes(attribute.Int("gxo.playbook.total_tasks", 0))
 		return nil, nil
 	}
-	e.log.Infof("DAG built successfully. Found %d tasks. Initial ready: %d", e.totalTasks, len(initialReadyNodes))
-	span.SetAttributes(attribute.Int("gxo.playbook.total_tasks", int(e.totalTasks)))
+	r.log.Infof("DAG built successfully. Found %d tasks. Initial ready: %d", r.totalTasks, len(initialReadyNodes))
+	span.SetAttributes(attribute.Int("gxo.playbook.total_tasks", int(r.totalTasks)))
 
-	if err := e.channelManager.CreateChannels(e.dag); err != nil {
-		e.log.Errorf("Failed to create execution channels: %v", err)
+	if err := r.channelManager.CreateChannels(r.dag); err != nil {
+		r.log.Errorf("Failed to create execution channels: %v", err)
 		finalErr = fmt.Errorf("failed to create channels: %w", err)
-		intTracing.RecordErrorWithContext(span, finalErr, e.redactedKeywords)
+		intTracing.RecordErrorWithContext(span, finalErr, r.redactedKeywords)
 		return nil, finalErr
 	}
 
-	readyChanBufferSize := int(e.totalTasks) + e.workerPoolSize
-	workQueueBufferSize := e.workerPoolSize * 2
-	fatalErrChan := make(chan error, 1)
+	r.initTaskStatuses()
+
+	if selection, ok := ctx.Value(gxo.TaskSelectionKey{}).(gxo.TaskSelection); ok && !selection.IsEmpty() && resumeFrom == nil && r.parent == nil {
+		if err := r.applySelection(selection); err != nil {
+			r.log.Errorf("Failed to apply the task selection: %v", err)
+			finalErr = err
+			intTracing.RecordErrorWithContext(span, finalErr, r.redactedKeywords)
+			return nil, finalErr
+		}
+	}
 
-	e.readyChan = make(chan string, readyChanBufferSize)
-	e.workQueue = make(chan string, workQueueBufferSize)
+	tasksAccountedFor := int32(0)
+	if resumeFrom != nil {
+		initialReadyNodes = r.restoreFromCheckpoint(resumeFrom)
+		tasksAccountedFor = r.completedTasks.Load()
+		r.log.Infof("Restored %d/%d finished tasks from checkpoint. Ready to dispatch: %d", tasksAccountedFor, r.totalTasks, len(initialReadyNodes))
+	}
+	r.saveCheckpoint()
+
+	var mainErr error
+	if tasksAccountedFor >= r.totalTasks {
+		r.log.Infof("All tasks already finished in a previous attempt of run %s. Nothing to resume.", runID)
+	} else {
+		if len(initialReadyNodes) == 0 && r.totalTasks > 0 {
+			finalErr = gxoerrors.NewConfigError("no initially ready tasks found in non-empty DAG (check for cycles or dependency issues)", nil)
+			r.log.Errorf(finalErr.Error())
+			intTracing.RecordErrorWithContext(span, finalErr, r.redactedKeywords)
+			return nil, finalErr
+		}
+		mainErr = r.scheduleDAG(runCtx, initialReadyNodes, tasksAccountedFor)
+	}
+
+	finalErr = r.runSections(runCtx, r.determineFinalOutcome(mainErr))
+	if outputsErr := r.renderOutputs(playbook, finalErr == nil); outputsErr != nil {
+		r.log.Errorf("%v", outputsErr)
+		finalErr = outputsErr
+	}
+
+	return finalReport, finalErr
+}
 
-	e.statusMu.Lock()
-	e.timingsMu.Lock()
-	for id := range e.dag.Nodes {
-		e.taskStatuses[id] = StatusPending
-		e.taskTimings[id] = taskTiming{}
-		if writeErr := e.writeTaskStatus(context.Background(), id, StatusPending); writeErr != nil {
-			e.log.Errorf("Failed to write initial pending status for task %s: %v", id, writeErr)
+// initTaskStatuses marks every task of r.dag as Pending, both in memory and in
+// the state store.
+func (r *playbookRun) initTaskStatuses() {
+	r.statusMu.Lock()
+	r.timingsMu.Lock()
+	for id, node := range r.dag.Nodes {
+		r.nodesByID[id] = node
+		r.taskStatuses[id] = StatusPending
+		r.taskTimings[id] = taskTiming{}
+		if writeErr := r.writeTaskStatus(context.Background(), id, StatusPending); writeErr != nil {
+			r.log.Errorf("Failed to write initial pending status for task %s: %v", id, writeErr)
 		}
 	}
-	e.timingsMu.Unlock()
-	e.statusMu.Unlock()
+	r.timingsMu.Unlock()
+	r.statusMu.Unlock()
+}
+
+// scheduleDAG dispatches the tasks of r.dag to a pool of workers until every
+// task has reached a terminal state, execution becomes stable or stalls, or a
+// fatal error cancels the run. It returns the first fatal error observed.
+func (r *playbookRun) scheduleDAG(ctx context.Context, initialReadyNodes []*Node, tasksAccountedFor int32) error {
+	runCtx, cancelRun := context.WithCancel(ctx)
+	defer cancelRun()
+	// A failure that halts the run cancels haltCtx, which stops the running
+	// tasks but leaves runCtx to the tasks that react to the failure.
+	haltCtx, haltRun := context.WithCancel(runCtx)
+	defer haltRun()
+
+	readyChanBufferSize := int(r.totalTasks) + r.workerPoolSize
+	workQueueBufferSize := r.workerPoolSize * 2
+	fatalErrChan := make(chan error, 1)
+
+	r.readyChan = make(chan string, readyChanBufferSize)
+	r.workQueue = make(chan string, workQueueBufferSize)
 
 	var workerWg sync.WaitGroup
-	workerWg.Add(e.workerPoolSize)
+	workerWg.Add(r.workerPoolSize)
 	var runningTasksWg sync.WaitGroup
-	e.log.Infof("Starting %d execution workers...", e.workerPoolSize)
-	for i := 0; i < e.workerPoolSize; i++ {
-		go e.worker(runCtx, &workerWg, &runningTasksWg, fatalErrChan, i)
+	r.log.Infof("Starting %d execution workers...", r.workerPoolSize)
+	for i := 0; i < r.workerPoolSize; i++ {
+		go r.worker(runCtx, haltCtx, &workerWg, &runningTasksWg, fatalErrChan, i)
 	}
 	defer func() {
-		close(e.workQueue)
+		close(r.workQueue)
 		workerWg.Wait()
-		e.log.Debugf("Worker pool shutdown complete.")
+		r.log.Debugf("Worker pool shutdown complete.")
 	}()
 
-	if len(initialReadyNodes) == 0 && e.totalTasks > 0 {
-		finalErr = gxoerrors.NewConfigError("no initially ready tasks found in non-empty DAG (check for cycles or dependency issues)", nil)
-		e.log.Errorf(finalErr.Error())
-		intTracing.RecordErrorWithContext(span, finalErr, e.redactedKeywords)
-		return nil, finalErr
-	}
 	for _, node := range initialReadyNodes {
-		e.log.Debugf("Seeding ready queue with initial task: %s", node.ID)
-		e.readyChan <- node.ID
+		r.log.Debugf("Seeding ready queue with initial task: %s", node.ID)
+		r.readyChan <- node.ID
 	}
 
 	var firstFatalError error
@@ -418,104 +541,140 @@ func (e *Engine) RunPlaybook(ctx context.Context, playbookYAML []byte) (finalRep
 	var dispatchMu sync.Mutex
 	lastAccountedForCount := int32(-1)
 	stallChecks := 0
-	tasksAccountedFor := int32(0)
 
-	ticker := time.NewTicker(e.stallPolicy.Interval)
+	ticker := time.NewTicker(r.stallPolicy.Interval)
 	defer ticker.Stop()
 
-	if e.activeWorkersGauge != nil {
-		e.activeWorkersGauge.Set(0)
-	}
-
 SchedulingLoop:
-	for tasksAccountedFor < e.totalTasks {
-		if e.activeWorkersGauge != nil {
-			e.activeWorkersGauge.Set(float64(e.activeWorkers.Load()))
+	for tasksAccountedFor < r.totalTasks {
+		if r.activeWorkersGauge != nil {
+			r.activeWorkersGauge.Set(float64(len(r.workerSlots)))
+		}
+
+		// While the run is paused, ready tasks stay queued in readyChan.
+		readyChan := r.readyChan
+		if r.paused.Load() {
+			readyChan = nil
 		}
 
 		select {
-		case taskID := <-e.readyChan:
+		case <-r.pauseChanged:
+			stallChecks = 0
+			continue SchedulingLoop
+
+		case taskID := <-readyChan:
 			stallChecks = 0
+			if runCtx.Err() != nil {
+				// A cancelled run dispatches nothing more.
+				continue SchedulingLoop
+			}
+			if r.paused.Load() {
+				// Paused after the select began; requeue the task for later.
+				r.readyChan <- taskID
+				continue SchedulingLoop
+			}
 			dispatchMu.Lock()
 			if dispatchedTasks[taskID] {
 				dispatchMu.Unlock()
-				e.log.Debugf("Task %s received from readyChan but already dispatched. Ignoring duplicate.", taskID)
+				r.log.Debugf("Task %s received from readyChan but already dispatched. Ignoring duplicate.", taskID)
 				continue
 			}
 
-			e.statusMu.Lock()
-			if e.taskStatuses[taskID] != StatusPending {
-				e.log.Warnf("Task %s was ready but status is now %s, not dispatching.", taskID, e.taskStatuses[taskID])
-				e.statusMu.Unlock()
+			r.statusMu.Lock()
+			if r.taskStatuses[taskID] != StatusPending {
+				r.log.Warnf("Task %s was ready but status is now %s, not dispatching.", taskID, r.taskStatuses[taskID])
+				r.statusMu.Unlock()
 				dispatchMu.Unlock()
 				continue
 			}
 
 			dispatchedTasks[taskID] = true
-			e.taskStatuses[taskID] = StatusRunning
-			e.timingsMu.Lock()
-			e.taskTimings[taskID] = taskTiming{start: time.Now()}
-			e.timingsMu.Unlock()
-			if writeErr := e.writeTaskStatus(runCtx, taskID, StatusRunning); writeErr != nil {
-				e.log.Errorf("Failed to write running status for task %s: %v", taskID, writeErr)
+			skipErr := r.prunedTasks[taskID]
+			if skipErr == nil {
+				skipErr = r.evaluateTriggerRule(r.dag.Nodes[taskID])
+			}
+			// Once a failure halted the run, only the tasks reacting to it
+			// run; blocks still settle the outcome of their members.
+			if skipErr == nil && firstFatalError != nil && !r.dag.Nodes[taskID].IsBlock() && !handlesFailure(r.dag.Nodes[taskID]) {
+				skipErr = r.haltedSkipError()
+				r.haltedTasks[taskID] = true
+			}
+			if skipErr != nil {
+				r.statusMu.Unlock()
+				dispatchMu.Unlock()
+				r.skipWithoutRunning(runCtx, r.dag.Nodes[taskID], skipErr, fatalErrChan)
+				continue
 			}
-			e.statusMu.Unlock()
+			r.taskStatuses[taskID] = StatusRunning
+			r.timingsMu.Lock()
+			r.taskTimings[taskID] = taskTiming{start: time.Now()}
+			r.timingsMu.Unlock()
+			if writeErr := r.writeTaskStatus(runCtx, taskID, StatusRunning); writeErr != nil {
+				r.log.Errorf("Failed to write running status for task %s: %v", taskID, writeErr)
+			}
+			r.statusMu.Unlock()
 			dispatchMu.Unlock()
 
-			e.log.Infof("Dispatching task to worker queue: %s", taskID)
-			node := e.dag.Nodes[taskID]
-			e.signalStreamDependents(node)
+			r.log.Infof("Dispatching task to worker queue: %s", taskID)
+			node := r.dag.Nodes[taskID]
+			r.signalStreamDependents(node)
 			runningTasksWg.Add(1)
 
 			select {
-			case e.workQueue <- taskID:
+			case r.workQueue <- taskID:
 			case <-runCtx.Done():
-				e.log.Warnf("Context cancelled while trying to dispatch task %s", taskID)
+				r.log.Warnf("Context cancelled while trying to dispatch task %s", taskID)
 				runningTasksWg.Done()
-				e.handleTaskCompletion(runCtx, taskID, StatusFailed, runCtx.Err(), fatalErrChan, true)
+				r.handleTaskCompletion(runCtx, taskID, StatusFailed, runCtx.Err(), fatalErrChan, true)
 			}
 
 		case err := <-fatalErrChan:
 			stallChecks = 0
-			redactedErr := template.RedactSecretsInError(err, e.redactedKeywords)
-			e.log.Errorf("Received fatal error signal: %v. Initiating cancellation.", redactedErr)
+			redactedErr := template.RedactSecretsInError(err, r.redactedKeywords)
+			r.log.Errorf("Received fatal error signal: %v. Initiating cancellation.", redactedErr)
 			if firstFatalError == nil {
 				firstFatalError = err
 			}
-			cancelRun()
+			haltRun()
 
 		case <-runCtx.Done():
-			e.log.Warnf("Playbook context cancelled (%v), terminating scheduling loop.", runCtx.Err())
+			r.log.Warnf("Playbook context cancelled (%v), terminating scheduling loop.", runCtx.Err())
 			if firstFatalError == nil {
 				firstFatalError = runCtx.Err()
 			}
 			break SchedulingLoop
 
 		case <-ticker.C:
-			currentAccounted := e.countTerminalTasks()
+			currentAccounted := r.countTerminalTasks()
 			tasksAccountedFor = currentAccounted
 
-			if tasksAccountedFor >= e.totalTasks {
+			if tasksAccountedFor >= r.totalTasks {
+				continue SchedulingLoop
+			}
+			if r.paused.Load() {
+				// A paused run makes no progress on purpose.
+				stallChecks = 0
+				lastAccountedForCount = currentAccounted
 				continue SchedulingLoop
 			}
 
-			activeWorkersCount := e.activeWorkers.Load()
-			runnablePendingTasksCount := e.countRunnablePendingTasks()
+			activeWorkersCount := r.activeWorkers.Load()
+			runnablePendingTasksCount := r.countRunnablePendingTasks()
 
 			if activeWorkersCount == 0 && runnablePendingTasksCount == 0 {
-				if e.hasPendingTasks() {
-					e.log.Infof("Execution stable: No active workers or runnable pending tasks. Blocked tasks remain.")
+				if r.hasPendingTasks() {
+					r.log.Infof("Execution stable: No active workers or runnable pending tasks. Blocked tasks remain.")
 					break SchedulingLoop
 				}
 			}
 
 			if currentAccounted == lastAccountedForCount {
 				stallChecks++
-				if stallChecks >= e.stallPolicy.Tolerance {
-					if !(activeWorkersCount == 0 && runnablePendingTasksCount == 0 && e.hasPendingTasks()) {
+				if stallChecks >= r.stallPolicy.Tolerance {
+					if !(activeWorkersCount == 0 && runnablePendingTasksCount == 0 && r.hasPendingTasks()) {
 						stallMsg := fmt.Sprintf("playbook execution stalled: %d/%d tasks accounted for, %d active workers, %d runnable tasks. No progress for %v.",
-							currentAccounted, e.totalTasks, activeWorkersCount, runnablePendingTasksCount, time.Duration(stallChecks)*e.stallPolicy.Interval)
-						e.log.Log(slog.LevelError, stallMsg)
+							currentAccounted, r.totalTasks, activeWorkersCount, runnablePendingTasksCount, time.Duration(stallChecks)*r.stallPolicy.Interval)
+						r.log.Log(slog.LevelError, stallMsg)
 						if firstFatalError == nil {
 							firstFatalError = errors.New("playbook execution stalled")
 						}
@@ -532,7 +691,7 @@ SchedulingLoop:
 		}
 	}
 
-	e.log.Debugf("Main scheduling loop finished. Tasks accounted for: %d/%d", tasksAccountedFor, e.totalTasks)
+	r.log.Debugf("Main scheduling loop finished. Tasks accounted for: %d/%d", tasksAccountedFor, r.totalTasks)
 
 	waitChan := make(chan struct{})
 	go func() {
@@ -542,53 +701,61 @@ SchedulingLoop:
 	waitTimeout := 10 * time.Second
 	select {
 	case <-waitChan:
-		e.log.Debugf("All dispatched tasks WaitGroup finished.")
+		r.log.Debugf("All dispatched tasks WaitGroup finished.")
 	case <-time.After(waitTimeout):
-		e.log.Errorf("Timeout (%v) waiting for running tasks WaitGroup after main loop exit.", waitTimeout)
+		r.log.Errorf("Timeout (%v) waiting for running tasks WaitGroup after main loop exit.", waitTimeout)
 		if firstFatalError == nil {
 			firstFatalError = fmt.Errorf("timeout waiting for running tasks WaitGroup")
 		}
 	case <-runCtx.Done():
-		e.log.Warnf("Context cancelled while waiting for running tasks WaitGroup: %v", runCtx.Err())
+		r.log.Warnf("Context cancelled while waiting for running tasks WaitGroup: %v", runCtx.Err())
 		if firstFatalError == nil {
 			firstFatalError = fmt.Errorf("cancelled while waiting for running tasks WaitGroup: %w", runCtx.Err())
 		}
 	}
 
-	finalErr = e.determineFinalOutcome(firstFatalError)
-
-	return finalReport, finalErr
+	return firstFatalError
 }
 
-func (e *Engine) worker(
+func (r *playbookRun) worker(
 	ctx context.Context,
+	haltCtx context.Context,
 	workerWg *sync.WaitGroup,
 	runningTasksWg *sync.WaitGroup,
 	fatalErrChan chan<- error,
 	workerID int,
 ) {
 	defer workerWg.Done()
-	workerLogger := e.log.With("worker_id", workerID)
+	workerLogger := r.log.With("worker_id", workerID)
 	workerLogger.Debugf("Worker started.")
-	tracer := e.tracerProvider.GetTracer(tracerName)
+	tracer := r.tracerProvider.GetTracer(tracerName)
 
 	for {
 		select {
-		case taskID, ok := <-e.workQueue:
+		case taskID, ok := <-r.workQueue:
 			if !ok {
 				workerLogger.Debugf("Work queue closed, worker exiting.")
 				return
 			}
 
 			func() {
-				e.activeWorkers.Add(1)
-				defer e.activeWorkers.Add(-1)
 				defer runningTasksWg.Done()
+				node, exists := r.dag.Nodes[taskID]
+				// Worker slots are shared by all runs of the engine. If the run
+				// is cancelled while waiting for one, the task fails below.
+				if !exists || node.TakesWorker() {
+					select {
+					case r.workerSlots <- struct{}{}:
+						defer func() { <-r.workerSlots }()
+					case <-ctx.Done():
+					}
+				}
+				r.activeWorkers.Add(1)
+				defer r.activeWorkers.Add(-1)
 
-				node, exists := e.dag.Nodes[taskID]
 				if !exists {
 					workerLogger.Errorf("Worker received unknown task ID from queue: %s", taskID)
-					e.handleTaskCompletion(ctx, taskID, StatusFailed, fmt.Errorf("task %s definition not found in DAG", taskID), fatalErrChan, true)
+					r.handleTaskCompletion(ctx, taskID, StatusFailed, fmt.Errorf("task %s definition not found in DAG", taskID), fatalErrChan, true)
 					return
 				}
 
@@ -598,16 +765,24 @@ func (e *Engine) worker(
 				}
 				taskLogger = taskLogger.With("task_id", taskID)
 
-				taskExecCtx := ctx
+				taskExecCtx := haltCtx
+				if handlesFailure(node) {
+					taskExecCtx = ctx
+				}
 
 				if taskExecCtx.Err() != nil {
 					taskLogger.Warnf("Context cancelled/timed out before worker could start task: %v", taskExecCtx.Err())
-					e.handleTaskCompletion(taskExecCtx, taskID, StatusFailed, taskExecCtx.Err(), fatalErrChan, false)
+					r.handleTaskCompletion(taskExecCtx, taskID, StatusFailed, taskExecCtx.Err(), fatalErrChan, false)
+					return
+				}
+
+				if node.IsBlock() {
+					r.completeBlock(taskExecCtx, node, fatalErrChan)
 					return
 				}
 
 				taskLogger.Debugf("Worker picked up task")
-				e.runTaskAndHandleCompletion(taskExecCtx, node, taskLogger, tracer, fatalErrChan)
+				r.runTaskAndHandleCompletion(taskExecCtx, node, taskLogger, tracer, fatalErrChan)
 			}()
 
 		case <-ctx.Done():
@@ -617,7 +792,7 @@ func (e *Engine) worker(
 	}
 }
 
-func (e *Engine) runTaskAndHandleCompletion(
+func (r *playbookRun) runTaskAndHandleCompletion(
 	ctx context.Context,
 	node *Node,
 	taskLogger gxolog.Logger,
@@ -630,7 +805,7 @@ func (e *Engine) runTaskAndHandleCompletion(
 
 	aggregatedErrChan := make(chan error, 10)
 
-	e.eventBus.Emit(events.Event{
+	r.eventBus.Emit(events.Event{
 		Type:      events.TaskStart,
 		Timestamp: time.Now(),
 		TaskName:  task.Name,
@@ -641,10 +816,18 @@ func (e *Engine) runTaskAndHandleCompletion(
 	// Create the GoRenderer instance here, unique to this task execution,
 	// and pass it to the TaskRunner.
 	secretTracker := intSecrets.NewSecretTracker()
-	taskInstanceRenderer := template.NewGoRenderer(e.secretsProvider, e.eventBus, secretTracker)
+	taskInstanceRenderer := template.NewGoRenderer(r.secretsProvider, r.eventBus, secretTracker)
+
+	// Each task runs on its own context so it can be cancelled on its own.
+	taskCtx, cancelTask := context.WithCancel(ctx)
+	r.registerTaskCancel(taskID, cancelTask)
 
 	// Correctly handle the two return values from ExecuteTask.
-	_, taskErr := e.taskRunner.ExecuteTask(ctx, task, node, taskLogger, tracer, aggregatedErrChan, taskInstanceRenderer, secretTracker)
+	_, taskErr := r.taskRunner.ExecuteTask(taskCtx, task, node, taskLogger, tracer, aggregatedErrChan, taskInstanceRenderer, secretTracker)
+	if r.unregisterTaskCancel(taskID) && taskErr != nil && ctx.Err() == nil {
+		taskErr = errTaskCancelled
+	}
+	cancelTask()
 
 	if taskErr == nil {
 		taskFinalStatus = StatusCompleted
@@ -652,15 +835,15 @@ func (e *Engine) runTaskAndHandleCompletion(
 		taskFinalStatus = StatusSkipped
 		taskLogger.Infof("Task skipped: %v", taskErr)
 	} else {
-		redactedErr := template.RedactSecretsInError(taskErr, e.redactedKeywords)
-		if errors.Is(taskErr, context.Canceled) || errors.Is(taskErr, context.DeadlineExceeded) {
+		redactedErr := template.RedactSecretsInError(taskErr, r.redactedKeywords)
+		if errors.Is(taskErr, context.Canceled) || errors.Is(taskErr, context.DeadlineExceeded) || errors.Is(taskErr, errTaskCancelled) {
 			taskLogger.Warnf("Task execution failed: %v", redactedErr)
 		} else {
 			taskLogger.Errorf("Task execution failed fatally: %v", redactedErr)
 		}
 	}
 
-	e.eventBus.Emit(events.Event{
+	r.eventBus.Emit(events.Event{
 		Type:      events.TaskEnd,
 		Timestamp: time.Now(),
 		TaskName:  task.Name,
@@ -673,10 +856,10 @@ func (e *Engine) runTaskAndHandleCompletion(
 		},
 	})
 
-	e.handleTaskCompletion(ctx, taskID, taskFinalStatus, taskErr, fatalErrChan, false)
+	r.handleTaskCompletion(ctx, taskID, taskFinalStatus, taskErr, fatalErrChan, false)
 }
 
-func (e *Engine) handleTaskCompletion(
+func (r *playbookRun) handleTaskCompletion(
 	ctx context.Context,
 	taskID string,
 	finalStatus TaskStatus,
@@ -684,46 +867,46 @@ func (e *Engine) handleTaskCompletion(
 	fatalErrChan chan<- error,
 	synthetic bool,
 ) {
-	e.statusMu.Lock()
+	r.statusMu.Lock()
 	taskName := taskID
-	node := e.dag.Nodes[taskID]
+	node := r.dag.Nodes[taskID]
 	if node != nil && node.Task != nil && node.Task.Name != "" {
 		taskName = node.Task.Name
 	}
 
-	currentStatus := e.taskStatuses[taskID]
+	currentStatus := r.taskStatuses[taskID]
 	isAlreadyTerminal := currentStatus == StatusCompleted || currentStatus == StatusFailed || currentStatus == StatusSkipped
 
 	if !synthetic && isAlreadyTerminal {
-		e.statusMu.Unlock()
-		e.log.Debugf("Ignoring duplicate completion signal for task %s ('%s'). Already in terminal state %s.", taskID, taskName, currentStatus)
+		r.statusMu.Unlock()
+		r.log.Debugf("Ignoring duplicate completion signal for task %s ('%s'). Already in terminal state %s.", taskID, taskName, currentStatus)
 		return
 	}
 
 	var taskDuration time.Duration
-	e.timingsMu.Lock()
-	timing := e.taskTimings[taskID]
+	r.timingsMu.Lock()
+	timing := r.taskTimings[taskID]
 	if timing.end.IsZero() {
 		timing.end = time.Now()
-		e.taskTimings[taskID] = timing
+		r.taskTimings[taskID] = timing
 	}
 	if !timing.start.IsZero() {
 		taskDuration = timing.end.Sub(timing.start)
 	}
-	e.timingsMu.Unlock()
+	r.timingsMu.Unlock()
 
 	oldStatus := currentStatus
-	e.taskStatuses[taskID] = finalStatus
-	e.errorsMu.Lock()
+	r.taskStatuses[taskID] = finalStatus
+	r.errorsMu.Lock()
 	if taskErr != nil {
-		e.taskErrors[taskID] = template.RedactSecretsInError(taskErr, e.redactedKeywords)
+		r.taskErrors[taskID] = template.RedactSecretsInError(taskErr, r.redactedKeywords)
 	} else {
-		delete(e.taskErrors, taskID)
+		delete(r.taskErrors, taskID)
 	}
-	e.errorsMu.Unlock()
-	e.statusMu.Unlock()
+	r.errorsMu.Unlock()
+	r.statusMu.Unlock()
 
-	e.eventBus.Emit(events.Event{
+	r.eventBus.Emit(events.Event{
 		Type: events.TaskStatusChanged, Timestamp: time.Now(),
 		TaskName: taskName, TaskID: taskID,
 		Payload: map[string]interface{}{
@@ -732,144 +915,161 @@ func (e *Engine) handleTaskCompletion(
 		},
 	})
 
-	if writeErr := e.writeTaskStatus(ctx, taskID, finalStatus); writeErr != nil {
-		e.log.LogCtx(ctx, slog.LevelError, "Failed to write final task status to state store",
+	if writeErr := r.writeTaskStatus(ctx, taskID, finalStatus); writeErr != nil {
+		r.log.LogCtx(ctx, slog.LevelError, "Failed to write final task status to state store",
 			"task_id", taskID, "task_name", taskName, "status", finalStatus, "error", writeErr)
 	}
+	r.saveCheckpoint()
 
 	taskType := ""
 	pbName := ""
 	if node != nil && node.Task != nil {
 		taskType = node.Task.Type
 	}
-	if e.taskCounter != nil {
-		e.taskCounter.WithLabelValues(pbName, taskName, taskType, string(finalStatus)).Inc()
+	if r.playbook != nil {
+		pbName = r.playbook.Name
+	}
+	if r.taskCounter != nil {
+		r.taskCounter.WithLabelValues(pbName, taskName, taskType, string(finalStatus)).Inc()
 	}
-	if e.taskDuration != nil && taskDuration > 0 {
-		e.taskDuration.WithLabelValues(pbName, taskName, taskType).Observe(taskDuration.Seconds())
+	if r.taskChangedCounter != nil && finalStatus == StatusCompleted && r.isChanged(taskID) {
+		r.taskChangedCounter.WithLabelValues(pbName, taskName, taskType).Inc()
+	}
+	if r.taskDuration != nil && taskDuration > 0 {
+		r.taskDuration.WithLabelValues(pbName, taskName, taskType).Observe(taskDuration.Seconds())
 	}
 
 	if oldStatus == StatusRunning || oldStatus == StatusPending {
-		completedCount := e.completedTasks.Add(1)
-		e.log.Debugf("Task %s ('%s') finished with status %s. Completed count: %d/%d", taskID, taskName, finalStatus, completedCount, e.totalTasks)
+		completedCount := r.completedTasks.Add(1)
+		r.log.Debugf("Task %s ('%s') finished with status %s. Completed count: %d/%d", taskID, taskName, finalStatus, completedCount, r.totalTasks)
 	} else {
-		e.log.Warnf("Task %s ('%s') completion handler called but old status was already terminal (%s). Completed count: %d/%d.", taskID, taskName, oldStatus, e.completedTasks.Load(), e.totalTasks)
+		r.log.Warnf("Task %s ('%s') completion handler called but old status was already terminal (%s). Completed count: %d/%d.", taskID, taskName, oldStatus, r.completedTasks.Load(), r.totalTasks)
 	}
 
 	if node == nil {
 		if !synthetic {
-			e.log.Errorf("Internal error: Node %s not found during completion handling.", taskID)
+			r.log.Errorf("Internal error: Node %s not found during completion handling.", taskID)
 		}
 		return
 	}
 
-	if finalStatus == StatusCompleted || finalStatus == StatusSkipped {
-		e.signalStateDependents(node)
+	// Dependents of a finished task are released so that their trigger rules
+	// are evaluated, even when its failure halts the run: tasks reacting to
+	// failures then run, and the others are skipped.
+	r.signalStateDependents(node)
+
+	// A task cancelled on its own fails without halting the run.
+	cancelledAlone := errors.Is(taskErr, errTaskCancelled)
+
+	if cancelledAlone && !node.Task.IgnoreErrors && node.Block == nil {
+		r.recordFirstFailure(node, taskErr)
+		return
 	}
 
-	if finalStatus == StatusFailed && !node.Task.IgnoreErrors {
+	// Failures inside a block are settled by the block once its rescue and
+	// always tasks have run.
+	if finalStatus == StatusFailed && !node.Task.IgnoreErrors && node.Block == nil {
 		if taskErr != nil && !errors.Is(taskErr, context.Canceled) && !errors.Is(taskErr, context.DeadlineExceeded) && !gxoerrors.IsSkipped(taskErr) {
+			r.recordFirstFailure(node, taskErr)
 			select {
 			case fatalErrChan <- taskErr:
-				e.log.Warnf("Task %s ('%s') failed fatally (unignored), signaling playbook halt.", taskID, taskName)
+				r.log.Warnf("Task %s ('%s') failed fatally (unignored), signaling playbook halt.", taskID, taskName)
 			default:
-				e.log.Debugf("Task %s ('%s') failed fatally, but halt already signaled.", taskID, taskName)
+				r.log.Debugf("Task %s ('%s') failed fatally, but halt already signaled.", taskID, taskName)
 			}
 		}
 	}
 }
 
-func (e *Engine) isTaskReady(node *Node) bool {
+func (r *playbookRun) isTaskReady(node *Node) bool {
 	return node.StreamDepsRemaining.Load() == 0 && node.StateDepsRemaining.Load() == 0
 }
 
-func (e *Engine) signalStreamDependents(node *Node) {
+func (r *playbookRun) signalStreamDependents(node *Node) {
 	for _, dependentNode := range node.RequiredBy {
 		if _, isStreamDep := dependentNode.StreamDependsOn[node.ID]; isStreamDep {
 			if dependentNode.StreamDepsRemaining.Add(-1) == 0 {
-				e.log.Debugf("Task %s stream dependencies met.", dependentNode.ID)
-				if e.isTaskReady(dependentNode) {
-					e.readyChan <- dependentNode.ID
+				r.log.Debugf("Task %s stream dependencies met.", dependentNode.ID)
+				if r.isTaskReady(dependentNode) {
+					r.readyChan <- dependentNode.ID
 				}
 			}
 		}
 	}
 }
 
-func (e *Engine) signalStateDependents(node *Node) {
+func (r *playbookRun) signalStateDependents(node *Node) {
 	for _, dependentNode := range node.RequiredBy {
 		if _, isStateDep := dependentNode.StateDependsOn[node.ID]; isStateDep {
-			if dependentNode.StateDepsRemaining.Add(-1) == 0 {
-				e.log.Debugf("Task %s state dependencies met.", dependentNode.ID)
-				if e.isTaskReady(dependentNode) {
-					e.readyChan <- dependentNode.ID
-				}
-			}
+			r.releaseStateDependency(dependentNode)
+		}
+	}
+}
+
+func (r *playbookRun) releaseStateDependency(dependentNode *Node) {
+	if dependentNode.StateDepsRemaining.Add(-1) == 0 {
+		r.log.Debugf("Task %s state dependencies met.", dependentNode.ID)
+		if r.isTaskReady(dependentNode) {
+			r.readyChan <- dependentNode.ID
 		}
 	}
 }
 
-func (e *Engine) writeTaskStatus(ctx context.Context, taskID string, status TaskStatus) error {
+func (r *playbookRun) writeTaskStatus(ctx context.Context, taskID string, status TaskStatus) error {
 	taskName := taskID
-	if e.dag != nil {
-		if node, ok := e.dag.Nodes[taskID]; ok && node.Task != nil && node.Task.Name != "" {
+	if r.dag != nil {
+		if node, ok := r.dag.Nodes[taskID]; ok && node.Task != nil && node.Task.Name != "" {
 			taskName = node.Task.Name
 		}
 	}
 	stateKey := fmt.Sprintf("%s.%s.status", StateKeyGxoTasksPrefix, taskName)
-	err := e.stateManager.Set(stateKey, string(status))
+	err := r.stateManager.Set(stateKey, string(status))
 	if err != nil {
-		e.log.LogCtx(ctx, slog.LevelError, "Failed to write task status to state", "key", stateKey, "status", status, "error", err)
+		r.log.LogCtx(ctx, slog.LevelError, "Failed to write task status to state", "key", stateKey, "status", status, "error", err)
 	}
 	return err
 }
 
-func (e *Engine) countTerminalTasks() int32 {
-	e.statusMu.RLock()
-	defer e.statusMu.RUnlock()
+func (r *playbookRun) countTerminalTasks() int32 {
+	r.statusMu.RLock()
+	defer r.statusMu.RUnlock()
 	count := int32(0)
-	for _, status := range e.taskStatuses {
-		if status == StatusCompleted || status == StatusFailed || status == StatusSkipped {
+	for id := range r.dag.Nodes {
+		if status := r.taskStatuses[id]; status == StatusCompleted || status == StatusFailed || status == StatusSkipped {
 			count++
 		}
 	}
 	return count
 }
 
-func (e *Engine) countRunnablePendingTasks() int {
-	e.statusMu.RLock()
-	defer e.statusMu.RUnlock()
+func (r *playbookRun) countRunnablePendingTasks() int {
+	r.statusMu.RLock()
+	defer r.statusMu.RUnlock()
 	count := 0
-	if e.dag == nil {
-		e.log.Warnf("countRunnablePendingTasks called with nil DAG")
+	if r.dag == nil {
+		r.log.Warnf("countRunnablePendingTasks called with nil DAG")
 		return 0
 	}
-	for id, status := range e.taskStatuses {
-		if status == StatusPending {
-			if node, ok := e.dag.Nodes[id]; ok && node != nil {
-				if e.isTaskReady(node) {
-					count++
-				}
-			} else {
-				e.log.Warnf("Inconsistency: Status found for task %s but node missing in DAG during runnable check.", id)
-			}
+	for id, node := range r.dag.Nodes {
+		if r.taskStatuses[id] == StatusPending && node != nil && r.isTaskReady(node) {
+			count++
 		}
 	}
 	return count
 }
 
-func (e *Engine) hasPendingTasks() bool {
-	e.statusMu.RLock()
-	defer e.statusMu.RUnlock()
-	for _, status := range e.taskStatuses {
-		if status == StatusPending {
+func (r *playbookRun) hasPendingTasks() bool {
+	r.statusMu.RLock()
+	defer r.statusMu.RUnlock()
+	for id := range r.dag.Nodes {
+		if r.taskStatuses[id] == StatusPending {
 			return true
 		}
 	}
 	return false
 }
 
-func (e *Engine) determineFinalOutcome(firstFatalError error) error {
+func (r *playbookRun) determineFinalOutcome(firstFatalError error) error {
 	if firstFatalError != nil {
 		if errors.Is(firstFatalError, context.Canceled) || errors.Is(firstFatalError, context.DeadlineExceeded) {
 			return firstFatalError
@@ -877,37 +1077,37 @@ func (e *Engine) determineFinalOutcome(firstFatalError error) error {
 		if firstFatalError.Error() == "playbook execution stalled" {
 			return firstFatalError
 		}
-		return gxoerrors.NewConfigError("playbook finished due to fatal error", template.RedactSecretsInError(firstFatalError, e.redactedKeywords))
+		return gxoerrors.NewConfigError("playbook finished due to fatal error", template.RedactSecretsInError(firstFatalError, r.redactedKeywords))
 	}
 
 	hasFailedTasks := false
 	hasUnexpectedPending := false
 
-	e.statusMu.RLock()
-	defer e.statusMu.RUnlock()
+	r.statusMu.RLock()
+	defer r.statusMu.RUnlock()
 
-	for id, status := range e.taskStatuses {
-		if status == StatusFailed {
+	for id, status := range r.taskStatuses {
+		if status == StatusFailed && !r.isFailureHandled(id) {
 			hasFailedTasks = true
 		}
 		if status == StatusPending {
 			isExpectedPending := false
-			if e.dag != nil {
-				if node, nodeExists := e.dag.Nodes[id]; nodeExists && node != nil {
+			if r.dag != nil {
+				if node, nodeExists := r.dag.Nodes[id]; nodeExists && node != nil {
 					if node.StateDepsRemaining.Load() > 0 {
 						isExpectedPending = true
 					}
-				} else if e.totalTasks > 0 {
-					e.log.Warnf("Task %s has status but node not found in DAG during final outcome check.", id)
+				} else if r.totalTasks > 0 {
+					r.log.Warnf("Task %s has status but node not found in DAG during final outcome check.", id)
 					hasUnexpectedPending = true
 				}
-			} else if e.totalTasks > 0 {
-				e.log.Log(slog.LevelError, "Internal consistency error: DAG is nil but totalTasks > 0 during final outcome check.")
+			} else if r.totalTasks > 0 {
+				r.log.Log(slog.LevelError, "Internal consistency error: DAG is nil but totalTasks > 0 during final outcome check.")
 				hasUnexpectedPending = true
 			}
 			if !isExpectedPending {
 				hasUnexpectedPending = true
-				e.log.Warnf("Task %s remains Pending unexpectedly at end of execution.", id)
+				r.log.Warnf("Task %s remains Pending unexpectedly at end of execution.", id)
 			}
 		}
 	}
@@ -922,32 +1122,35 @@ func (e *Engine) determineFinalOutcome(firstFatalError error) error {
 	return nil
 }
 
-func (e *Engine) generateReport(playbookName string, start, end time.Time, finalExecError error) *gxo.ExecutionReport {
+func (r *playbookRun) generateReport(playbookName string, start, end time.Time, finalExecError error) *gxo.ExecutionReport {
 	report := &gxo.ExecutionReport{
+		RunID:         r.runID,
 		PlaybookName:  playbookName,
 		StartTime:     start,
 		EndTime:       end,
 		Duration:      end.Sub(start),
 		TaskResults:   make(map[string]gxo.TaskResult),
 		OverallStatus: "Completed",
+		Outputs:       r.outputs,
 	}
 
 	if finalExecError != nil {
 		report.OverallStatus = "Failed"
-		report.Error = template.RedactSecretsInError(finalExecError, e.redactedKeywords).Error()
+		report.Error = template.RedactSecretsInError(finalExecError, r.redactedKeywords).Error()
 	}
 
-	e.statusMu.RLock()
-	e.timingsMu.RLock()
-	e.errorsMu.Lock()
-	defer e.errorsMu.Unlock()
-	defer e.timingsMu.RUnlock()
-	defer e.statusMu.RUnlock()
+	r.statusMu.RLock()
+	r.timingsMu.RLock()
+	r.errorsMu.Lock()
+	defer r.errorsMu.Unlock()
+	defer r.timingsMu.RUnlock()
+	defer r.statusMu.RUnlock()
 
-	for id, status := range e.taskStatuses {
-		timing := e.taskTimings[id]
+	unhandledFailures := 0
+	for id, status := range r.taskStatuses {
+		timing := r.taskTimings[id]
 		taskErrStr := ""
-		if taskErr, exists := e.taskErrors[id]; exists && taskErr != nil {
+		if taskErr, exists := r.taskErrors[id]; exists && taskErr != nil {
 			taskErrStr = taskErr.Error()
 		}
 
@@ -959,6 +1162,9 @@ func (e *Engine) generateReport(playbookName string, start, end time.Time, final
 		switch status {
 		case StatusFailed:
 			report.FailedTasks++
+			if !r.isFailureHandled(id) {
+				unhandledFailures++
+			}
 			if taskErrStr == "" {
 				taskErrStr = "Task failed (unknown error)"
 			}
@@ -966,11 +1172,11 @@ func (e *Engine) generateReport(playbookName string, start, end time.Time, final
 			report.CompletedTasks++
 		case StatusSkipped:
 			report.SkippedTasks++
-			if taskErr, exists := e.taskErrors[id]; exists && gxoerrors.IsSkipped(taskErr) {
+			if taskErr, exists := r.taskErrors[id]; exists && gxoerrors.IsSkipped(taskErr) {
 				taskErrStr = taskErr.Error()
 			}
 		case StatusPending, StatusRunning:
-			e.log.Warnf("Task %s found in non-terminal state (%s) during report generation.", id, status)
+			r.log.Warnf("Task %s found in non-terminal state (%s) during report generation.", id, status)
 			if report.OverallStatus == "Completed" {
 				report.OverallStatus = "Failed"
 				if report.Error == "" {
@@ -983,19 +1189,39 @@ func (e *Engine) generateReport(playbookName string, start, end time.Time, final
 				taskErrStr = "Task remained in Running state"
 			}
 			report.FailedTasks++
+			unhandledFailures++
 		}
 
-		report.TaskResults[id] = gxo.TaskResult{
+		result := gxo.TaskResult{
 			Status:    string(status),
 			Error:     taskErrStr,
 			StartTime: timing.start,
 			EndTime:   timing.end,
 			Duration:  taskDuration,
 		}
+		result.Changed = r.isChanged(id)
+		if result.Changed && status == StatusCompleted {
+			report.ChangedTasks++
+		}
+		result.CacheHits, result.CacheMisses = r.cacheLookups(id)
+		report.CacheHits += result.CacheHits
+		report.CacheMisses += result.CacheMisses
+		result.NestedReports = r.nestedReports(id)
+		result.Iterations = r.iterationResults(id)
+		result.IterationCounts = countIterations(result.Iterations)
+		if node, exists := r.nodesByID[id]; exists {
+			if node.Section != config.SectionTasks {
+				result.Section = node.Section
+			}
+			if node.Block != nil {
+				result.Block = node.Block.DisplayName()
+			}
+		}
+		report.TaskResults[id] = result
 	}
-	report.TotalTasks = len(e.taskStatuses)
+	report.TotalTasks = len(r.taskStatuses)
 
-	if report.FailedTasks > 0 && report.OverallStatus == "Completed" {
+	if unhandledFailures > 0 && report.OverallStatus == "Completed" {
 		report.OverallStatus = "Failed"
 		if report.Error == "" {
 			report.Error = "Playbook finished with one or more failed tasks"
@@ -1013,6 +1239,7 @@ func (e *Engine) emitFinalEvents(report *gxo.ExecutionReport) {
 		"playbook_name": report.PlaybookName, "duration_ms": report.Duration.Milliseconds(),
 		"status": report.OverallStatus, "total_tasks": report.TotalTasks,
 		"completed": report.CompletedTasks, "failed": report.FailedTasks, "skipped": report.SkippedTasks,
+		"changed": report.ChangedTasks, "cache_hits": report.CacheHits, "cache_misses": report.CacheMisses,
 		"error_message": report.Error,
 	}
 	e.eventBus.Emit(events.Event{Type: events.PlaybookEnd, Timestamp: report.EndTime, PlaybookName: report.PlaybookName, Payload: payload})
@@ -1026,9 +1253,6 @@ func (e *Engine) SetStateStore(store gxov1state.Store) error {
 		return gxoerrors.NewConfigError("state store cannot be nil", nil)
 	}
 	e.stateManager = store
-	if e.taskRunner != nil {
-		e.taskRunner.stateManager = store
-	}
 	return nil
 }
 
@@ -1037,9 +1261,6 @@ func (e *Engine) SetSecretsProvider(provider secrets.Provider) error {
 		return gxoerrors.NewConfigError("secrets provider cannot be nil", nil)
 	}
 	e.secretsProvider = provider
-	if e.taskRunner != nil {
-		e.taskRunner.secretsProvider = provider
-	}
 	return nil
 }
 
@@ -1048,9 +1269,6 @@ func (e *Engine) SetEventBus(bus events.Bus) error {
 		return gxoerrors.NewConfigError("event bus cannot be nil", nil)
 	}
 	e.eventBus = bus
-	if e.taskRunner != nil {
-		e.taskRunner.eventBus = bus
-	}
 	return nil
 }
 
@@ -1059,9 +1277,6 @@ func (e *Engine) SetPluginRegistry(registry plugin.Registry) error {
 		return gxoerrors.NewConfigError("plugin registry cannot be nil", nil)
 	}
 	e.pluginRegistry = registry
-	if e.taskRunner != nil {
-		e.taskRunner.pluginRegistry = registry
-	}
 	return nil
 }
 
@@ -1079,9 +1294,22 @@ func (e *Engine) SetTracerProvider(provider gxotracing.TracerProvider) error {
 		return gxoerrors.NewConfigError("tracer provider cannot be nil", nil)
 	}
 	e.tracerProvider = provider
-	if e.taskRunner != nil {
-		e.taskRunner.tracerProvider = provider
+	return nil
+}
+
+func (e *Engine) SetCheckpointStore(store checkpoint.Store) error {
+	if store == nil {
+		return gxoerrors.NewConfigError("checkpoint store cannot be nil", nil)
 	}
+	e.checkpointStore = store
+	return nil
+}
+
+func (e *Engine) SetCacheStore(store cache.Store) error {
+	if store == nil {
+		return gxoerrors.NewConfigError("cache store cannot be nil", nil)
+	}
+	e.cacheStore = store
 	return nil
 }
 
@@ -1090,9 +1318,6 @@ func (e *Engine) SetDefaultTimeout(timeout time.Duration) error {
 		return gxoerrors.NewConfigError("default timeout cannot be negative", nil)
 	}
 	e.defaultTimeout = timeout
-	if e.taskRunner != nil {
-		e.taskRunner.defaultTimeout = timeout
-	}
 	return nil
 }
 
@@ -1101,6 +1326,7 @@ func (e *Engine) SetWorkerPoolSize(size int) error {
 		return gxoerrors.NewConfigError("worker pool size must be positive", nil)
 	}
 	e.workerPoolSize = size
+	e.workerSlots = make(chan struct{}, size)
 	return nil
 }
 
@@ -1115,9 +1341,6 @@ func (e *Engine) SetDefaultChannelPolicy(policy gxo.ChannelPolicy) error {
 		return gxoerrors.NewConfigError("default channel policy buffer_size cannot be negative", nil)
 	}
 	e.defaultChannelPolicy = internalPolicy
-	if e.channelManager != nil {
-		e.channelManager = NewChannelManager(e.defaultChannelPolicy)
-	}
 	return nil
 }
 
@@ -1145,9 +1368,6 @@ func (e *Engine) SetRedactedKeywords(keywords []string) error {
 		}
 	}
 	e.redactedKeywords = newMap
-	if e.taskRunner != nil {
-		e.taskRunner.redactedKeywords = e.redactedKeywords
-	}
 	if e.retryHelper != nil {
 		e.retryHelper.SetRedactedKeywords(e.redactedKeywords)
 	}
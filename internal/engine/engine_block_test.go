@@ -0,0 +1,210 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_Block_RescueRecoversFromFailure(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, stateStore := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: block_rescue_test
+tasks:
+  - name: deploy
+    block:
+      - name: step_fail
+        type: mock
+        params:
+          fail_message: "deployment failed"
+      - name: step_after
+        type: mock
+        params:
+          prev: "{{ ._gxo.tasks.step_fail.status }}"
+    rescue:
+      - name: rollback
+        type: mock
+        register: rollback_out
+    always:
+      - name: notify
+        type: mock
+  - name: after_block
+    type: mock
+    params:
+      deploy_status: "{{ ._gxo.tasks.deploy.status }}"
+    register: after_out
+`
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	report, execErr := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+
+	require.NoError(t, execErr)
+	require.NotNil(t, report)
+	assert.Equal(t, "Completed", report.OverallStatus)
+	assert.Equal(t, "Completed", report.TaskResults["deploy"].Status, "A rescued block must complete")
+	assert.Equal(t, "Failed", report.TaskResults["step_fail"].Status)
+	assert.Equal(t, "deploy", report.TaskResults["step_fail"].Block)
+	assert.Equal(t, "Skipped", report.TaskResults["step_after"].Status, "Dependents of a failed block task must be skipped")
+	assert.Equal(t, "Completed", report.TaskResults["rollback"].Status)
+	assert.Equal(t, "Completed", report.TaskResults["notify"].Status)
+	assert.Equal(t, "", report.TaskResults["after_block"].Block)
+
+	_, found := stateStore.Get("rollback_out")
+	assert.True(t, found, "Rescue task must have run")
+	afterOut, found := stateStore.Get("after_out")
+	require.True(t, found, "Tasks depending on a rescued block must run")
+	assert.Equal(t, "Completed", afterOut.(map[string]interface{})["deploy_status"])
+}
+
+func TestEngine_Block_FailureWithoutRescueRunsAlways(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, stateStore := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: block_always_test
+tasks:
+  - name: deploy
+    block:
+      - name: step_fail
+        type: mock
+        params:
+          fail_message: "deployment failed"
+    always:
+      - name: cleanup
+        type: mock
+        register: cleanup_out
+on_failure:
+  - name: report_failure
+    type: mock
+    params:
+      failed_task: "{{ ._gxo.run.failed_task }}"
+    register: failure_out
+`
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	report, execErr := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+
+	require.Error(t, execErr)
+	require.NotNil(t, report)
+	assert.Equal(t, "Failed", report.OverallStatus)
+	assert.Equal(t, "Failed", report.TaskResults["deploy"].Status)
+	assert.Contains(t, report.TaskResults["deploy"].Error, "deployment failed")
+	assert.Equal(t, "Completed", report.TaskResults["cleanup"].Status)
+
+	_, found := stateStore.Get("cleanup_out")
+	assert.True(t, found, "Always task must run after a failure")
+	failureOut, found := stateStore.Get("failure_out")
+	require.True(t, found)
+	assert.Equal(t, "step_fail", failureOut.(map[string]interface{})["failed_task"], "The failed block member must be reported as the cause")
+}
+
+func TestEngine_Block_FailedRescueFailsBlock(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: block_rescue_fail_test
+tasks:
+  - name: deploy
+    block:
+      - name: step_fail
+        type: mock
+        params:
+          fail_message: "deployment failed"
+    rescue:
+      - name: rollback
+        type: mock
+        params:
+          fail_message: "rollback failed"
+`
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	report, execErr := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+
+	require.Error(t, execErr)
+	require.NotNil(t, report)
+	assert.Equal(t, "Failed", report.TaskResults["deploy"].Status)
+	assert.Equal(t, "Failed", report.TaskResults["rollback"].Status)
+}
+
+func TestEngine_Block_InheritsWhenAndSkipsRescue(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, stateStore := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: block_when_test
+vars:
+  enabled: false
+tasks:
+  - name: disabled_block
+    when: "{{ .enabled }}"
+    block:
+      - name: child_a
+        type: mock
+      - name: child_b
+        type: mock
+        when: "true"
+  - name: enabled_block
+    block:
+      - name: child_ok
+        type: mock
+        register: child_ok_out
+    rescue:
+      - name: unused_rescue
+        type: mock
+`
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	report, execErr := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+
+	require.NoError(t, execErr)
+	require.NotNil(t, report)
+	assert.Equal(t, "Skipped", report.TaskResults["child_a"].Status)
+	assert.Equal(t, "Skipped", report.TaskResults["child_b"].Status, "The block's 'when' must apply in addition to the task's own")
+	assert.Equal(t, "Skipped", report.TaskResults["disabled_block"].Status)
+	assert.Equal(t, "Completed", report.TaskResults["enabled_block"].Status)
+	assert.Equal(t, "Skipped", report.TaskResults["unused_rescue"].Status, "Rescue tasks must not run if the block succeeded")
+
+	_, found := stateStore.Get("child_ok_out")
+	assert.True(t, found)
+}
+
+func TestEngine_Block_ValidationErrors(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: block_validation_test
+tasks:
+  - name: bad_block
+    type: mock
+    register: bad_out
+    block:
+      - name: child
+        type: mock
+  - name: orphan_rescue
+    type: mock
+    rescue:
+      - name: fix
+        type: mock
+`
+	_, execErr := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.Error(t, execErr)
+	assert.Contains(t, execErr.Error(), "'type' cannot be combined with 'block'")
+	assert.Contains(t, execErr.Error(), "a block cannot use 'params', 'register'")
+	assert.Contains(t, execErr.Error(), "'rescue' and 'always' require 'block'")
+}
@@ -0,0 +1,258 @@
+package engine_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gxo-labs/gxo/internal/cache"
+	"github.com/gxo-labs/gxo/internal/config"
+	"github.com/gxo-labs/gxo/internal/engine"
+	gxo "github.com/gxo-labs/gxo/pkg/gxo/v1"
+	"github.com/gxo-labs/gxo/pkg/gxo/v1/plugin"
+	gxov1state "github.com/gxo-labs/gxo/pkg/gxo/v1/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingModule counts its executions and returns its 'target' param.
+type countingModule struct {
+	mu    *sync.Mutex
+	calls *int
+}
+
+func (m *countingModule) Perform(
+	ctx context.Context,
+	params map[string]interface{},
+	stateReader gxov1state.StateReader,
+	inputs map[string]<-chan map[string]interface{},
+	outputChans []chan<- map[string]interface{},
+	errChan chan<- error,
+) (interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	*m.calls++
+	return map[string]interface{}{"target": params["target"], "artifacts": 2}, nil
+}
+
+func setupCacheTestEngine(t *testing.T, cacheDir string) (*engine.Engine, gxov1state.Store, *int) {
+	t.Helper()
+	var mu sync.Mutex
+	calls := 0
+	reg := NewInMemoryRegistry()
+	require.NoError(t, reg.Register("build", func() plugin.Module { return &countingModule{mu: &mu, calls: &calls} }))
+	engineInstance, stateStore := setupTestEngine(t, reg)
+	require.NoError(t, engineInstance.SetCacheStore(cache.NewFileStore(cacheDir)))
+	return engineInstance, stateStore, &calls
+}
+
+func cacheMetricTotal(t *testing.T, engineInstance *engine.Engine, name string) float64 {
+	t.Helper()
+	var total float64
+	families, err := engineInstance.MetricsRegistryProvider().Registry().Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			total += metric.GetCounter().GetValue()
+		}
+	}
+	return total
+}
+
+func TestEngine_Cache_SkipsUnchangedExecutions(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src"), 0o755))
+	inputPath := filepath.Join(dir, "src", "main.go")
+	require.NoError(t, os.WriteFile(inputPath, []byte("package main"), 0o644))
+	playbookPath := filepath.Join(dir, "playbook.yaml")
+	engineInstance, stateStore, calls := setupCacheTestEngine(t, filepath.Join(dir, "cache"))
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: cache_test
+vars:
+  version: "1.0"
+tasks:
+  - name: compile
+    type: build
+    loop: ["linux", "darwin"]
+    params:
+      target: "{{ .item }}"
+    cache:
+      key: "{{ .version }}"
+      ttl: 24h
+      inputs_files: ["src/main.go"]
+    register: binaries
+`
+	run := func() *gxo.ExecutionReport {
+		ctx := context.WithValue(context.Background(), gxo.PlaybookPathKey{}, playbookPath)
+		report, err := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+		require.NoError(t, err)
+		return report
+	}
+
+	first := run()
+	assert.Equal(t, 2, *calls)
+	assert.Equal(t, 0, first.TaskResults["compile"].CacheHits)
+	assert.Equal(t, 2, first.TaskResults["compile"].CacheMisses)
+
+	second := run()
+	assert.Equal(t, 2, *calls, "Unchanged iterations are restored from the cache")
+	assert.Equal(t, 2, second.TaskResults["compile"].CacheHits)
+	assert.Equal(t, 2, second.CacheHits)
+	assert.Equal(t, 0, second.CacheMisses)
+	binaries, found := stateStore.Get("binaries")
+	require.True(t, found)
+	summary := binaries.([]interface{})[1].(map[string]interface{})["summary"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"target": "darwin", "artifacts": 2}, summary, "The cached summary is registered")
+
+	require.NoError(t, os.WriteFile(inputPath, []byte("package main // changed"), 0o644))
+	third := run()
+	assert.Equal(t, 4, *calls, "A changed input file invalidates the cache")
+	assert.Equal(t, 2, third.CacheMisses)
+
+	assert.Equal(t, float64(2), cacheMetricTotal(t, engineInstance, "gxo_task_cache_hits_total"))
+	assert.Equal(t, float64(4), cacheMetricTotal(t, engineInstance, "gxo_task_cache_misses_total"))
+
+	// The cache counters are labelled like the other task metrics, so that
+	// they can be joined.
+	families, err := engineInstance.MetricsRegistryProvider().Registry().Gather()
+	require.NoError(t, err)
+	labelled := make(map[string]bool)
+	for _, family := range families {
+		if !strings.HasPrefix(family.GetName(), "gxo_task_") {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "playbook_name" {
+					assert.Equal(t, "cache_test", label.GetValue(), "Metric %s", family.GetName())
+					labelled[family.GetName()] = true
+				}
+			}
+		}
+	}
+	for _, name := range []string{"gxo_task_runs_total", "gxo_task_run_duration_seconds", "gxo_task_cache_hits_total", "gxo_task_cache_misses_total"} {
+		assert.True(t, labelled[name], "Metric %s", name)
+	}
+}
+
+func TestEngine_Cache_ExpiryAndBypass(t *testing.T) {
+	engineInstance, _, calls := setupCacheTestEngine(t, t.TempDir())
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: cache_bypass_test
+tasks:
+  - name: fresh
+    type: build
+    params:
+      target: "linux"
+    cache: {}
+  - name: stale
+    type: build
+    params:
+      target: "darwin"
+    cache:
+      ttl: 1ns
+`
+	_, err := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.NoError(t, err)
+	assert.Equal(t, 2, *calls)
+
+	report, err := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.NoError(t, err)
+	assert.Equal(t, 3, *calls, "Only the expired entry is executed again")
+	assert.Equal(t, 1, report.TaskResults["fresh"].CacheHits)
+	assert.Equal(t, 1, report.TaskResults["stale"].CacheMisses)
+
+	ctx := context.WithValue(context.Background(), gxo.NoCacheKey{}, true)
+	report, err = engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+	require.NoError(t, err)
+	assert.Equal(t, 5, *calls, "NoCacheKey forces execution")
+	assert.Equal(t, 0, report.CacheHits)
+	assert.Equal(t, 2, report.CacheMisses)
+}
+
+func TestEngine_Cache_KeyIncludesEnvironment(t *testing.T) {
+	engineInstance, _, calls := setupCacheTestEngine(t, t.TempDir())
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: cache_environment_test
+environment:
+  TARGET: %s
+tasks:
+  - name: deploy
+    type: build
+    params:
+      target: "linux"
+    cache: {}
+`
+	run := func(target string) *gxo.ExecutionReport {
+		report, err := engineInstance.RunPlaybook(context.Background(), []byte(fmt.Sprintf(playbookYAML, target)))
+		require.NoError(t, err)
+		return report
+	}
+
+	run("prod")
+	assert.Equal(t, 1, run("prod").CacheHits)
+	assert.Equal(t, 1, *calls)
+
+	report := run("staging")
+	assert.Equal(t, 2, *calls, "A changed environment invalidates the cache")
+	assert.Equal(t, 1, report.CacheMisses)
+}
+
+func TestEngine_Cache_HitIsNeverChanged(t *testing.T) {
+	engineInstance, _, calls := setupCacheTestEngine(t, t.TempDir())
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: cache_changed_test
+tasks:
+  - name: compile
+    type: build
+    params:
+      target: "linux"
+    changed_when: "{{ eq .result.artifacts 2 }}"
+    cache: {}
+`
+	first, err := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.NoError(t, err)
+	assert.True(t, first.TaskResults["compile"].Changed)
+	assert.Equal(t, 1, first.ChangedTasks)
+
+	second, err := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.NoError(t, err)
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, 1, second.TaskResults["compile"].CacheHits)
+	assert.False(t, second.TaskResults["compile"].Changed, "A restored summary did not change anything")
+	assert.Equal(t, 0, second.ChangedTasks)
+	assert.Equal(t, float64(1), cacheMetricTotal(t, engineInstance, "gxo_task_changed_total"))
+}
+
+func TestEngine_Cache_Validation(t *testing.T) {
+	playbookYAML := `schemaVersion: "v1.0.0"
+name: cache_validation_test
+tasks:
+  - name: producer
+    type: mock
+  - name: consumer
+    type: mock
+    stream_inputs: ["producer"]
+    cache:
+      key: "v1"
+`
+	_, err := config.LoadPlaybook([]byte(playbookYAML), "cache.yaml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cache.yaml:9:5")
+	assert.Contains(t, err.Error(), "'cache' cannot be combined with 'stream_inputs'")
+}
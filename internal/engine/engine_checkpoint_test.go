@@ -0,0 +1,207 @@
+package engine_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/gxo-labs/gxo/internal/checkpoint"
+	"github.com/gxo-labs/gxo/pkg/gxo/v1/plugin"
+	gxov1state "github.com/gxo-labs/gxo/pkg/gxo/v1/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyModule counts invocations per 'id' param and fails while its shared
+// 'broken' flag is set and the task opts in via 'fail_while_broken'.
+type flakyModule struct {
+	mu     *sync.Mutex
+	calls  map[string]int
+	broken *bool
+}
+
+func (m *flakyModule) Perform(
+	ctx context.Context,
+	params map[string]interface{},
+	stateReader gxov1state.StateReader,
+	inputs map[string]<-chan map[string]interface{},
+	outputChans []chan<- map[string]interface{},
+	errChan chan<- error,
+) (interface{}, error) {
+	id, _ := params["id"].(string)
+	m.mu.Lock()
+	m.calls[id]++
+	broken := *m.broken
+	m.mu.Unlock()
+	if failWhileBroken, _ := params["fail_while_broken"].(bool); failWhileBroken && broken {
+		return nil, errors.New("flaky failure")
+	}
+	return params, nil
+}
+
+func TestEngine_ResumeRun_SkipsFinishedTasks(t *testing.T) {
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	broken := true
+	newRegistry := func() *InMemoryRegistry {
+		reg := NewInMemoryRegistry()
+		require.NoError(t, reg.Register("flaky", func() plugin.Module {
+			return &flakyModule{mu: &mu, calls: calls, broken: &broken}
+		}))
+		return reg
+	}
+	store := checkpoint.NewFileStore(t.TempDir())
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: resume_test
+vars:
+  greeting: "hello"
+tasks:
+  - name: task_a
+    type: flaky
+    params:
+      id: "a"
+      value: "{{ .greeting }}"
+    register: a_out
+  - name: task_b
+    type: flaky
+    params:
+      id: "b"
+      fail_while_broken: true
+      from_a: "{{ .a_out }}"
+    register: b_out
+  - name: task_c
+    type: flaky
+    params:
+      id: "c"
+      from_b: "{{ .b_out }}"
+    register: c_out
+`
+
+	firstEngine, _ := setupTestEngine(t, newRegistry())
+	require.NoError(t, firstEngine.SetCheckpointStore(store))
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	firstReport, firstErr := firstEngine.RunPlaybook(ctx, []byte(playbookYAML))
+	require.Error(t, firstErr)
+	require.NotNil(t, firstReport)
+	require.NotEmpty(t, firstReport.RunID)
+	assert.Equal(t, "Failed", firstReport.OverallStatus)
+	assert.Equal(t, "Completed", firstReport.TaskResults["task_a"].Status)
+	assert.Equal(t, "Failed", firstReport.TaskResults["task_b"].Status)
+
+	cp, err := store.Load(firstReport.RunID)
+	require.NoError(t, err)
+	assert.Equal(t, "Completed", cp.TaskStatuses["task_a"])
+	assert.Equal(t, "Failed", cp.TaskStatuses["task_b"])
+	assert.Equal(t, map[string]interface{}{"id": "a", "value": "hello"}, cp.Registered["a_out"])
+
+	mu.Lock()
+	broken = false
+	mu.Unlock()
+
+	secondEngine, secondStore := setupTestEngine(t, newRegistry())
+	require.NoError(t, secondEngine.SetCheckpointStore(store))
+	secondReport, secondErr := secondEngine.ResumeRun(ctx, firstReport.RunID)
+	require.NoError(t, secondErr)
+	require.NotNil(t, secondReport)
+	assert.Equal(t, firstReport.RunID, secondReport.RunID)
+	assert.Equal(t, "Completed", secondReport.OverallStatus)
+	assert.Equal(t, 3, secondReport.CompletedTasks)
+
+	mu.Lock()
+	assert.Equal(t, 1, calls["a"], "Completed task must not run again on resume")
+	assert.Equal(t, 2, calls["b"], "Failed task must run again on resume")
+	assert.Equal(t, 1, calls["c"])
+	mu.Unlock()
+
+	cOut, found := secondStore.Get("c_out")
+	require.True(t, found)
+	fromB, ok := cOut.(map[string]interface{})["from_b"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"id": "a", "value": "hello"}, fromB["from_a"], "Registered value of task_a must be restored from the checkpoint")
+}
+
+func TestEngine_ResumeRun_RerunsStateDependentsOfRerunStreamProducer(t *testing.T) {
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	broken := true
+	newRegistry := func() *InMemoryRegistry {
+		reg := NewInMemoryRegistry()
+		require.NoError(t, reg.Register("flaky", func() plugin.Module {
+			return &flakyModule{mu: &mu, calls: calls, broken: &broken}
+		}))
+		return reg
+	}
+	store := checkpoint.NewFileStore(t.TempDir())
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: resume_stream_test
+tasks:
+  - name: producer
+    type: flaky
+    params:
+      id: "producer"
+    register: producer_out
+  - name: reader
+    type: flaky
+    params:
+      id: "reader"
+      from_producer: "{{ .producer_out }}"
+    register: reader_out
+  - name: consumer
+    type: flaky
+    stream_inputs: [producer]
+    depends_on: [reader]
+    params:
+      id: "consumer"
+      fail_while_broken: true
+`
+
+	firstEngine, _ := setupTestEngine(t, newRegistry())
+	require.NoError(t, firstEngine.SetCheckpointStore(store))
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	firstReport, firstErr := firstEngine.RunPlaybook(ctx, []byte(playbookYAML))
+	require.Error(t, firstErr)
+	require.NotNil(t, firstReport)
+	assert.Equal(t, "Completed", firstReport.TaskResults["producer"].Status)
+	assert.Equal(t, "Failed", firstReport.TaskResults["consumer"].Status)
+	assert.Equal(t, "Completed", firstReport.TaskResults["reader"].Status)
+
+	mu.Lock()
+	broken = false
+	mu.Unlock()
+
+	secondEngine, _ := setupTestEngine(t, newRegistry())
+	require.NoError(t, secondEngine.SetCheckpointStore(store))
+	secondReport, secondErr := secondEngine.ResumeRun(ctx, firstReport.RunID)
+	require.NoError(t, secondErr)
+	assert.Equal(t, "Completed", secondReport.OverallStatus)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 2, calls["consumer"], "Failed stream consumer must run again on resume")
+	assert.Equal(t, 2, calls["producer"], "The producer of a stream consumer that runs again must run again")
+	assert.Equal(t, 2, calls["reader"], "A task reading the register of a producer that runs again must run again")
+}
+
+func TestEngine_ResumeRun_UnknownRunID(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+
+	_, err := engineInstance.ResumeRun(context.Background(), "missing")
+	require.Error(t, err, "Resuming without a checkpoint store must fail")
+
+	require.NoError(t, engineInstance.SetCheckpointStore(checkpoint.NewFileStore(t.TempDir())))
+	_, err = engineInstance.ResumeRun(context.Background(), "missing")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "checkpoint not found")
+}
@@ -0,0 +1,129 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	gxo "github.com/gxo-labs/gxo/pkg/gxo/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_Control_PauseHoldsNewTasks(t *testing.T) {
+	engineInstance := setupConcurrentTestEngine(t, 2)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: pause_test
+tasks:
+  - name: first
+    type: mock
+    params:
+      _mock_delay: "300ms"
+  - name: second
+    type: mock
+    depends_on: [first]
+`
+	handle, err := engineInstance.StartPlaybook(context.Background(), []byte(playbookYAML))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return handle.Status().TaskStatuses["first"] == "Running"
+	}, testTimeout, 5*time.Millisecond)
+	handle.Pause()
+	assert.Equal(t, gxo.RunStatePaused, handle.Status().State)
+
+	require.Eventually(t, func() bool {
+		return handle.Status().TaskStatuses["first"] == "Completed"
+	}, testTimeout, 10*time.Millisecond, "A running task must finish while the run is paused")
+	// Outlast a stall check to make sure a paused run is not considered stuck.
+	time.Sleep(1500 * time.Millisecond)
+	status := handle.Status()
+	assert.Equal(t, gxo.RunStatePaused, status.State)
+	assert.NotEqual(t, "Completed", status.TaskStatuses["second"], "No task may start while the run is paused")
+
+	handle.Resume()
+	report, err := handle.Wait()
+	require.NoError(t, err)
+	assert.Equal(t, "Completed", report.OverallStatus)
+	assert.Equal(t, "Completed", report.TaskResults["second"].Status)
+}
+
+func TestEngine_Control_CancelTaskFailsOnlyThatTask(t *testing.T) {
+	engineInstance := setupConcurrentTestEngine(t, 2)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: cancel_task_test
+tasks:
+  - name: slow
+    type: mock
+    params:
+      _mock_delay: "30s"
+  - name: after_slow
+    type: mock
+    depends_on: [slow]
+  - name: independent
+    type: mock
+    params:
+      _mock_delay: "200ms"
+  - name: always
+    type: mock
+    depends_on: [slow]
+    trigger_rule: all_done
+`
+	handle, err := engineInstance.StartPlaybook(context.Background(), []byte(playbookYAML))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return handle.Status().TaskStatuses["slow"] == "Running"
+	}, testTimeout, 5*time.Millisecond)
+
+	start := time.Now()
+	require.NoError(t, handle.CancelTask("slow"))
+	report, err := handle.Wait()
+	assert.Less(t, time.Since(start), 10*time.Second, "CancelTask must interrupt the task")
+
+	require.Error(t, err)
+	assert.Equal(t, "Failed", report.OverallStatus)
+	assert.Equal(t, "Failed", report.TaskResults["slow"].Status)
+	assert.Contains(t, report.TaskResults["slow"].Error, "task cancelled")
+	assert.Equal(t, "Skipped", report.TaskResults["after_slow"].Status)
+	assert.Equal(t, "Completed", report.TaskResults["independent"].Status, "Other tasks must not be cancelled")
+	assert.Equal(t, "Completed", report.TaskResults["always"].Status, "Dependents must be released to their trigger rules")
+}
+
+func TestEngine_Control_CancelTaskErrors(t *testing.T) {
+	engineInstance := setupConcurrentTestEngine(t, 2)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: cancel_errors_test
+tasks:
+  - name: slow
+    type: mock
+    params:
+      _mock_delay: "300ms"
+  - name: waiting
+    type: mock
+    depends_on: [slow]
+`
+	handle, err := engineInstance.StartPlaybook(context.Background(), []byte(playbookYAML))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return handle.Status().TaskStatuses["slow"] == "Running"
+	}, testTimeout, 5*time.Millisecond)
+
+	err = handle.CancelTask("missing")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "task 'missing' not found")
+
+	err = handle.CancelTask("waiting")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "task 'waiting' is not running")
+
+	report, err := handle.Wait()
+	require.NoError(t, err)
+	assert.Equal(t, "Completed", report.OverallStatus)
+}
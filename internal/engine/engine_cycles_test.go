@@ -0,0 +1,108 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_Cycles_ReportEveryCycleWithEdgeKinds(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: cycles_test
+tasks:
+  - name: a
+    type: mock
+    params:
+      in: "{{ .c_out }}"
+    register: a_out
+  - name: b
+    type: mock
+    stream_inputs: [a]
+  - name: c
+    type: mock
+    params:
+      prev: "{{ ._gxo.tasks.b.status }}"
+    register: c_out
+  - name: x
+    type: mock
+    depends_on: [y]
+  - name: y
+    type: mock
+    params:
+      prev: "{{ ._gxo.tasks.x.status }}"
+  - name: independent
+    type: mock
+    params:
+      in: "{{ .a_out }}"
+`
+	report, execErr := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.Error(t, execErr)
+	require.NotNil(t, report)
+	assert.Contains(t, execErr.Error(), "cycle detected in task dependencies: a -[stream]-> b -[state]-> c -[state]-> a")
+	assert.Contains(t, execErr.Error(), "cycle detected in task dependencies: x -[state]-> y -[state]-> x")
+	assert.NotContains(t, execErr.Error(), "independent")
+	assert.Empty(t, report.TaskResults, "No task may run when the playbook has a cycle")
+}
+
+func TestEngine_Cycles_ThroughBlock(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: block_cycle_test
+tasks:
+  - name: group
+    block:
+      - name: member
+        type: mock
+        depends_on: [outside]
+  - name: outside
+    type: mock
+    params:
+      prev: "{{ ._gxo.tasks.group.status }}"
+`
+	_, execErr := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.Error(t, execErr)
+	assert.Contains(t, execErr.Error(), "cycle detected in task dependencies: group -[state]-> outside -[state]-> member -[block]-> group")
+}
+
+func TestEngine_Cycles_ReportEveryCycleOfAComponent(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: component_cycles_test
+tasks:
+  - name: a
+    type: mock
+    params:
+      in: "{{ .b_out }} {{ .c_out }}"
+    register: a_out
+  - name: b
+    type: mock
+    stream_inputs: [a]
+    params:
+      prev: "{{ ._gxo.tasks.a.status }}"
+    register: b_out
+  - name: c
+    type: mock
+    params:
+      in: "{{ .a_out }}"
+    register: c_out
+`
+	_, execErr := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.Error(t, execErr)
+	assert.Contains(t, execErr.Error(), "cycle detected in task dependencies: a -[state+stream]-> b -[state]-> a", "Both kinds of a parallel edge must be reported")
+	assert.Contains(t, execErr.Error(), "cycle detected in task dependencies: a -[state]-> c -[state]-> a", "Every cycle of a group of tasks must be reported")
+}
@@ -0,0 +1,145 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_DependsOn_OrdersTasks(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: depends_on_test
+tasks:
+  - name: start_app
+    type: mock
+    depends_on: [migrate, seed]
+  - name: migrate
+    type: mock
+    params:
+      _mock_delay: "200ms"
+  - name: seed
+    type: mock
+    depends_on: [migrate]
+`
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	report, execErr := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+
+	require.NoError(t, execErr)
+	require.NotNil(t, report)
+	migrate, seed, startApp := report.TaskResults["migrate"], report.TaskResults["seed"], report.TaskResults["start_app"]
+	assert.False(t, seed.StartTime.Before(migrate.EndTime), "seed must start after migrate finished")
+	assert.False(t, startApp.StartTime.Before(seed.EndTime), "start_app must start after seed finished")
+}
+
+func TestEngine_DependsOn_FailedDependencySkipsTask(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: depends_on_failure_test
+tasks:
+  - name: migrate
+    type: mock
+    ignore_errors: true
+    params:
+      fail_message: "migration failed"
+  - name: start_app
+    type: mock
+    depends_on: [migrate]
+`
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	report, execErr := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+
+	require.Error(t, execErr)
+	require.NotNil(t, report)
+	assert.Equal(t, "Skipped", report.TaskResults["start_app"].Status)
+}
+
+func TestEngine_DependsOn_ValidationErrors(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+
+	testCases := []struct {
+		name         string
+		playbookYAML string
+		expected     string
+	}{
+		{
+			name: "unknown task",
+			playbookYAML: `
+schemaVersion: "v1.0.0"
+name: depends_on_unknown
+tasks:
+  - name: task_a
+    type: mock
+    depends_on: [missing]
+`,
+			expected: "task name 'missing' is referenced by another task but is not defined",
+		},
+		{
+			name: "self reference",
+			playbookYAML: `
+schemaVersion: "v1.0.0"
+name: depends_on_self
+tasks:
+  - name: task_a
+    type: mock
+    depends_on: [task_a]
+`,
+			expected: "'depends_on' cannot target itself",
+		},
+		{
+			name: "cycle",
+			playbookYAML: `
+schemaVersion: "v1.0.0"
+name: depends_on_cycle
+tasks:
+  - name: task_a
+    type: mock
+    depends_on: [task_c]
+  - name: task_b
+    type: mock
+    depends_on: [task_a]
+  - name: task_c
+    type: mock
+    depends_on: [task_b]
+`,
+			expected: "cycle detected in task dependencies: task_a -[state]-> task_b -[state]-> task_c -[state]-> task_a",
+		},
+		{
+			name: "later section",
+			playbookYAML: `
+schemaVersion: "v1.0.0"
+name: depends_on_later_section
+tasks:
+  - name: task_a
+    type: mock
+    depends_on: [cleanup]
+finally:
+  - name: cleanup
+    type: mock
+`,
+			expected: "cannot depend on task 'cleanup' in the later 'finally' section",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, execErr := engineInstance.RunPlaybook(context.Background(), []byte(tc.playbookYAML))
+			require.Error(t, execErr)
+			assert.Contains(t, execErr.Error(), tc.expected)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gxo-labs/gxo/internal/config"
+	gxo "github.com/gxo-labs/gxo/pkg/gxo/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_Diagnostics_SchemaErrorsHavePositions(t *testing.T) {
+	playbookYAML := `schemaVersion: "v1.0.0"
+name: diagnostics_test
+tasks:
+  - name: first
+    type: mock
+    retry:
+      attempts: 0
+  - name: second
+    type: mock
+    unknown_key: true
+`
+	_, err := config.LoadPlaybook([]byte(playbookYAML), "diag.yaml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "diag.yaml:7:7: Field 'tasks.0.retry.attempts'")
+
+	diagnostics := config.Diagnostics(err, "diag.yaml")
+	require.Len(t, diagnostics, 2)
+	assert.Equal(t, config.Diagnostic{File: "diag.yaml", Line: 7, Column: 7, Field: "tasks.0.retry.attempts", Message: "Field 'tasks.0.retry.attempts': Must be greater than or equal to 1"}, diagnostics[0])
+	assert.Equal(t, 10, diagnostics[1].Line, "Unknown keys are located at the key itself")
+	assert.Equal(t, "tasks.1.unknown_key", diagnostics[1].Field)
+}
+
+func TestEngine_Diagnostics_StructureErrorsHavePositions(t *testing.T) {
+	playbookYAML := `schemaVersion: "v1.0.0"
+name: diagnostics_test
+tasks:
+  - name: first
+    type: mock
+    register: result
+  - name: second
+    type: mock
+    register: result
+    depends_on: [missing]
+  - block:
+      - name: nested
+        type: mock
+    rescue:
+      - name: rescuer
+        type: mock
+        retry:
+          attempts: 2
+          delay: 2s
+          max_delay: 1s
+`
+	_, err := config.LoadPlaybook([]byte(playbookYAML), "diag.yaml")
+	require.Error(t, err)
+
+	diagnostics := config.Diagnostics(err, "diag.yaml")
+	require.Len(t, diagnostics, 3)
+	assert.Equal(t, 9, diagnostics[0].Line)
+	assert.Equal(t, "tasks.1.register", diagnostics[0].Field)
+	assert.Contains(t, diagnostics[0].Message, "'register' key 'result' is already used by task 'first'")
+	assert.Equal(t, 10, diagnostics[1].Line)
+	assert.Contains(t, diagnostics[1].Message, "task name 'missing' is referenced by another task but is not defined")
+	assert.Equal(t, "diag.yaml:20:11: task 2 > rescue 0 ('rescuer'): 'retry.max_delay' (1s) cannot be less than 'retry.delay' (2s)", diagnostics[2].String())
+}
+
+func TestEngine_Diagnostics_YAMLSyntaxErrorHasLine(t *testing.T) {
+	_, err := config.LoadPlaybook([]byte("schemaVersion: \"v1.0.0\"\nname: broken\ntasks:\n  - name: a\n\ttype: mock\n"), "diag.yaml")
+	require.Error(t, err)
+
+	diagnostics := config.Diagnostics(err, "diag.yaml")
+	require.Len(t, diagnostics, 1)
+	assert.Equal(t, "diag.yaml", diagnostics[0].File)
+	assert.Greater(t, diagnostics[0].Line, 0)
+	assert.Contains(t, diagnostics[0].Message, "tab character")
+}
+
+func TestEngine_Diagnostics_RoleErrorsPointToRoleFile(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+	dir := t.TempDir()
+	rolesPath := writePlaybookFile(t, dir, "roles.yaml", `
+roles:
+  checked:
+    tasks:
+      - name: check
+        type: mock
+        timeout: "soon"
+`)
+	playbookPath := writePlaybookFile(t, dir, "playbook.yaml", "")
+
+	playbookYAML := "schemaVersion: \"v1.0.0\"\nname: diagnostics_test\nimports: [roles.yaml]\ntasks:\n  - use: checked\n"
+	ctx := context.WithValue(context.Background(), gxo.PlaybookPathKey{}, playbookPath)
+	_, err := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+	require.Error(t, err)
+
+	diagnostics := config.Diagnostics(err, playbookPath)
+	require.Len(t, diagnostics, 1)
+	assert.Equal(t, rolesPath, diagnostics[0].File)
+	assert.Equal(t, 7, diagnostics[0].Line)
+	assert.Equal(t, "tasks.0.timeout", diagnostics[0].Field)
+}
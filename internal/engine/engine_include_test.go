@@ -0,0 +1,224 @@
+package engine_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePlaybookFile writes a playbook to dir and returns its path.
+func writePlaybookFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+const includedDeployPlaybook = `
+schemaVersion: "v1.0.0"
+name: deploy
+vars:
+  greeting: hello
+  target: nowhere
+tasks:
+  - name: build
+    type: mock
+    params:
+      message: "{{ .greeting }} {{ .target }}"
+    register: build_result
+outputs:
+  message: "{{ .build_result.message }}"
+  target: "{{ .target }}"
+`
+
+func TestEngine_Include_PassesVarsAndReturnsOutputs(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, stateStore := setupTestEngine(t, reg)
+	subPath := writePlaybookFile(t, t.TempDir(), "deploy.yaml", includedDeployPlaybook)
+
+	playbookYAML := fmt.Sprintf(`
+schemaVersion: "v1.0.0"
+name: include_test
+vars:
+  env: prod
+tasks:
+  - name: deploy
+    type: include_playbook
+    params:
+      path: %q
+      vars:
+        target: "{{ .env }}"
+    register: deployed
+  - name: announce
+    type: mock
+    depends_on: [deploy]
+    params:
+      text: "deployed: {{ .deployed.message }}"
+    register: announced
+`, subPath)
+	report, err := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.NoError(t, err)
+	assert.Equal(t, "Completed", report.OverallStatus)
+
+	deployed, exists := stateStore.Get("deployed")
+	require.True(t, exists)
+	assert.Equal(t, map[string]interface{}{"message": "hello prod", "target": "prod"}, deployed)
+	announced, _ := stateStore.Get("announced")
+	assert.Equal(t, "deployed: hello prod", announced.(map[string]interface{})["text"])
+
+	// The included playbook's state lives in its own namespace.
+	_, leaked := stateStore.Get("build_result")
+	assert.False(t, leaked, "Included registrations must not leak into the parent's namespace")
+	nested, exists := stateStore.Get("_gxo.includes.deploy.build_result")
+	require.True(t, exists)
+	assert.Equal(t, "hello prod", nested.(map[string]interface{})["message"])
+
+	deployResult := report.TaskResults["deploy"]
+	assert.Equal(t, "Completed", deployResult.Status)
+	require.Len(t, deployResult.NestedReports, 1)
+	nestedReport := deployResult.NestedReports[0]
+	assert.Equal(t, "deploy", nestedReport.PlaybookName)
+	assert.Equal(t, report.RunID+"/deploy", nestedReport.RunID)
+	assert.Equal(t, "Completed", nestedReport.TaskResults["build"].Status)
+	assert.Empty(t, report.TaskResults["announce"].NestedReports)
+}
+
+func TestEngine_Include_FailureFailsTask(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+	subPath := writePlaybookFile(t, t.TempDir(), "failing.yaml", `
+schemaVersion: "v1.0.0"
+name: failing
+tasks:
+  - name: broken
+    type: mock
+    params:
+      fail_message: "boom"
+`)
+
+	playbookYAML := fmt.Sprintf(`
+schemaVersion: "v1.0.0"
+name: include_failure_test
+tasks:
+  - name: run_failing
+    type: include_playbook
+    params:
+      path: %q
+  - name: after
+    type: mock
+    depends_on: [run_failing]
+`, subPath)
+	report, err := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.Error(t, err)
+	assert.Equal(t, "Failed", report.OverallStatus)
+
+	result := report.TaskResults["run_failing"]
+	assert.Equal(t, "Failed", result.Status)
+	assert.Contains(t, result.Error, "included playbook 'failing' failed")
+	require.Len(t, result.NestedReports, 1)
+	assert.Equal(t, "Failed", result.NestedReports[0].OverallStatus)
+	assert.Equal(t, "Failed", result.NestedReports[0].TaskResults["broken"].Status)
+	assert.NotEqual(t, "Completed", report.TaskResults["after"].Status)
+}
+
+func TestEngine_Include_LoopUsesNamespacePerIteration(t *testing.T) {
+	// A single worker must be enough: include tasks do not hold a worker
+	// while their nested tasks run.
+	engineInstance := setupConcurrentTestEngine(t, 1)
+	subPath := writePlaybookFile(t, t.TempDir(), "deploy.yaml", includedDeployPlaybook)
+
+	playbookYAML := fmt.Sprintf(`
+schemaVersion: "v1.0.0"
+name: include_loop_test
+tasks:
+  - name: deploy_all
+    type: include_playbook
+    loop: ["eu", "us"]
+    params:
+      path: %q
+      vars:
+        target: "{{ .item }}"
+`, subPath)
+	report, err := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.NoError(t, err)
+	assert.Equal(t, "Completed", report.OverallStatus)
+
+	reports := report.TaskResults["deploy_all"].NestedReports
+	require.Len(t, reports, 2)
+	assert.Equal(t, report.RunID+"/deploy_all.0", reports[0].RunID)
+	assert.Equal(t, report.RunID+"/deploy_all.1", reports[1].RunID)
+}
+
+func TestEngine_Include_DetectsCycles(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+	dir := t.TempDir()
+	// Relative paths are resolved against the including playbook's directory.
+	first := writePlaybookFile(t, dir, "first.yaml", `
+schemaVersion: "v1.0.0"
+name: first
+tasks:
+  - name: to_second
+    type: include_playbook
+    params:
+      path: second.yaml
+`)
+	writePlaybookFile(t, dir, "second.yaml", `
+schemaVersion: "v1.0.0"
+name: second
+tasks:
+  - name: to_first
+    type: include_playbook
+    params:
+      path: first.yaml
+`)
+
+	playbookYAML := fmt.Sprintf(`
+schemaVersion: "v1.0.0"
+name: include_cycle_test
+tasks:
+  - name: start
+    type: include_playbook
+    params:
+      path: %q
+`, first)
+	report, err := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.Error(t, err)
+	result := report.TaskResults["start"]
+	assert.Equal(t, "Failed", result.Status)
+	assert.Contains(t, result.Error, fmt.Sprintf("include cycle detected: %s -> %s -> %s", first, filepath.Join(dir, "second.yaml"), first))
+}
+
+func TestEngine_Include_ValidatesParams(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: include_validation_test
+tasks:
+  - name: bad_include
+    type: include_playbook
+    params:
+      vars: "not a map"
+      extra: true
+  - name: consumer
+    type: mock
+    stream_inputs: [bad_include]
+`
+	_, err := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "'include_playbook' requires a non-empty string 'params.path'")
+	assert.Contains(t, err.Error(), "'params.vars' must be a map")
+	assert.Contains(t, err.Error(), "unsupported param 'extra' for 'include_playbook'")
+	assert.Contains(t, err.Error(), "'stream_inputs' target 'bad_include' includes a playbook and produces no stream")
+}
@@ -0,0 +1,162 @@
+package engine_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/gxo-labs/gxo/internal/config"
+	gxo "github.com/gxo-labs/gxo/pkg/gxo/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const inputsPlaybook = `
+schemaVersion: v1.0.0
+name: inputs_test
+vars:
+  greeting: hello
+inputs:
+  env:
+    type: string
+    required: true
+    enum: [dev, prod]
+    description: Target environment.
+  replicas:
+    type: int
+    default: 1
+  debug:
+    type: bool
+    default: false
+  hosts:
+    type: list
+tasks:
+  - name: deploy
+    type: mock
+    register: deploy_out
+    params:
+      target: "{{ .greeting }} {{ .env }}"
+`
+
+func TestEngine_Inputs_SuppliedValuesAndDefaults(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, stateStore := setupTestEngine(t, reg)
+
+	supplied := map[string]interface{}{"env": "prod", "replicas": "3", "hosts": "[a, b]"}
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, gxo.InputValuesKey{}, supplied)
+	report, err := engineInstance.RunPlaybook(ctx, []byte(inputsPlaybook))
+	require.NoError(t, err)
+	assert.Equal(t, "Completed", report.OverallStatus)
+
+	replicas, _ := stateStore.Get("replicas")
+	assert.Equal(t, 3, replicas, "String values are parsed into the declared type")
+	debug, _ := stateStore.Get("debug")
+	assert.Equal(t, false, debug, "Unsupplied inputs take their default")
+	hosts, _ := stateStore.Get("hosts")
+	assert.Equal(t, []interface{}{"a", "b"}, hosts)
+	deployOut, found := stateStore.Get("deploy_out")
+	require.True(t, found)
+	assert.Equal(t, "hello prod", deployOut.(map[string]interface{})["target"])
+}
+
+func TestEngine_Inputs_InvalidValuesFailBeforeRun(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, stateStore := setupTestEngine(t, reg)
+
+	supplied := map[string]interface{}{"env": "staging", "replicas": "many", "region": "eu"}
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, gxo.InputValuesKey{}, supplied)
+	_, err := engineInstance.RunPlaybook(ctx, []byte(inputsPlaybook))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "playbook 'inputs_test' has 3 invalid input(s)")
+	assert.Contains(t, err.Error(), "unknown input 'region' (declared: debug, env, hosts, replicas)")
+	assert.Contains(t, err.Error(), "input 'env': value staging is not one of: dev, prod")
+	assert.Contains(t, err.Error(), `input 'replicas': expected an int, got "many"`)
+	_, found := stateStore.Get("deploy_out")
+	assert.False(t, found, "No task runs when inputs are invalid")
+
+	_, err = engineInstance.RunPlaybook(context.Background(), []byte(inputsPlaybook))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "input 'env' is required but was not supplied")
+}
+
+func TestEngine_Inputs_IncludeVarsSupplyIncludedInputs(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, stateStore := setupTestEngine(t, reg)
+	dir := t.TempDir()
+	writePlaybookFile(t, dir, "child.yaml", `
+schemaVersion: v1.0.0
+name: child
+inputs:
+  count:
+    type: int
+    required: true
+tasks:
+  - name: child_task
+    type: mock
+outputs:
+  count_type: "{{ printf \"%T\" .count }}"
+`)
+	playbookPath := writePlaybookFile(t, dir, "parent.yaml", "")
+
+	playbookYAML := `
+schemaVersion: v1.0.0
+name: parent
+tasks:
+  - name: run_child
+    type: include_playbook
+    register: child_out
+    params:
+      path: child.yaml
+      vars:
+        count: "21"
+`
+	ctx := context.WithValue(context.Background(), gxo.PlaybookPathKey{}, playbookPath)
+	report, err := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+	require.NoError(t, err)
+	assert.Equal(t, "Completed", report.TaskResults["run_child"].Status)
+	childOut, found := stateStore.Get("child_out")
+	require.True(t, found)
+	assert.Equal(t, "int", childOut.(map[string]interface{})["count_type"], "Include vars are parsed into the declared input types")
+}
+
+func TestEngine_Inputs_DeclarationsAreValidated(t *testing.T) {
+	playbookYAML := `
+schemaVersion: v1.0.0
+name: inputs_invalid_test
+vars:
+  env: dev
+inputs:
+  env:
+    type: string
+  replicas:
+    type: int
+    default: many
+  mode:
+    type: string
+    required: true
+    default: fast
+  size:
+    type: string
+    enum: [small, large]
+    default: medium
+tasks:
+  - name: a
+    type: mock
+`
+	_, err := config.LoadPlaybook([]byte(playbookYAML), filepath.Join("inputs", "invalid.yaml"))
+	require.Error(t, err)
+	diagnostics := config.Diagnostics(err, "")
+	require.Len(t, diagnostics, 4)
+	assert.Contains(t, diagnostics[0].Message, "input 'env': name is already used by a playbook var")
+	assert.Contains(t, diagnostics[1].Message, `input 'replicas': invalid 'default': expected an int, got "many"`)
+	assert.Equal(t, 11, diagnostics[1].Line)
+	assert.Contains(t, diagnostics[2].Message, "input 'mode': a required input cannot have a 'default'")
+	assert.Contains(t, diagnostics[3].Message, "input 'size': 'default' medium is not one of: small, large")
+}
@@ -0,0 +1,223 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gxo-labs/gxo/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_Loop_ReportsIterations(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: loop_iterations_test
+tasks:
+  - name: deploy
+    type: mock
+    loop: ["eu", "us", "ap"]
+    params:
+      _mock_delay: "{{ if eq .item \"us\" }}soon{{ else }}1ms{{ end }}"
+`
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	report, err := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+	require.Error(t, err)
+	require.NotNil(t, report)
+
+	result := report.TaskResults["deploy"]
+	assert.Equal(t, "Failed", result.Status)
+	require.Len(t, result.Iterations, 3)
+	for i, iteration := range result.Iterations {
+		assert.Equal(t, i, iteration.Index)
+		assert.False(t, iteration.StartTime.IsZero())
+	}
+	assert.Equal(t, "Completed", result.Iterations[0].Status)
+	assert.Equal(t, "Failed", result.Iterations[1].Status)
+	assert.Contains(t, result.Iterations[1].Error, "invalid _mock_delay format")
+	assert.Equal(t, "Completed", result.Iterations[2].Status)
+}
+
+func TestEngine_NoLoop_ReportsNoIterations(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: no_loop_test
+tasks:
+  - name: single
+    type: mock
+`
+	report, err := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.NoError(t, err)
+	assert.Empty(t, report.TaskResults["single"].Iterations)
+}
+
+func TestEngine_Loop_RegistersResultsInItemOrder(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, stateStore := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: loop_register_test
+tasks:
+  - name: probe
+    type: mock
+    loop: ["a", "b", "c", "d"]
+    loop_control:
+      parallel: 4
+    register: probes
+    ignore_errors: true
+    params:
+      host: "{{ .item }}"
+      _mock_delay: "{{ if eq .item \"a\" }}30ms{{ else if eq .item \"c\" }}bad{{ else }}1ms{{ end }}"
+  - name: summarize
+    type: mock
+    depends_on: [probe]
+    trigger_rule: all_done
+    register: summary
+    params:
+      first: "{{ (index .probes 0).summary.host }}"
+      failed: "{{ (index .probes 2).status }}"
+`
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	report, err := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+	require.Error(t, err, "The failed iteration fails the task")
+	require.NotNil(t, report)
+
+	probes, found := stateStore.Get("probes")
+	require.True(t, found, "A loop registers its results even when an iteration failed")
+	results, ok := probes.([]interface{})
+	require.True(t, ok, "Expected a list, got %T", probes)
+	require.Len(t, results, 4)
+	for i, item := range []string{"a", "b", "c", "d"} {
+		entry := results[i].(map[string]interface{})
+		assert.Equal(t, item, entry["item"])
+		assert.Equal(t, i, entry["index"])
+	}
+	first := results[0].(map[string]interface{})
+	assert.Equal(t, "Completed", first["status"])
+	assert.Equal(t, "a", first["summary"].(map[string]interface{})["host"], "The slowest iteration keeps its place")
+	assert.Equal(t, "", first["error"])
+	failed := results[2].(map[string]interface{})
+	assert.Equal(t, "Failed", failed["status"])
+	assert.Nil(t, failed["summary"])
+	assert.Contains(t, failed["error"], "invalid _mock_delay format")
+
+	summary, found := stateStore.Get("summary")
+	require.True(t, found)
+	assert.Equal(t, "a", summary.(map[string]interface{})["first"])
+	assert.Equal(t, "Failed", summary.(map[string]interface{})["failed"])
+
+	counts := report.TaskResults["probe"].IterationCounts
+	require.NotNil(t, counts)
+	assert.Equal(t, 4, counts.Total)
+	assert.Equal(t, 3, counts.Completed)
+	assert.Equal(t, 1, counts.Failed)
+	assert.Equal(t, 0, counts.Skipped)
+}
+
+func TestEngine_Loop_EmptyLoopRegistersEmptyList(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, stateStore := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: empty_loop_test
+vars:
+  hosts: []
+tasks:
+  - name: probe
+    type: mock
+    loop: "{{ .hosts }}"
+    register: probes
+`
+	_, err := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.NoError(t, err)
+	probes, found := stateStore.Get("probes")
+	require.True(t, found)
+	assert.Equal(t, []interface{}{}, probes)
+}
+
+func TestEngine_LoopMatrix_ExpandsCombinations(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, stateStore := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: loop_matrix_test
+vars:
+  systems: ["linux", "darwin"]
+tasks:
+  - name: build
+    type: mock
+    loop_matrix:
+      os: "{{ .systems }}"
+      arch: ["amd64", "arm64"]
+      exclude:
+        - {os: darwin, arch: amd64}
+      include:
+        - {os: linux, arch: arm64, cgo: true}
+        - {os: windows, arch: amd64}
+    loop_control:
+      parallel: 4
+      loop_var: target
+    register: builds
+    params:
+      platform: "{{ .target.os }}/{{ .target.arch }}"
+`
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	report, err := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+	require.NoError(t, err)
+
+	builds, found := stateStore.Get("builds")
+	require.True(t, found)
+	results := builds.([]interface{})
+	var platforms []string
+	for _, result := range results {
+		platforms = append(platforms, result.(map[string]interface{})["summary"].(map[string]interface{})["platform"].(string))
+	}
+	assert.Equal(t, []string{"linux/amd64", "linux/arm64", "darwin/arm64", "windows/amd64"}, platforms)
+	assert.Equal(t, map[string]interface{}{"os": "linux", "arch": "arm64", "cgo": true}, results[1].(map[string]interface{})["item"])
+
+	iterations := report.TaskResults["build"].Iterations
+	require.Len(t, iterations, 4)
+	assert.Equal(t, "os=linux,arch=amd64", iterations[0].Name)
+	assert.Equal(t, "os=linux,arch=arm64,cgo=true", iterations[1].Name, "Keys added by 'include' follow the axes")
+	assert.Equal(t, "os=windows,arch=amd64", iterations[3].Name)
+}
+
+func TestEngine_LoopMatrix_Validation(t *testing.T) {
+	playbookYAML := `schemaVersion: "v1.0.0"
+name: loop_matrix_validation_test
+tasks:
+  - name: both
+    type: mock
+    loop: [1, 2]
+    loop_matrix:
+      os: [linux]
+  - name: bad_axes
+    type: mock
+    loop_matrix:
+      os: "linux"
+      exclude:
+        - {arch: arm64}
+`
+	_, err := config.LoadPlaybook([]byte(playbookYAML), "matrix.yaml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "'loop' and 'loop_matrix' cannot be combined")
+	assert.Contains(t, err.Error(), "'loop_matrix' axis 'os' must be a list, or a template resolving to one")
+	assert.Contains(t, err.Error(), "'loop_matrix.exclude' entry 0 references unknown axis 'arch'")
+}
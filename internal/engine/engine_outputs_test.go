@@ -0,0 +1,113 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_Outputs_RenderedIntoReport(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: v1.0.0
+name: outputs_test
+vars:
+  region: eu-west-1
+tasks:
+  - name: build
+    type: mock
+    register: build_out
+    params:
+      image: app:1.2.3
+      layers: 7
+outputs:
+  image: "{{ .build_out.image }}"
+  layers: "{{ .build_out.layers }}"
+  summary: "{{ .build_out.image }} in {{ .region }}"
+`
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	report, err := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"image":   "app:1.2.3",
+		"layers":  7,
+		"summary": "app:1.2.3 in eu-west-1",
+	}, report.Outputs, "Single references keep their native type")
+}
+
+func TestEngine_Outputs_SecretsAreRedacted(t *testing.T) {
+	engineInstance, _, mockSecrets := setupSecurityTestEngine(t)
+	mockSecrets.AddSecret("DEPLOY_TOKEN", "tok_abc123")
+
+	playbookYAML := `
+schemaVersion: v1.0.0
+name: outputs_secret_test
+tasks:
+  - name: deploy
+    type: mock
+outputs:
+  auth_header: "Bearer {{ secret \"DEPLOY_TOKEN\" }}"
+  target: prod
+`
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	report, err := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+	require.NoError(t, err)
+	assert.Equal(t, "[REDACTED_SECRET]", report.Outputs["auth_header"])
+	assert.Equal(t, "prod", report.Outputs["target"])
+}
+
+func TestEngine_Outputs_RenderFailures(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+
+	t.Run("fail a successful run", func(t *testing.T) {
+		engineInstance, _ := setupTestEngine(t, reg)
+		playbookYAML := `
+schemaVersion: v1.0.0
+name: outputs_missing_test
+tasks:
+  - name: a
+    type: mock
+outputs:
+  missing: "{{ .never_registered.value }}"
+`
+		report, err := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to render output 'missing' of playbook 'outputs_missing_test'")
+		assert.Equal(t, "Failed", report.OverallStatus)
+	})
+
+	t.Run("are skipped after a failed run", func(t *testing.T) {
+		engineInstance, _ := setupTestEngine(t, reg)
+		playbookYAML := `
+schemaVersion: v1.0.0
+name: outputs_failed_run_test
+tasks:
+  - name: first
+    type: mock
+    register: first_out
+    params:
+      value: kept
+  - name: broken
+    type: mock
+    register: broken_out
+    depends_on: [first]
+    params:
+      fail_message: boom
+outputs:
+  first: "{{ .first_out.value }}"
+  broken: "{{ .broken_out.value }}"
+`
+		report, err := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "boom", "The run keeps its own error")
+		assert.Equal(t, map[string]interface{}{"first": "kept"}, report.Outputs)
+	})
+}
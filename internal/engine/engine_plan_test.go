@@ -0,0 +1,161 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/gxo-labs/gxo/internal/config"
+	"github.com/gxo-labs/gxo/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// planWaveNames returns the display names of the tasks of each wave.
+func planWaveNames(section engine.SectionPlan) [][]string {
+	var waves [][]string
+	for _, wave := range section.Waves {
+		var names []string
+		for _, task := range wave.Tasks {
+			names = append(names, task.DisplayName())
+		}
+		waves = append(waves, names)
+	}
+	return waves
+}
+
+func plannedTask(t *testing.T, p *engine.Plan, name string) engine.PlannedTask {
+	t.Helper()
+	for _, section := range p.Sections {
+		for _, wave := range section.Waves {
+			for _, task := range wave.Tasks {
+				if task.DisplayName() == name {
+					return task
+				}
+			}
+		}
+	}
+	t.Fatalf("task '%s' not found in plan", name)
+	return engine.PlannedTask{}
+}
+
+func TestBuildPlan_WavesAndCriticalPath(t *testing.T) {
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: plan_waves
+tasks:
+  - name: fetch
+    type: mock
+    register: fetched
+  - name: lint
+    type: mock
+  - name: test
+    type: mock
+  - name: consume
+    type: mock
+    stream_inputs: [fetch]
+  - name: build
+    type: mock
+    depends_on: [lint, test]
+  - name: publish
+    type: mock
+    params:
+      data: "{{ .fetched }}"
+    depends_on: [build]
+finally:
+  - name: cleanup
+    type: mock
+`
+	playbook, err := config.LoadPlaybook([]byte(playbookYAML), "plan.yaml")
+	require.NoError(t, err)
+
+	unlimited, err := engine.BuildPlan(playbook, 8)
+	require.NoError(t, err)
+	require.Len(t, unlimited.Sections, 2)
+	assert.Equal(t, [][]string{{"fetch", "lint", "test", "consume"}, {"build"}, {"publish"}}, planWaveNames(unlimited.Sections[0]))
+	assert.Equal(t, []string{"lint", "build", "publish"}, unlimited.Sections[0].CriticalPath)
+	assert.Equal(t, config.SectionFinally, unlimited.Sections[1].Section)
+	assert.Equal(t, [][]string{{"cleanup"}}, planWaveNames(unlimited.Sections[1]))
+
+	limited, err := engine.BuildPlan(playbook, 2)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"fetch", "lint"}, {"test", "consume"}, {"build"}, {"publish"}}, planWaveNames(limited.Sections[0]))
+
+	_, err = engine.BuildPlan(playbook, 0)
+	require.Error(t, err)
+}
+
+func TestBuildPlan_LoopsAndConditions(t *testing.T) {
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: plan_static
+vars:
+  envs: [dev, staging, prod]
+  enabled: false
+  not_a_list: "text"
+tasks:
+  - name: produce
+    type: mock
+    register: produced
+  - name: literal_loop
+    type: mock
+    loop: [a, b]
+    loop_control:
+      parallel: 2
+  - name: vars_loop
+    type: mock
+    loop: "{{ .envs }}"
+  - name: runtime_loop
+    type: mock
+    loop: "{{ .produced }}"
+  - name: broken_loop
+    type: mock
+    loop: "{{ .not_a_list }}"
+  - name: disabled
+    type: mock
+    when: "{{ .enabled }}"
+  - name: enabled
+    type: mock
+    when: "{{ not .enabled }}"
+  - name: after_produce
+    type: mock
+    when: '{{ eq ._gxo.tasks.produce.status "Completed" }}'
+  - name: group
+    when: "{{ .enabled }}"
+    block:
+      - name: member
+        type: mock
+  - name: overridden
+    type: mock
+    vars:
+      enabled: true
+    when: "{{ .enabled }}"
+    loop: "{{ .envs }}"
+  - name: scoped_group
+    vars:
+      envs: [eu]
+    block:
+      - name: scoped_member
+        type: mock
+        loop: "{{ .envs }}"
+`
+	playbook, err := config.LoadPlaybook([]byte(playbookYAML), "plan.yaml")
+	require.NoError(t, err)
+	p, err := engine.BuildPlan(playbook, 4)
+	require.NoError(t, err)
+
+	assert.Equal(t, &engine.LoopPlan{Static: true, Items: 2, Parallel: 2}, plannedTask(t, p, "literal_loop").Loop)
+	assert.Equal(t, &engine.LoopPlan{Static: true, Items: 3, Parallel: 1}, plannedTask(t, p, "vars_loop").Loop)
+	assert.Equal(t, &engine.LoopPlan{Static: false, Parallel: 1}, plannedTask(t, p, "runtime_loop").Loop)
+	assert.Contains(t, plannedTask(t, p, "broken_loop").Loop.Error, "expected slice, array, or map")
+	assert.Nil(t, plannedTask(t, p, "produce").Loop)
+
+	assert.Equal(t, engine.PlanWhenFalse, plannedTask(t, p, "disabled").When)
+	assert.Equal(t, engine.PlanWhenTrue, plannedTask(t, p, "enabled").When)
+	assert.Equal(t, engine.PlanWhenRuntime, plannedTask(t, p, "after_produce").When)
+	assert.Empty(t, plannedTask(t, p, "produce").When)
+	assert.Equal(t, engine.PlanWhenFalse, plannedTask(t, p, "member").When, "Members inherit the block's condition")
+	assert.Empty(t, plannedTask(t, p, "group").When)
+
+	assert.Equal(t, engine.PlanWhenRuntime, plannedTask(t, p, "overridden").When, "Task vars shadow the playbook's and are only known while running")
+	assert.Equal(t, &engine.LoopPlan{Static: true, Items: 3, Parallel: 1}, plannedTask(t, p, "overridden").Loop)
+	assert.Equal(t, &engine.LoopPlan{Static: false, Parallel: 1}, plannedTask(t, p, "scoped_member").Loop, "Vars inherited from a block shadow the playbook's")
+}
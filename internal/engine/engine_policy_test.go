@@ -7,6 +7,9 @@ import (
 	"testing"
 
 	"github.com/gxo-labs/gxo/internal/config"
+	"github.com/gxo-labs/gxo/internal/engine"
+	intState "github.com/gxo-labs/gxo/internal/state"
+	"github.com/gxo-labs/gxo/internal/template"
 	gxoerrors "github.com/gxo-labs/gxo/pkg/gxo/v1/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -180,4 +183,131 @@ tasks:
 	assert.Equal(t, "Skipped", report.TaskResults["task_a_skipped"].Status)
 	require.NotNil(t, report.TaskResults["task_b_fails"])
 	assert.Equal(t, "Failed", report.TaskResults["task_b_fails"].Status)
-}
\ No newline at end of file
+}
+
+const namedPoliciesPlaybook = `
+schemaVersion: v1.0.0
+name: named_policy_test
+channel_policy:
+  buffer_size: 7
+policies:
+  critical:
+    retry:
+      attempts: 3
+      delay: 10ms
+    timeout: 50ms
+    task_policy:
+      skip_on_no_input: true
+    state_policy:
+      access_mode: unsafe_direct_reference
+    channel_policy:
+      overflow_strategy: drop_oldest
+tasks:
+  - name: call
+    type: mock
+    policy_ref: critical
+  - name: override
+    type: mock
+    policy_ref: critical
+    timeout: 2s
+  - name: group
+    policy_ref: critical
+    block:
+      - name: member_own_retry
+        type: mock
+        retry:
+          attempts: 1
+      - name: member_inherits
+        type: mock
+  - name: plain
+    type: mock
+`
+
+// TestEngine_NamedPolicy_ResolvedInDAG verifies the precedence of a task's own
+// settings over its named policy, and of the named policy over the playbook
+// and block levels.
+func TestEngine_NamedPolicy_ResolvedInDAG(t *testing.T) {
+	playbook, err := config.LoadPlaybook([]byte(namedPoliciesPlaybook), "named_policies.yaml")
+	require.NoError(t, err)
+	dag, _, err := engine.BuildDAG(playbook, intState.NewMemoryStateStore(), template.NewGoRenderer(nil, nil, nil))
+	require.NoError(t, err)
+
+	call := dag.Nodes["call"]
+	assert.Equal(t, 3, call.Task.GetRetryAttempts())
+	assert.Equal(t, "50ms", call.Task.Timeout)
+	assert.True(t, *call.TaskPolicy.SkipOnNoInput)
+	assert.Equal(t, config.StateAccessUnsafeDirectReference, call.StatePolicy.AccessMode)
+	require.NotNil(t, call.ChannelPolicy)
+	assert.Equal(t, config.OverflowDropOldest, call.ChannelPolicy.OverflowStrategy)
+	assert.Equal(t, 7, *call.ChannelPolicy.BufferSize, "Unset channel settings fall back to the playbook's")
+
+	override := dag.Nodes["override"]
+	assert.Equal(t, "2s", override.Task.Timeout, "The task's own timeout wins over its policy's")
+	assert.Equal(t, 3, override.Task.GetRetryAttempts())
+
+	assert.Equal(t, 1, dag.Nodes["member_own_retry"].Task.GetRetryAttempts())
+	assert.Equal(t, "50ms", dag.Nodes["member_own_retry"].Task.Timeout)
+	assert.Equal(t, 3, dag.Nodes["member_inherits"].Task.GetRetryAttempts())
+	assert.Equal(t, config.OverflowDropOldest, dag.Nodes["member_inherits"].ChannelPolicy.OverflowStrategy)
+
+	plain := dag.Nodes["plain"]
+	assert.Equal(t, 1, plain.Task.GetRetryAttempts())
+	assert.Equal(t, config.StateAccessDeepCopy, plain.StatePolicy.AccessMode)
+	require.NotNil(t, plain.ChannelPolicy)
+	assert.Equal(t, 7, *plain.ChannelPolicy.BufferSize)
+}
+
+// TestEngine_NamedPolicy_TimeoutApplies verifies that a named policy's timeout
+// is enforced at run time.
+func TestEngine_NamedPolicy_TimeoutApplies(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: v1.0.0
+name: named_policy_timeout_test
+policies:
+  quick:
+    timeout: 50ms
+tasks:
+  - name: too_slow
+    type: mock
+    policy_ref: quick
+    params:
+      _mock_delay: 300ms
+  - name: given_more_time
+    type: mock
+    policy_ref: quick
+    timeout: 5s
+    params:
+      _mock_delay: 300ms
+`
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	report, err := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+	require.Error(t, err)
+	require.NotNil(t, report)
+	assert.Equal(t, "Failed", report.TaskResults["too_slow"].Status)
+	assert.Contains(t, report.TaskResults["too_slow"].Error, "deadline exceeded")
+	assert.Equal(t, "Completed", report.TaskResults["given_more_time"].Status)
+}
+
+// TestEngine_NamedPolicy_UnknownReference verifies that references to
+// undefined policies are rejected when the playbook is loaded.
+func TestEngine_NamedPolicy_UnknownReference(t *testing.T) {
+	playbookYAML := `
+schemaVersion: v1.0.0
+name: named_policy_unknown_test
+policies:
+  critical:
+    timeout: 1s
+tasks:
+  - name: call
+    type: mock
+    policy_ref: critcal
+`
+	_, err := config.LoadPlaybook([]byte(playbookYAML), "unknown_policy.yaml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown_policy.yaml:10:5: task 0 ('call'): 'policy_ref' references unknown policy 'critcal' (defined: critical)")
+}
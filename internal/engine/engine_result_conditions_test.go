@@ -0,0 +1,222 @@
+package engine_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gxo-labs/gxo/internal/engine"
+	"github.com/gxo-labs/gxo/pkg/gxo/v1/plugin"
+	gxov1state "github.com/gxo-labs/gxo/pkg/gxo/v1/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exitModule behaves like a command that exits with its 'exit_code' param:
+// it returns its summary along with an error when the code is not zero. A
+// 'nil_summary' param makes it return no summary. It counts its executions.
+type exitModule struct {
+	calls *atomic.Int32
+}
+
+func (m *exitModule) Perform(
+	ctx context.Context,
+	params map[string]interface{},
+	stateReader gxov1state.StateReader,
+	inputs map[string]<-chan map[string]interface{},
+	outputChans []chan<- map[string]interface{},
+	errChan chan<- error,
+) (interface{}, error) {
+	m.calls.Add(1)
+	exitCode, _ := params["exit_code"].(int)
+	if nilSummary, _ := params["nil_summary"].(bool); nilSummary {
+		if exitCode != 0 {
+			return nil, fmt.Errorf("command exited with non-zero status: %d", exitCode)
+		}
+		return nil, nil
+	}
+	summary := map[string]interface{}{"exit_code": exitCode, "stdout": params["stdout"]}
+	if exitCode != 0 {
+		return summary, fmt.Errorf("command exited with non-zero status: %d", exitCode)
+	}
+	return summary, nil
+}
+
+func newResultConditionsRegistry(t *testing.T) *InMemoryRegistry {
+	reg, _ := newCountingResultConditionsRegistry(t)
+	return reg
+}
+
+// newCountingResultConditionsRegistry also returns the number of executions
+// of the 'exit' module.
+func newCountingResultConditionsRegistry(t *testing.T) (*InMemoryRegistry, *atomic.Int32) {
+	calls := &atomic.Int32{}
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	require.NoError(t, reg.Register("exit", func() plugin.Module { return &exitModule{calls: calls} }))
+	return reg, calls
+}
+
+func TestEngine_FailedWhen_DecidesFailure(t *testing.T) {
+	engineInstance, stateStore := setupTestEngine(t, newResultConditionsRegistry(t))
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: failed_when_test
+tasks:
+  - name: grep_errors
+    type: exit
+    params:
+      exit_code: 1
+    failed_when: "{{ gt .result.exit_code 1 }}"
+    register: grep_out
+  - name: check_output
+    type: exit
+    params:
+      stdout: "ERROR: disk full"
+    failed_when: '{{ eq .result.stdout "ERROR: disk full" }}'
+`
+	report, err := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.Error(t, err)
+
+	assert.Equal(t, "Completed", report.TaskResults["grep_errors"].Status, "A false 'failed_when' overrides the module's error")
+	grepOut, found := stateStore.Get("grep_out")
+	require.True(t, found)
+	assert.Equal(t, 1, grepOut.(map[string]interface{})["exit_code"])
+
+	assert.Equal(t, "Failed", report.TaskResults["check_output"].Status)
+	assert.Contains(t, report.TaskResults["check_output"].Error, "'failed_when' condition is true: [true]")
+}
+
+func TestEngine_FailedWhen_EvaluatedOnceAfterRetries(t *testing.T) {
+	reg, calls := newCountingResultConditionsRegistry(t)
+	engineInstance, _ := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: failed_when_retry_test
+tasks:
+  - name: check_output
+    type: exit
+    params:
+      stdout: "ERROR: disk full"
+    failed_when: '{{ eq .result.stdout "ERROR: disk full" }}'
+    retry:
+      attempts: 3
+      delay: 1ms
+`
+	report, err := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.Error(t, err)
+	assert.Equal(t, "Failed", report.TaskResults["check_output"].Status)
+	assert.Equal(t, int32(1), calls.Load(), "A result 'failed_when' fails must not be retried")
+}
+
+func TestEngine_FailedWhen_WithoutSummary(t *testing.T) {
+	engineInstance, _ := setupTestEngine(t, newResultConditionsRegistry(t))
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: failed_when_nil_summary_test
+tasks:
+  - name: tolerated
+    type: exit
+    params:
+      exit_code: 2
+      nil_summary: true
+    failed_when: "false"
+  - name: empty_result
+    type: exit
+    params:
+      nil_summary: true
+    failed_when: "{{ not .result }}"
+`
+	report, err := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.Error(t, err)
+	assert.Equal(t, "Completed", report.TaskResults["tolerated"].Status, "A false 'failed_when' overrides a module error without a summary")
+	assert.Equal(t, "Failed", report.TaskResults["empty_result"].Status, "'failed_when' applies to a missing summary")
+	assert.Contains(t, report.TaskResults["empty_result"].Error, "'failed_when' condition is true")
+}
+
+func TestEngine_ChangedWhen_ReportsChanges(t *testing.T) {
+	engineInstance, _ := setupTestEngine(t, newResultConditionsRegistry(t))
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: changed_when_test
+tasks:
+  - name: ensure_users
+    type: mock
+    loop: ["alice", "bob"]
+    params:
+      created: '{{ if eq .item "bob" }}yes{{ else }}no{{ end }}'
+    changed_when: '{{ eq .result.created "yes" }}'
+  - name: converged
+    type: mock
+    params:
+      created: "no"
+    changed_when: '{{ eq .result.created "yes" }}'
+  - name: module_reported
+    type: mock
+    params:
+      changed: true
+  - name: unknown
+    type: mock
+`
+	report, err := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.NoError(t, err)
+
+	assert.True(t, report.TaskResults["ensure_users"].Changed, "A loop changed something if any iteration did")
+	assert.False(t, report.TaskResults["converged"].Changed)
+	assert.True(t, report.TaskResults["module_reported"].Changed, "Without 'changed_when', the summary's 'changed' key decides")
+	assert.False(t, report.TaskResults["unknown"].Changed)
+	assert.Equal(t, 2, report.ChangedTasks)
+
+	assert.Equal(t, float64(2), changedTotal(t, engineInstance))
+}
+
+func TestEngine_ChangedWhen_CountsOnlyCompletedTasks(t *testing.T) {
+	engineInstance, _ := setupTestEngine(t, newResultConditionsRegistry(t))
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: changed_failed_test
+tasks:
+  - name: partial
+    type: mock
+    loop: ["good", "bad"]
+    ignore_errors: true
+    params:
+      name: "{{ .item }}"
+    failed_when: '{{ eq .result.name "bad" }}'
+    changed_when: "{{ true }}"
+  - name: applied
+    type: mock
+    params:
+      changed: true
+`
+	report, _ := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.NotNil(t, report)
+
+	assert.Equal(t, "Failed", report.TaskResults["partial"].Status)
+	assert.True(t, report.TaskResults["partial"].Changed, "The iteration that succeeded changed something")
+	assert.Equal(t, 1, report.ChangedTasks, "A failed task is not counted as changed")
+	assert.Equal(t, float64(1), changedTotal(t, engineInstance), "The metric counts the same tasks as the report")
+}
+
+// changedTotal returns the sum of the gxo_task_changed_total counters.
+func changedTotal(t *testing.T, engineInstance *engine.Engine) float64 {
+	t.Helper()
+	var total float64
+	families, gatherErr := engineInstance.MetricsRegistryProvider().Registry().Gather()
+	require.NoError(t, gatherErr)
+	for _, family := range families {
+		if family.GetName() != "gxo_task_changed_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			total += metric.GetCounter().GetValue()
+		}
+	}
+	return total
+}
@@ -0,0 +1,228 @@
+package engine_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	gxo "github.com/gxo-labs/gxo/pkg/gxo/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const deployServiceRoles = `
+roles:
+  deploy_service:
+    params:
+      name:
+      replicas: 1
+      labels: [web]
+    tasks:
+      - name: render
+        type: mock
+        params:
+          service: "[[ .name ]]"
+          replicas: "[[ .replicas ]]"
+          labels: "[[ .labels ]]"
+          summary: "[[ .name ]] x[[ .replicas ]]"
+        register: "[[ .prefix ]]_rendered"
+      - name: apply
+        type: mock
+        depends_on: [render]
+        params:
+          render_status: "{{ ._gxo.tasks.render.status }}"
+`
+
+func TestEngine_Roles_ExpandedWithPrefixedNamesAndParams(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, stateStore := setupTestEngine(t, reg)
+	dir := t.TempDir()
+	writePlaybookFile(t, dir, "roles.yaml", deployServiceRoles)
+	playbookPath := writePlaybookFile(t, dir, "playbook.yaml", "")
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: roles_test
+imports: [roles.yaml]
+tasks:
+  - use: deploy_service
+    name: api
+    with:
+      name: api
+      replicas: 3
+  - use: deploy_service
+    with:
+      name: worker
+  - name: done
+    type: mock
+    depends_on: [api_apply, deploy_service_apply]
+`
+	// Imports are resolved relative to the playbook's path.
+	ctx := context.WithValue(context.Background(), gxo.PlaybookPathKey{}, playbookPath)
+	report, err := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+	require.NoError(t, err)
+	assert.Equal(t, "Completed", report.OverallStatus)
+	for _, name := range []string{"api_render", "api_apply", "deploy_service_render", "deploy_service_apply", "done"} {
+		require.Contains(t, report.TaskResults, name)
+		assert.Equal(t, "Completed", report.TaskResults[name].Status, "Task %s", name)
+	}
+
+	rendered, exists := stateStore.Get("api_rendered")
+	require.True(t, exists)
+	apiParams := rendered.(map[string]interface{})
+	assert.Equal(t, "api", apiParams["service"])
+	assert.Equal(t, 3, apiParams["replicas"], "A parameter used alone keeps its type")
+	assert.Equal(t, []interface{}{"web"}, apiParams["labels"])
+	assert.Equal(t, "api x3", apiParams["summary"])
+
+	rendered, exists = stateStore.Get("deploy_service_rendered")
+	require.True(t, exists)
+	assert.Equal(t, "worker", rendered.(map[string]interface{})["service"])
+	assert.Equal(t, 1, rendered.(map[string]interface{})["replicas"], "Defaults apply to omitted parameters")
+}
+
+func TestEngine_Roles_StatusReferencesPrefixedOnce(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, stateStore := setupTestEngine(t, reg)
+	dir := t.TempDir()
+	writePlaybookFile(t, dir, "roles.yaml", `
+roles:
+  checks:
+    tasks:
+      - name: x
+        type: mock
+      - name: api_x
+        type: mock
+        when: "{{ false }}"
+      - name: report
+        type: mock
+        params:
+          x_status: "{{ ._gxo.tasks.x.status }}"
+          api_x_status: "{{ ._gxo.tasks.api_x.status }}"
+        register: checks_report
+`)
+	playbookPath := writePlaybookFile(t, dir, "playbook.yaml", "")
+	ctx := context.WithValue(context.Background(), gxo.PlaybookPathKey{}, playbookPath)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: roles_status_test
+imports: [roles.yaml]
+tasks:
+  - use: checks
+    name: api
+`
+	// The references used to be rewritten in map order, so run it a few times.
+	for i := 0; i < 3; i++ {
+		report, err := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+		require.NoError(t, err)
+		assert.Equal(t, "Skipped", report.TaskResults["api_api_x"].Status)
+
+		checksReport, exists := stateStore.Get("checks_report")
+		require.True(t, exists)
+		params := checksReport.(map[string]interface{})
+		assert.Equal(t, "Completed", params["x_status"], "The reference to 'x' must point to 'api_x'")
+		assert.Equal(t, "Skipped", params["api_x_status"], "The reference to 'api_x' must point to 'api_api_x'")
+	}
+}
+
+func TestEngine_Roles_Errors(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+	dir := t.TempDir()
+	writePlaybookFile(t, dir, "roles.yaml", deployServiceRoles)
+	playbookPath := writePlaybookFile(t, dir, "playbook.yaml", "")
+	ctx := context.WithValue(context.Background(), gxo.PlaybookPathKey{}, playbookPath)
+
+	testCases := []struct {
+		name     string
+		tasks    string
+		expected []string
+	}{
+		{
+			name:     "UnknownRole",
+			tasks:    "  - use: deploy_serivce\n    with: {name: api}\n",
+			expected: []string{playbookPath + ":7:5: unknown role 'deploy_serivce' (imported roles: deploy_service)"},
+		},
+		{
+			name:     "MissingParam",
+			tasks:    "  - use: deploy_service\n",
+			expected: []string{"role 'deploy_service' requires parameter(s): name"},
+		},
+		{
+			name:     "UnknownParam",
+			tasks:    "  - use: deploy_service\n    with: {name: api, replica: 2}\n",
+			expected: []string{"role 'deploy_service' has no parameter 'replica'"},
+		},
+		{
+			name:     "NameClash",
+			tasks:    "  - use: deploy_service\n    with: {name: api}\n  - name: deploy_service_render\n    type: mock\n",
+			expected: []string{"('deploy_service_render'): duplicate task name found"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			playbookYAML := fmt.Sprintf("schemaVersion: \"v1.0.0\"\nname: roles_error_test\nimports: [roles.yaml]\n\ntasks:\n\n%s", tc.tasks)
+			_, err := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+			require.Error(t, err)
+			for _, expected := range tc.expected {
+				assert.Contains(t, err.Error(), expected)
+			}
+		})
+	}
+
+	t.Run("ValidationCitesRoleFile", func(t *testing.T) {
+		invalidRoles := writePlaybookFile(t, dir, "invalid.yaml", `
+roles:
+  broken:
+    tasks:
+      - name: ok
+        type: mock
+      - name: bad
+        register: bad_result
+        block:
+          - type: mock
+`)
+		playbookYAML := fmt.Sprintf("schemaVersion: \"v1.0.0\"\nname: roles_error_test\nimports: [%q]\ntasks:\n  - use: broken\n", invalidRoles)
+		_, err := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), fmt.Sprintf("('broken_bad') from %s:7 (role 'broken' used at %s:5)", invalidRoles, playbookPath))
+	})
+
+	t.Run("SchemaErrorCitesRoleFile", func(t *testing.T) {
+		invalidRoles := writePlaybookFile(t, dir, "schema_invalid.yaml", `
+roles:
+  broken:
+    tasks:
+      - name: bad
+        type: mock
+        ignore_errors: "yes"
+`)
+		playbookYAML := fmt.Sprintf("schemaVersion: \"v1.0.0\"\nname: roles_error_test\nimports: [%q]\ntasks:\n  - use: broken\n", invalidRoles)
+		_, err := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Field 'tasks.0.ignore_errors'")
+		assert.Contains(t, err.Error(), fmt.Sprintf("(from %s:5 (role 'broken' used at %s:5))", invalidRoles, playbookPath))
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		cyclic := writePlaybookFile(t, dir, "cyclic.yaml", `
+roles:
+  a:
+    tasks:
+      - use: b
+  b:
+    tasks:
+      - use: a
+`)
+		playbookYAML := fmt.Sprintf("schemaVersion: \"v1.0.0\"\nname: roles_cycle_test\nimports: [%q]\ntasks:\n  - use: a\n", cyclic)
+		_, err := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), filepath.Base(cyclic))
+		assert.Contains(t, err.Error(), "role cycle detected: a -> b -> a")
+	})
+}
@@ -0,0 +1,263 @@
+package engine_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gxo-labs/gxo/internal/engine"
+	intEvents "github.com/gxo-labs/gxo/internal/events"
+	"github.com/gxo-labs/gxo/internal/logger"
+	intTracing "github.com/gxo-labs/gxo/internal/tracing"
+	gxo "github.com/gxo-labs/gxo/pkg/gxo/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupConcurrentTestEngine creates an engine without a configured state
+// store, so that every run gets its own.
+func setupConcurrentTestEngine(t *testing.T, workerPoolSize int) *engine.Engine {
+	t.Helper()
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	noOpTracerProvider, err := intTracing.NewNoOpProvider()
+	require.NoError(t, err)
+
+	engineInstance, err := engine.NewEngine(logger.NewLogger("info", "text", os.Stderr),
+		gxo.WithEventBus(intEvents.NewNoOpEventBus()),
+		gxo.WithPluginRegistry(reg),
+		gxo.WithWorkerPoolSize(workerPoolSize),
+		gxo.WithTracerProvider(noOpTracerProvider),
+	)
+	require.NoError(t, err)
+	return engineInstance
+}
+
+func TestEngine_StartPlaybook_ConcurrentRunsAreIsolated(t *testing.T) {
+	engineInstance := setupConcurrentTestEngine(t, 4)
+
+	const runs = 5
+	handles := make([]gxo.RunHandle, runs)
+	for i := 0; i < runs; i++ {
+		playbookYAML := fmt.Sprintf(`
+schemaVersion: "v1.0.0"
+name: concurrent_%d
+vars:
+  run_number: "%d"
+tasks:
+  - name: produce
+    type: mock
+    params:
+      value: "{{ .run_number }}"
+      _mock_delay: "50ms"
+    register: produced
+  - name: consume
+    type: mock
+    params:
+      value: "{{ .produced.value }}"
+    depends_on: [produce]
+`, i, i)
+		handle, err := engineInstance.StartPlaybook(context.Background(), []byte(playbookYAML))
+		require.NoError(t, err)
+		handles[i] = handle
+	}
+
+	runIDs := make(map[string]bool)
+	for i, handle := range handles {
+		report, err := handle.Wait()
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("concurrent_%d", i), report.PlaybookName)
+		assert.Equal(t, "Completed", report.OverallStatus)
+		assert.Equal(t, 2, report.CompletedTasks)
+		assert.Equal(t, handle.RunID(), report.RunID)
+		runIDs[handle.RunID()] = true
+
+		status := handle.Status()
+		assert.Equal(t, gxo.RunStateCompleted, status.State)
+		assert.Equal(t, 2, status.FinishedTasks)
+		assert.Equal(t, "Completed", status.TaskStatuses["consume"])
+	}
+	assert.Len(t, runIDs, runs, "Every run must have its own ID")
+}
+
+func TestEngine_StartPlaybook_StatusAndCancel(t *testing.T) {
+	engineInstance := setupConcurrentTestEngine(t, 2)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: cancel_test
+tasks:
+  - name: quick
+    type: mock
+  - name: slow
+    type: mock
+    params:
+      _mock_delay: "30s"
+finally:
+  - name: cleanup
+    type: mock
+`
+	handle, err := engineInstance.StartPlaybook(context.Background(), []byte(playbookYAML))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return handle.Status().TaskStatuses["slow"] == "Running"
+	}, testTimeout, 10*time.Millisecond)
+	status := handle.Status()
+	assert.Equal(t, gxo.RunStateRunning, status.State)
+	assert.Equal(t, "cancel_test", status.PlaybookName)
+	assert.Equal(t, handle.RunID(), status.RunID)
+
+	start := time.Now()
+	handle.Cancel()
+	report, err := handle.Wait()
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 10*time.Second, "Cancel must interrupt the running task")
+	assert.Equal(t, "Failed", report.OverallStatus)
+	assert.Equal(t, "Failed", report.TaskResults["slow"].Status)
+	assert.Equal(t, "Completed", report.TaskResults["cleanup"].Status, "The finally section still runs after a cancel")
+	assert.Equal(t, gxo.RunStateFailed, handle.Status().State)
+}
+
+func TestEngine_StartPlaybook_SharesWorkerPool(t *testing.T) {
+	engineInstance := setupConcurrentTestEngine(t, 1)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: pool_test
+tasks:
+  - name: work
+    type: mock
+    params:
+      _mock_delay: "300ms"
+`
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.GreaterOrEqual(t, time.Since(start), 600*time.Millisecond, "A single worker slot must be shared by both runs")
+}
+
+func TestEngine_StartPlaybook_ConcurrentRunsShareConfiguredStateStore(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, stateStore := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: shared_store_test
+vars:
+  run: %q
+tasks:
+  - name: produce
+    type: mock
+    params:
+      run: "{{ .run }}"
+    register: produced
+  - name: consume
+    type: mock
+    params:
+      from: "{{ .produced }}"
+    register: consumed
+outputs:
+  run: "{{ .run }}"
+  produced: "{{ .produced.run }}"
+  consumed: "{{ .consumed.from.run }}"
+`
+	const runs = 8
+	handles := make([]gxo.RunHandle, runs)
+	for i := range handles {
+		handle, err := engineInstance.StartPlaybook(context.Background(), []byte(fmt.Sprintf(playbookYAML, fmt.Sprintf("run-%d", i))))
+		require.NoError(t, err)
+		handles[i] = handle
+	}
+	for i, handle := range handles {
+		report, err := handle.Wait()
+		require.NoError(t, err, "A run may start while others use the configured store")
+		assert.Equal(t, "Completed", report.OverallStatus)
+
+		want := fmt.Sprintf("run-%d", i)
+		assert.Equal(t, map[string]interface{}{
+			"run":      want,
+			"produced": want,
+			"consumed": want,
+		}, report.Outputs, "Every run reads its own vars and registered values")
+	}
+	_, found := stateStore.Get("produced")
+	assert.False(t, found, "No run may write outside its namespace")
+	assert.Empty(t, stateStore.GetAll(), "The namespace of every run is removed once it finished")
+}
+
+func TestEngine_RunPlaybook_ConcurrentRunsShareConfiguredStateStore(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, stateStore := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: concurrent_run_playbook_test
+vars:
+  run: %q
+tasks:
+  - name: produce
+    type: mock
+    params:
+      run: "{{ .run }}"
+      _mock_delay: "100ms"
+    register: produced
+  - name: consume
+    type: mock
+    params:
+      run: "{{ .run }}"
+      status: "{{ ._gxo.tasks.produce.status }}"
+    register: consumed
+outputs:
+  produced: "{{ .produced.run }}"
+  consumed: "{{ .consumed.run }}"
+`
+	runs := []string{"first", "second"}
+	reports := make([]*gxo.ExecutionReport, len(runs))
+	var wg sync.WaitGroup
+	for i, run := range runs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report, err := engineInstance.RunPlaybook(context.Background(), []byte(fmt.Sprintf(playbookYAML, run)))
+			assert.NoError(t, err)
+			reports[i] = report
+		}()
+	}
+	wg.Wait()
+
+	for i, run := range runs {
+		require.NotNil(t, reports[i])
+		assert.Equal(t, "Completed", reports[i].OverallStatus)
+		assert.Equal(t, map[string]interface{}{"produced": run, "consumed": run}, reports[i].Outputs, "Every run reads its own vars and registered values")
+	}
+
+	// The final state of each run is copied to the top level of the store as
+	// a whole, so every key comes from the run that finished last.
+	produced, found := stateStore.Get("produced")
+	require.True(t, found, "Registered values are copied to the top level of the store")
+	consumed, found := stateStore.Get("consumed")
+	require.True(t, found)
+	run, _ := stateStore.Get("run")
+	assert.Contains(t, runs, run)
+	assert.Equal(t, run, produced.(map[string]interface{})["run"])
+	assert.Equal(t, run, consumed.(map[string]interface{})["run"])
+	assert.Equal(t, "Completed", consumed.(map[string]interface{})["status"])
+	status, _ := stateStore.Get("_gxo.tasks.produce.status")
+	assert.Equal(t, "Completed", status)
+	_, found = stateStore.GetAll()["_gxo"].(map[string]interface{})["runs"]
+	assert.False(t, found, "The namespace of every run is removed once it finished")
+}
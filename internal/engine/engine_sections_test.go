@@ -0,0 +1,157 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_OnFailureAndFinally_RunAfterFailure(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, stateStore := setupTestEngine(t, reg)
+	require.NoError(t, engineInstance.SetRedactedKeywords([]string{"password"}))
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: sections_failure_test
+tasks:
+  - name: task_ok
+    type: mock
+  - name: task_fail
+    type: mock
+    params:
+      fail_message: "disk is full, password=hunter2"
+on_failure:
+  - name: notify
+    type: mock
+    params:
+      failed_task: "{{ ._gxo.run.failed_task }}"
+      error: "{{ ._gxo.run.error }}"
+    register: notify_out
+finally:
+  - name: cleanup
+    type: mock
+    params:
+      run_status: "{{ ._gxo.run.status }}"
+    register: cleanup_out
+`
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	report, execErr := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+
+	require.Error(t, execErr)
+	require.NotNil(t, report)
+	assert.Equal(t, "Failed", report.OverallStatus)
+	assert.Equal(t, 4, report.TotalTasks)
+	assert.Equal(t, "Failed", report.TaskResults["task_fail"].Status)
+	assert.Equal(t, "", report.TaskResults["task_fail"].Section)
+	assert.Equal(t, "Completed", report.TaskResults["notify"].Status)
+	assert.Equal(t, "on_failure", report.TaskResults["notify"].Section)
+	assert.Equal(t, "Completed", report.TaskResults["cleanup"].Status)
+	assert.Equal(t, "finally", report.TaskResults["cleanup"].Section)
+
+	notifyOut, found := stateStore.Get("notify_out")
+	require.True(t, found)
+	notifyMap := notifyOut.(map[string]interface{})
+	assert.Equal(t, "task_fail", notifyMap["failed_task"])
+	assert.Contains(t, notifyMap["error"], "disk is full")
+	assert.NotContains(t, notifyMap["error"], "hunter2", "The failed task's error must be redacted")
+
+	cleanupOut, found := stateStore.Get("cleanup_out")
+	require.True(t, found)
+	assert.Equal(t, "Failed", cleanupOut.(map[string]interface{})["run_status"])
+}
+
+func TestEngine_OnFailureAndFinally_SuccessfulRun(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, stateStore := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: sections_success_test
+tasks:
+  - name: task_ok
+    type: mock
+on_failure:
+  - name: notify
+    type: mock
+finally:
+  - name: cleanup_a
+    type: mock
+    register: cleanup_a_out
+  - name: cleanup_b
+    type: mock
+    params:
+      prev: "{{ ._gxo.tasks.cleanup_a.status }}"
+`
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	report, execErr := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+
+	require.NoError(t, execErr)
+	require.NotNil(t, report)
+	assert.Equal(t, "Completed", report.OverallStatus)
+	assert.Equal(t, 3, report.TotalTasks)
+	assert.NotContains(t, report.TaskResults, "notify", "on_failure tasks must not run when the run succeeds")
+	assert.Equal(t, "Completed", report.TaskResults["cleanup_b"].Status)
+
+	status, _ := stateStore.Get("_gxo.tasks.cleanup_b.status")
+	assert.Equal(t, "Completed", status)
+}
+
+func TestEngine_Finally_FailureFailsRun(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: finally_failure_test
+tasks:
+  - name: task_ok
+    type: mock
+finally:
+  - name: cleanup
+    type: mock
+    params:
+      fail_message: "cleanup failed"
+`
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	report, execErr := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+
+	require.Error(t, execErr)
+	assert.Contains(t, execErr.Error(), "'finally' section")
+	require.NotNil(t, report)
+	assert.Equal(t, "Failed", report.OverallStatus)
+	assert.Equal(t, "Completed", report.TaskResults["task_ok"].Status)
+	assert.Equal(t, "Failed", report.TaskResults["cleanup"].Status)
+}
+
+func TestEngine_Sections_RejectCrossSectionReferences(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: cross_section_test
+tasks:
+  - name: task_main
+    type: mock
+    params:
+      later: "{{ ._gxo.tasks.cleanup.status }}"
+finally:
+  - name: cleanup
+    type: mock
+    stream_inputs: ["task_main"]
+`
+	_, execErr := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.Error(t, execErr)
+	assert.Contains(t, execErr.Error(), "cannot reference the status of task 'cleanup' in the later 'finally' section")
+	assert.Contains(t, execErr.Error(), "'stream_inputs' target 'task_main' must be in the same section")
+}
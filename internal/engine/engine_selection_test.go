@@ -0,0 +1,149 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gxo-labs/gxo/internal/checkpoint"
+	gxo "github.com/gxo-labs/gxo/pkg/gxo/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const selectionPlaybook = `
+schemaVersion: v1.0.0
+name: selection_test
+tasks:
+  - name: build
+    type: mock
+    tags: [build]
+    register: build_out
+    params:
+      artifact: app.tar
+  - name: test
+    type: mock
+    tags: [test]
+    register: test_out
+    params:
+      build: "{{ .build_out }}"
+  - name: deploy
+    type: mock
+    tags: [deploy]
+    register: deploy_out
+    params:
+      build: "{{ .build_out }}"
+  - name: notify
+    type: mock
+    tags: [notify]
+    register: notify_out
+    params:
+      message: done
+`
+
+// runSelected runs selectionPlaybook with the given task selection.
+func runSelected(t *testing.T, engineInstance gxo.EngineV1, selection gxo.TaskSelection) (*gxo.ExecutionReport, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, gxo.TaskSelectionKey{}, selection)
+	return engineInstance.RunPlaybook(ctx, []byte(selectionPlaybook))
+}
+
+func TestEngine_Selection_TagsPullInDependencies(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, stateStore := setupTestEngine(t, reg)
+
+	report, err := runSelected(t, engineInstance, gxo.TaskSelection{Tags: []string{"deploy"}})
+	require.NoError(t, err)
+	assert.Equal(t, "Completed", report.OverallStatus)
+	assert.Equal(t, "Completed", report.TaskResults["build"].Status, "A dependency of a selected task must run")
+	assert.Equal(t, "Completed", report.TaskResults["deploy"].Status)
+	for _, taskID := range []string{"test", "notify"} {
+		assert.Equal(t, "Skipped", report.TaskResults[taskID].Status)
+		assert.Equal(t, "task skipped: not selected (tags: deploy)", report.TaskResults[taskID].Error)
+	}
+
+	_, found := stateStore.Get("deploy_out")
+	assert.True(t, found)
+	_, found = stateStore.Get("notify_out")
+	assert.False(t, found, "A pruned task must not run")
+}
+
+func TestEngine_Selection_SkipTags(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+
+	report, err := runSelected(t, engineInstance, gxo.TaskSelection{SkipTags: []string{"notify", "test"}})
+	require.NoError(t, err)
+	assert.Equal(t, "Completed", report.TaskResults["build"].Status)
+	assert.Equal(t, "Completed", report.TaskResults["deploy"].Status)
+	assert.Equal(t, "Skipped", report.TaskResults["test"].Status)
+	assert.Equal(t, "task skipped: not selected (skip-tags: notify,test)", report.TaskResults["notify"].Error)
+}
+
+func TestEngine_Selection_StartAtTask(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+
+	report, err := runSelected(t, engineInstance, gxo.TaskSelection{StartAtTask: "build"})
+	require.NoError(t, err)
+	for _, taskID := range []string{"build", "test", "deploy"} {
+		assert.Equal(t, "Completed", report.TaskResults[taskID].Status, "Task %s depends on the start task", taskID)
+	}
+	assert.Equal(t, "Skipped", report.TaskResults["notify"].Status)
+}
+
+func TestEngine_Selection_OnlyReusesRegisteredValues(t *testing.T) {
+	store := checkpoint.NewFileStore(t.TempDir())
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+
+	firstEngine, _ := setupTestEngine(t, reg)
+	require.NoError(t, firstEngine.SetCheckpointStore(store))
+	firstReport, err := runSelected(t, firstEngine, gxo.TaskSelection{})
+	require.NoError(t, err)
+
+	secondEngine, secondStore := setupTestEngine(t, reg)
+	require.NoError(t, secondEngine.SetCheckpointStore(store))
+	report, err := runSelected(t, secondEngine, gxo.TaskSelection{Only: []string{"test"}, ReuseRunID: firstReport.RunID})
+	require.NoError(t, err)
+	assert.Equal(t, "Completed", report.TaskResults["test"].Status)
+	assert.Equal(t, "Skipped", report.TaskResults["build"].Status)
+	assert.Equal(t, "task skipped: not selected (only: test); its registered value 'build_out' is reused", report.TaskResults["build"].Error)
+	assert.Equal(t, "Skipped", report.TaskResults["deploy"].Status)
+
+	testOut, found := secondStore.Get("test_out")
+	require.True(t, found)
+	assert.Equal(t, map[string]interface{}{"artifact": "app.tar"}, testOut.(map[string]interface{})["build"], "The reused value must reach the selected task")
+}
+
+func TestEngine_Selection_OnlyWithoutReuseRunsDependencies(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+
+	report, err := runSelected(t, engineInstance, gxo.TaskSelection{Only: []string{"test"}})
+	require.NoError(t, err)
+	assert.Equal(t, "Completed", report.TaskResults["build"].Status)
+	assert.Equal(t, "Completed", report.TaskResults["test"].Status)
+	assert.Equal(t, "Skipped", report.TaskResults["deploy"].Status)
+}
+
+func TestEngine_Selection_InvalidSelection(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, stateStore := setupTestEngine(t, reg)
+
+	_, err := runSelected(t, engineInstance, gxo.TaskSelection{Only: []string{"biuld"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "task selection 'only' names unknown task 'biuld'")
+
+	_, err = runSelected(t, engineInstance, gxo.TaskSelection{Tags: []string{"release"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "task selection (tags: release) matches no task")
+	_, found := stateStore.Get("build_out")
+	assert.False(t, found, "No task may run when the selection is invalid")
+}
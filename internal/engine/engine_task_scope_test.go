@@ -0,0 +1,177 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gxo-labs/gxo/internal/module"
+	"github.com/gxo-labs/gxo/pkg/gxo/v1/plugin"
+	gxov1state "github.com/gxo-labs/gxo/pkg/gxo/v1/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// envModule returns the environment it received through its context.
+type envModule struct{}
+
+func (m *envModule) Perform(
+	ctx context.Context,
+	params map[string]interface{},
+	stateReader gxov1state.StateReader,
+	inputs map[string]<-chan map[string]interface{},
+	outputChans []chan<- map[string]interface{},
+	errChan chan<- error,
+) (interface{}, error) {
+	environment := make(map[string]interface{})
+	for name, value := range module.Environment(ctx) {
+		environment[name] = value
+	}
+	return environment, nil
+}
+
+func TestEngine_TaskVars_AreScopedToTheTask(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, stateStore := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: task_vars_test
+vars:
+  name: "world"
+tasks:
+  - name: greet
+    type: mock
+    vars:
+      greeting: "hello {{ .name }}"
+      targets: ["a", "b"]
+    when: '{{ eq .greeting "hello world" }}'
+    loop: "{{ .targets }}"
+    register: greetings
+    params:
+      message: "{{ .greeting }} from {{ .item }}"
+  - block:
+      - name: shadow
+        type: mock
+        vars:
+          name: "member"
+        register: shadowed
+        params:
+          name: "{{ .name }}"
+          level: "{{ .level }}"
+    vars:
+      name: "block"
+      level: "inner"
+`
+	report, err := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.NoError(t, err)
+	assert.Equal(t, "Completed", report.TaskResults["greet"].Status)
+
+	greetings, found := stateStore.Get("greetings")
+	require.True(t, found)
+	results := greetings.([]interface{})
+	require.Len(t, results, 2)
+	assert.Equal(t, "hello world from b", results[1].(map[string]interface{})["summary"].(map[string]interface{})["message"])
+
+	shadowed, found := stateStore.Get("shadowed")
+	require.True(t, found)
+	assert.Equal(t, "member", shadowed.(map[string]interface{})["name"], "A task's vars override its block's")
+	assert.Equal(t, "inner", shadowed.(map[string]interface{})["level"])
+
+	_, found = stateStore.Get("greeting")
+	assert.False(t, found, "Task vars are not written to the state")
+	name, _ := stateStore.Get("name")
+	assert.Equal(t, "world", name)
+}
+
+func TestEngine_TaskVars_RenderedPerIteration(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, stateStore := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: task_vars_loop_test
+vars:
+  domain: "example.com"
+tasks:
+  - name: hosts
+    type: mock
+    vars:
+      services:
+        - name: api
+        - name: web
+      host: "{{ .service.name }}.{{ .domain }}"
+      labels:
+        app: "{{ .service.name }}"
+    loop: "{{ .services }}"
+    loop_control:
+      loop_var: service
+    register: hosts
+    params:
+      host: "{{ .host }}"
+      app: "{{ .labels.app }}"
+`
+	report, err := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.NoError(t, err)
+	assert.Equal(t, "Completed", report.TaskResults["hosts"].Status)
+
+	hosts, found := stateStore.Get("hosts")
+	require.True(t, found)
+	results := hosts.([]interface{})
+	require.Len(t, results, 2)
+	for i, service := range []string{"api", "web"} {
+		summary := results[i].(map[string]interface{})["summary"].(map[string]interface{})
+		assert.Equal(t, service+".example.com", summary["host"], "A var derived from the loop variable is rendered for each iteration")
+		assert.Equal(t, service, summary["app"])
+	}
+}
+
+func TestEngine_Environment_MergesPlaybookBlockAndTask(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, reg.Register("env", func() plugin.Module { return &envModule{} }))
+	engineInstance, stateStore := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: environment_test
+vars:
+  token: "s3cr3t"
+environment:
+  STAGE: prod
+  REGION: eu
+tasks:
+  - name: plain
+    type: env
+    register: plain_env
+  - block:
+      - name: deploy
+        type: env
+        loop: ["api", "web"]
+        environment:
+          SERVICE: "{{ .item }}"
+          TOKEN: "{{ .token }}"
+          REGION: ap
+        register: deploy_env
+      - name: inherit
+        type: env
+        register: inherit_env
+    environment:
+      REGION: us
+      PORT: 8080
+`
+	_, err := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.NoError(t, err)
+
+	plainEnv, _ := stateStore.Get("plain_env")
+	assert.Equal(t, map[string]interface{}{"STAGE": "prod", "REGION": "eu"}, plainEnv)
+
+	inheritEnv, _ := stateStore.Get("inherit_env")
+	assert.Equal(t, map[string]interface{}{"STAGE": "prod", "REGION": "us", "PORT": "8080"}, inheritEnv, "A block's environment overrides the playbook's")
+
+	deployEnv, _ := stateStore.Get("deploy_env")
+	results := deployEnv.([]interface{})
+	require.Len(t, results, 2)
+	assert.Equal(t, map[string]interface{}{"STAGE": "prod", "REGION": "ap", "PORT": "8080", "SERVICE": "web", "TOKEN": "s3cr3t"},
+		results[1].(map[string]interface{})["summary"], "A task's environment overrides its block's and is rendered per iteration")
+}
@@ -180,8 +180,8 @@ tasks:
 
 	assert.Equal(t, 3, report.TotalTasks)
 	assert.Equal(t, 1, report.CompletedTasks)
-	assert.Equal(t, 2, report.FailedTasks)
-	assert.Equal(t, 0, report.SkippedTasks)
+	assert.Equal(t, 1, report.FailedTasks)
+	assert.Equal(t, 1, report.SkippedTasks)
 
 	statusA, foundA := stateStore.Get("_gxo.tasks.task_a.status")
 	statusFail, foundFail := stateStore.Get("_gxo.tasks.task_fail.status")
@@ -192,7 +192,7 @@ tasks:
 	assert.True(t, foundFail)
 	assert.Equal(t, "Failed", statusFail)
 	assert.True(t, foundC)
-	assert.Equal(t, "Pending", statusC, "Task C should remain Pending as its dependency failed")
+	assert.Equal(t, "Skipped", statusC, "Task C should be skipped as its dependency failed")
 }
 
 func TestEngine_RunPlaybook_IgnoredFailureAllowsContinuation(t *testing.T) {
@@ -234,8 +234,8 @@ tasks:
 
 	assert.Equal(t, 4, report.TotalTasks)
 	assert.Equal(t, 2, report.CompletedTasks)
-	assert.Equal(t, 2, report.FailedTasks)
-	assert.Equal(t, 0, report.SkippedTasks)
+	assert.Equal(t, 1, report.FailedTasks)
+	assert.Equal(t, 1, report.SkippedTasks)
 
 	statusA, _ := stateStore.Get("_gxo.tasks.task_a.status")
 	statusFail, _ := stateStore.Get("_gxo.tasks.task_fail_ignored.status")
@@ -244,7 +244,7 @@ tasks:
 
 	assert.Equal(t, "Completed", statusA)
 	assert.Equal(t, "Failed", statusFail, "Ignored task should still have Failed status")
-	assert.Equal(t, "Pending", statusC, "Task C should remain Pending")
+	assert.Equal(t, "Skipped", statusC, "Task C should be Skipped by its default trigger rule")
 	assert.Equal(t, "Completed", statusD)
 }
 
@@ -378,5 +378,5 @@ tasks:
 	statusNever, _ := stateStore.Get("_gxo.tasks.task_never_runs.status")
 
 	assert.Equal(t, "Failed", statusFail)
-	assert.Equal(t, "Pending", statusNever, "Dependent task should remain Pending")
+	assert.Equal(t, "Skipped", statusNever, "Dependent task should be Skipped by its default trigger rule")
 }
\ No newline at end of file
@@ -0,0 +1,184 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_TriggerRules_AfterIgnoredFailure(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: trigger_rules_failure_test
+tasks:
+  - name: task_ok
+    type: mock
+    register: ok_out
+  - name: task_fail
+    type: mock
+    params:
+      fail_message: "boom"
+    ignore_errors: true
+  - name: default_rule
+    type: mock
+    params:
+      a: "{{ .ok_out }}"
+      b: "{{ ._gxo.tasks.task_fail.status }}"
+  - name: alert
+    type: mock
+    trigger_rule: one_failed
+    params:
+      b: "{{ ._gxo.tasks.task_fail.status }}"
+  - name: cleanup
+    type: mock
+    trigger_rule: all_done
+    params:
+      a: "{{ .ok_out }}"
+      b: "{{ ._gxo.tasks.task_fail.status }}"
+  - name: any_success
+    type: mock
+    trigger_rule: one_success
+    params:
+      a: "{{ .ok_out }}"
+      b: "{{ ._gxo.tasks.task_fail.status }}"
+  - name: all_success
+    type: mock
+    trigger_rule: all_success
+    params:
+      a: "{{ .ok_out }}"
+      b: "{{ ._gxo.tasks.task_fail.status }}"
+  - name: after_default
+    type: mock
+    params:
+      prev: "{{ ._gxo.tasks.default_rule.status }}"
+`
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	report, execErr := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+
+	require.Error(t, execErr, "The ignored failure still fails the run")
+	require.NotNil(t, report)
+	assert.Equal(t, "Skipped", report.TaskResults["default_rule"].Status)
+	assert.Contains(t, report.TaskResults["default_rule"].Error, "trigger_rule 'none_failed' not satisfied")
+	assert.Equal(t, "Completed", report.TaskResults["alert"].Status)
+	assert.Equal(t, "Completed", report.TaskResults["cleanup"].Status)
+	assert.Equal(t, "Completed", report.TaskResults["any_success"].Status)
+	assert.Equal(t, "Skipped", report.TaskResults["all_success"].Status)
+	assert.Equal(t, "Skipped", report.TaskResults["after_default"].Status, "A skip caused by a failure must propagate")
+	assert.Equal(t, 0, report.TotalTasks-report.CompletedTasks-report.FailedTasks-report.SkippedTasks, "No task may be left Pending")
+}
+
+func TestEngine_TriggerRules_AfterSkippedDependency(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: trigger_rules_skip_test
+tasks:
+  - name: task_skipped
+    type: mock
+    when: "false"
+  - name: default_rule
+    type: mock
+    params:
+      b: "{{ ._gxo.tasks.task_skipped.status }}"
+  - name: all_success
+    type: mock
+    trigger_rule: all_success
+    params:
+      b: "{{ ._gxo.tasks.task_skipped.status }}"
+  - name: alert
+    type: mock
+    trigger_rule: one_failed
+    params:
+      b: "{{ ._gxo.tasks.task_skipped.status }}"
+`
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	report, execErr := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+
+	require.NoError(t, execErr)
+	require.NotNil(t, report)
+	assert.Equal(t, "Completed", report.OverallStatus)
+	assert.Equal(t, "Completed", report.TaskResults["default_rule"].Status)
+	assert.Equal(t, "Skipped", report.TaskResults["all_success"].Status)
+	assert.Equal(t, "Skipped", report.TaskResults["alert"].Status)
+}
+
+func TestEngine_TriggerRules_InvalidRule(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	require.NoError(t, RegisterTestMockModule(reg))
+	engineInstance, _ := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: trigger_rules_invalid_test
+tasks:
+  - name: task_a
+    type: mock
+    trigger_rule: sometimes
+`
+	_, execErr := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.Error(t, execErr)
+	assert.Contains(t, execErr.Error(), "trigger_rule")
+}
+
+func TestEngine_RunPlaybook_FailureRunsFailureHandlers(t *testing.T) {
+	reg := NewInMemoryRegistry()
+	err := RegisterTestMockModule(reg)
+	require.NoError(t, err)
+	engineInstance, stateStore := setupTestEngine(t, reg)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: failure_handler_test
+tasks:
+  - name: a
+    type: mock
+    params:
+      fail_message: "Something went wrong"
+
+  - name: alert
+    type: mock
+    depends_on: ["a"]
+    trigger_rule: one_failed
+    params:
+      message: "a failed"
+
+  - name: cleanup
+    type: mock
+    depends_on: ["a"]
+    trigger_rule: all_done
+
+  - name: c
+    type: mock
+    depends_on: ["a"]
+`
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	report, execErr := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+
+	require.Error(t, execErr, "The unignored failure still fails the playbook")
+	assert.Contains(t, execErr.Error(), "Something went wrong")
+	require.NotNil(t, report)
+	assert.Equal(t, "Failed", report.OverallStatus)
+	assert.Equal(t, 2, report.CompletedTasks)
+	assert.Equal(t, 1, report.FailedTasks)
+	assert.Equal(t, 1, report.SkippedTasks)
+
+	for task, expected := range map[string]string{"a": "Failed", "alert": "Completed", "cleanup": "Completed", "c": "Skipped"} {
+		status, found := stateStore.Get("_gxo.tasks." + task + ".status")
+		assert.True(t, found, "Status of task '%s' should be recorded", task)
+		assert.Equal(t, expected, status, "Unexpected status for task '%s'", task)
+	}
+	require.Contains(t, report.TaskResults, "c")
+	assert.NotEmpty(t, report.TaskResults["c"].Error, "The skipped task should carry the reason it was skipped")
+}
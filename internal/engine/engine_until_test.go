@@ -0,0 +1,184 @@
+package engine_test
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/gxo-labs/gxo/internal/config"
+	"github.com/gxo-labs/gxo/internal/engine"
+	"github.com/gxo-labs/gxo/internal/logger"
+	"github.com/gxo-labs/gxo/internal/state"
+	intTracing "github.com/gxo-labs/gxo/internal/tracing"
+	gxo "github.com/gxo-labs/gxo/pkg/gxo/v1"
+	"github.com/gxo-labs/gxo/pkg/gxo/v1/events"
+	"github.com/gxo-labs/gxo/pkg/gxo/v1/plugin"
+	gxov1state "github.com/gxo-labs/gxo/pkg/gxo/v1/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pollModule reports a job as "pending" until it has been called
+// 'ready_after' times, then as "ready".
+type pollModule struct {
+	mu    *sync.Mutex
+	calls *int
+}
+
+func (m *pollModule) Perform(
+	ctx context.Context,
+	params map[string]interface{},
+	stateReader gxov1state.StateReader,
+	inputs map[string]<-chan map[string]interface{},
+	outputChans []chan<- map[string]interface{},
+	errChan chan<- error,
+) (interface{}, error) {
+	m.mu.Lock()
+	*m.calls++
+	calls := *m.calls
+	m.mu.Unlock()
+	status := "pending"
+	if readyAfter, _ := params["ready_after"].(int); calls >= readyAfter {
+		status = "ready"
+	}
+	return map[string]interface{}{"status": status, "calls": calls}, nil
+}
+
+// recordingBus keeps the events of a given type.
+type recordingBus struct {
+	mu        sync.Mutex
+	eventType events.EventType
+	events    []events.Event
+}
+
+func (b *recordingBus) Emit(event events.Event) {
+	if event.Type != b.eventType {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = append(b.events, event)
+}
+
+func setupPollTestEngine(t *testing.T) (*engine.Engine, gxov1state.Store, *recordingBus, *int) {
+	t.Helper()
+	var mu sync.Mutex
+	calls := 0
+	reg := NewInMemoryRegistry()
+	require.NoError(t, reg.Register("poll", func() plugin.Module { return &pollModule{mu: &mu, calls: &calls} }))
+	noOpTracerProvider, err := intTracing.NewNoOpProvider()
+	require.NoError(t, err)
+
+	stateStore := state.NewMemoryStateStore()
+	bus := &recordingBus{eventType: events.TaskPolled}
+	engineInstance, err := engine.NewEngine(logger.NewLogger("debug", "text", os.Stderr),
+		gxo.WithStateStore(stateStore),
+		gxo.WithEventBus(bus),
+		gxo.WithPluginRegistry(reg),
+		gxo.WithWorkerPoolSize(2),
+		gxo.WithTracerProvider(noOpTracerProvider),
+	)
+	require.NoError(t, err)
+	return engineInstance, stateStore, bus, &calls
+}
+
+func TestEngine_Until_PollsUntilConditionHolds(t *testing.T) {
+	engineInstance, stateStore, bus, calls := setupPollTestEngine(t)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: until_test
+tasks:
+  - name: wait_for_job
+    type: poll
+    params:
+      ready_after: 3
+    until: '{{ eq .result.status "ready" }}'
+    until_control:
+      delay: 1ms
+      max_attempts: 5
+    register: job
+`
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	_, err := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+	require.NoError(t, err)
+	assert.Equal(t, 3, *calls)
+
+	job, found := stateStore.Get("job")
+	require.True(t, found)
+	assert.Equal(t, "ready", job.(map[string]interface{})["status"], "The attempt that met the condition is registered")
+
+	require.Len(t, bus.events, 3, "Each poll emits an event")
+	for i, event := range bus.events {
+		assert.Equal(t, "wait_for_job", event.TaskID)
+		assert.Equal(t, i+1, event.Payload["attempt"])
+		assert.Equal(t, 5, event.Payload["max_attempts"])
+		assert.Equal(t, i == 2, event.Payload["condition_met"])
+	}
+}
+
+func TestEngine_Until_FailsWhenConditionNeverHolds(t *testing.T) {
+	engineInstance, _, bus, calls := setupPollTestEngine(t)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: until_exhausted_test
+tasks:
+  - name: wait_for_job
+    type: poll
+    params:
+      ready_after: 10
+    until: '{{ eq .result.status "ready" }}'
+    until_control:
+      delay: 1ms
+      max_attempts: 2
+`
+	report, err := engineInstance.RunPlaybook(context.Background(), []byte(playbookYAML))
+	require.Error(t, err)
+	assert.Equal(t, 2, *calls)
+	assert.Len(t, bus.events, 2)
+	result := report.TaskResults["wait_for_job"]
+	assert.Equal(t, "Failed", result.Status)
+	assert.Contains(t, result.Error, "'until' condition never became true after 2 attempts (last result: [false])")
+}
+
+func TestEngine_Until_FailsAtDeadline(t *testing.T) {
+	engineInstance, _, _, _ := setupPollTestEngine(t)
+
+	playbookYAML := `
+schemaVersion: "v1.0.0"
+name: until_deadline_test
+tasks:
+  - name: wait_for_job
+    type: poll
+    params:
+      ready_after: 1000
+    until: '{{ eq .result.status "ready" }}'
+    until_control:
+      delay: 20ms
+      max_attempts: 1000
+      timeout: 100ms
+`
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	report, err := engineInstance.RunPlaybook(ctx, []byte(playbookYAML))
+	require.Error(t, err)
+	assert.Contains(t, report.TaskResults["wait_for_job"].Error, "'until' condition did not become true within 100ms")
+}
+
+func TestEngine_Until_Validation(t *testing.T) {
+	playbookYAML := `schemaVersion: "v1.0.0"
+name: until_validation_test
+tasks:
+  - name: no_condition
+    type: mock
+    until_control:
+      max_attempts: 2
+`
+	_, err := config.LoadPlaybook([]byte(playbookYAML), "until.yaml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "until.yaml:6:5")
+	assert.Contains(t, err.Error(), "'until_control' requires 'until'")
+}
@@ -0,0 +1,320 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gxo-labs/gxo/internal/config"
+	intState "github.com/gxo-labs/gxo/internal/state"
+	"github.com/gxo-labs/gxo/internal/template"
+	gxo "github.com/gxo-labs/gxo/pkg/gxo/v1"
+	gxov1state "github.com/gxo-labs/gxo/pkg/gxo/v1/state"
+)
+
+// StateKeyGxoIncludesPrefix is the state namespace of included playbooks. The
+// playbook included by task 'x' keeps its state under _gxo.includes.x, or under
+// _gxo.includes.x.<iteration> for each iteration of a looping task. Unnamed
+// tasks are identified by their internal ID.
+const StateKeyGxoIncludesPrefix = template.GxoStateKeyPrefix + ".includes"
+
+// includePlaybookModule runs the include_playbook built-in task type. It goes
+// through the same retry, hook and tracing logic as a plugin module.
+type includePlaybookModule struct {
+	runner       *TaskRunner
+	task         *config.Task
+	iteration    int
+	renderer     template.Renderer
+	templateData map[string]interface{}
+}
+
+func (m *includePlaybookModule) Perform(ctx context.Context, params map[string]interface{}, _ gxov1state.StateReader, _ map[string]<-chan map[string]interface{}, _ []chan<- map[string]interface{}, _ chan<- error) (interface{}, error) {
+	path, ok := params[config.IncludeParamPath].(string)
+	if !ok || path == "" {
+		return nil, fmt.Errorf("'params.%s' must render to a non-empty string", config.IncludeParamPath)
+	}
+	vars := make(map[string]interface{})
+	if rawVars, exists := params[config.IncludeParamVars].(map[string]interface{}); exists {
+		for key, value := range rawVars {
+			rendered, err := renderNested(value, m.renderer, m.templateData)
+			if err != nil {
+				return nil, fmt.Errorf("failed to render include var '%s': %w", key, err)
+			}
+			vars[key] = rendered
+		}
+	}
+	return m.runner.includePlaybook(ctx, m.task, m.iteration, path, vars)
+}
+
+// renderNested resolves the templates in a value, descending into maps and
+// slices.
+func renderNested(value interface{}, renderer template.Renderer, data map[string]interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return renderer.Resolve(v, data)
+	case map[string]interface{}:
+		rendered := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			renderedItem, err := renderNested(item, renderer, data)
+			if err != nil {
+				return nil, err
+			}
+			rendered[key] = renderedItem
+		}
+		return rendered, nil
+	case []interface{}:
+		rendered := make([]interface{}, len(v))
+		for i, item := range v {
+			renderedItem, err := renderNested(item, renderer, data)
+			if err != nil {
+				return nil, err
+			}
+			rendered[i] = renderedItem
+		}
+		return rendered, nil
+	default:
+		return value, nil
+	}
+}
+
+// resolvePlaybookPath resolves a relative path against the directory of the
+// run's playbook.
+func (r *playbookRun) resolvePlaybookPath(path string) string {
+	if !filepath.IsAbs(path) && r.playbook != nil {
+		return filepath.Join(filepath.Dir(r.playbook.FilePath), path)
+	}
+	return path
+}
+
+// includePlaybook loads the playbook at path and runs it as a nested run whose
+// state lives in its own namespace of this run's state store. iteration is the
+// loop iteration of the task, or -1. It returns the included playbook's
+// rendered outputs. A relative path is resolved against the directory of the
+// including playbook.
+func (r *playbookRun) includePlaybook(ctx context.Context, task *config.Task, iteration int, path string, vars map[string]interface{}) (interface{}, error) {
+	path = r.resolvePlaybookPath(path)
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve included playbook path '%s': %w", path, err)
+	}
+	for i, included := range r.includeChain {
+		if included == absPath {
+			chain := append(append([]string{}, r.includeChain[i:]...), absPath)
+			return nil, fmt.Errorf("include cycle detected: %s", strings.Join(chain, " -> "))
+		}
+	}
+	playbook, err := config.LoadPlaybookFromFile(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load included playbook: %w", err)
+	}
+
+	includeID := task.Name
+	if includeID == "" {
+		includeID = task.InternalID
+	}
+	if iteration >= 0 {
+		includeID = fmt.Sprintf("%s.%d", includeID, iteration)
+	}
+	store := newNamespacedStore(r.stateManager, StateKeyGxoIncludesPrefix+"."+includeID)
+	childCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	child := r.newPlaybookRun(r.runID+"/"+includeID, store, cancel)
+	child.parent = r
+	child.preloaded = playbook
+	child.includeVars = vars
+	child.includeChain = append(append([]string{}, r.includeChain...), absPath)
+
+	r.log.Infof("Task '%s' is running included playbook '%s' (%s).", task.InternalID, playbook.Name, absPath)
+	report, runErr := child.executeRun(childCtx, nil)
+	r.recordIncludedReport(task.InternalID, iteration, report)
+	if runErr != nil {
+		return nil, fmt.Errorf("included playbook '%s' failed: %w", playbook.Name, runErr)
+	}
+	return child.outputs, nil
+}
+
+// recordIncludedReport keeps the report of a playbook included by a task. A
+// retried include replaces the report of its previous attempt.
+func (r *playbookRun) recordIncludedReport(taskID string, iteration int, report *gxo.ExecutionReport) {
+	if report == nil {
+		return
+	}
+	r.includesMu.Lock()
+	defer r.includesMu.Unlock()
+	if r.includedReports[taskID] == nil {
+		r.includedReports[taskID] = make(map[int]*gxo.ExecutionReport)
+	}
+	r.includedReports[taskID][iteration] = report
+}
+
+// nestedReports returns the reports of the playbooks included by a task, in
+// the order of its loop iterations.
+func (r *playbookRun) nestedReports(taskID string) []*gxo.ExecutionReport {
+	r.includesMu.Lock()
+	defer r.includesMu.Unlock()
+	byIteration := r.includedReports[taskID]
+	if len(byIteration) == 0 {
+		return nil
+	}
+	iterations := make([]int, 0, len(byIteration))
+	for iteration := range byIteration {
+		iterations = append(iterations, iteration)
+	}
+	sort.Ints(iterations)
+	reports := make([]*gxo.ExecutionReport, len(iterations))
+	for i, iteration := range iterations {
+		reports[i] = byIteration[iteration]
+	}
+	return reports
+}
+
+// namespacedStore is a view of a state store in which every key lives under a
+// prefix. It gives an included playbook its own namespace in its parent's
+// store, and a run its own namespace in the engine's configured store.
+type namespacedStore struct {
+	store  gxov1state.Store
+	prefix string
+
+	// keys holds the keys set through the view, so that clear can remove them
+	// from any underlying store.
+	keys   map[string]struct{}
+	keysMu sync.Mutex
+}
+
+// newNamespacedStore returns the namespace of store under prefix.
+func newNamespacedStore(store gxov1state.Store, prefix string) *namespacedStore {
+	return &namespacedStore{store: store, prefix: prefix, keys: make(map[string]struct{})}
+}
+
+func (s *namespacedStore) Get(key string) (interface{}, bool) {
+	return s.store.Get(s.prefix + "." + key)
+}
+
+// GetAll returns the nested map found at the prefix in the underlying store.
+func (s *namespacedStore) GetAll() map[string]interface{} {
+	return getAllWithPrefix(s.store, s.prefix)
+}
+
+// GetAllWithPrefix returns the nested map found at prefix in the namespace.
+func (s *namespacedStore) GetAllWithPrefix(prefix string) map[string]interface{} {
+	return getAllWithPrefix(s.store, s.prefix+"."+prefix)
+}
+
+// getAllWithPrefix returns the nested map found at prefix in store. Only the
+// keys under prefix are copied when the store is a PrefixReader.
+func getAllWithPrefix(store gxov1state.StateReader, prefix string) map[string]interface{} {
+	if reader, ok := store.(gxov1state.PrefixReader); ok {
+		return reader.GetAllWithPrefix(prefix)
+	}
+	current := store.GetAll()
+	for _, part := range strings.Split(prefix, ".") {
+		next, ok := current[part].(map[string]interface{})
+		if !ok {
+			return make(map[string]interface{})
+		}
+		current = next
+	}
+	return current
+}
+
+func (s *namespacedStore) Set(key string, value interface{}) error {
+	if err := s.store.Set(s.prefix+"."+key, value); err != nil {
+		return err
+	}
+	s.keysMu.Lock()
+	s.keys[key] = struct{}{}
+	s.keysMu.Unlock()
+	return nil
+}
+
+func (s *namespacedStore) Delete(key string) error {
+	s.keysMu.Lock()
+	delete(s.keys, key)
+	s.keysMu.Unlock()
+	return s.store.Delete(s.prefix + "." + key)
+}
+
+// Load sets every key of data in the namespace. Unlike a full store, it does
+// not remove keys that are not in data.
+func (s *namespacedStore) Load(data map[string]interface{}) error {
+	for key, value := range data {
+		if err := s.Set(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publish copies every key set through the view to the top level of the
+// underlying store.
+func (s *namespacedStore) publish() error {
+	s.keysMu.Lock()
+	defer s.keysMu.Unlock()
+	for key := range s.keys {
+		value, exists := s.store.Get(s.prefix + "." + key)
+		if !exists {
+			continue
+		}
+		if err := s.store.Set(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clear removes every key set through the view from the underlying store.
+func (s *namespacedStore) clear() error {
+	s.keysMu.Lock()
+	defer s.keysMu.Unlock()
+	for key := range s.keys {
+		if err := s.store.Delete(s.prefix + "." + key); err != nil && !errors.Is(err, gxov1state.ErrKeyNotFound) && !errors.Is(err, intState.ErrKeyNotFound) {
+			return err
+		}
+		delete(s.keys, key)
+	}
+	return nil
+}
+
+// Close is a no-op: the underlying store belongs to the parent run or to the
+// engine.
+func (s *namespacedStore) Close() error {
+	return nil
+}
+
+var _ gxov1state.Store = (*namespacedStore)(nil)
+var _ gxov1state.PrefixReader = (*namespacedStore)(nil)
+
+// resolveInputs returns the values of the playbook's declared inputs. A run
+// started by an include_playbook task is given them by the include's vars,
+// other runs by the values in the context under gxo.InputValuesKey.
+func (r *playbookRun) resolveInputs(ctx context.Context, playbook *config.Playbook) (map[string]interface{}, error) {
+	supplied, _ := ctx.Value(gxo.InputValuesKey{}).(map[string]interface{})
+	if r.parent != nil {
+		supplied = make(map[string]interface{})
+		for name := range playbook.Inputs {
+			if value, exists := r.includeVars[name]; exists {
+				supplied[name] = value
+			}
+		}
+	}
+	if len(playbook.Inputs) == 0 && len(supplied) == 0 {
+		return nil, nil
+	}
+	return playbook.ResolveInputs(supplied)
+}
+
+// mergeVars returns a copy of vars with the overrides added.
+func mergeVars(vars, overrides map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(vars)+len(overrides))
+	for key, value := range vars {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
@@ -0,0 +1,283 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gxo-labs/gxo/internal/config"
+	intTemplate "github.com/gxo-labs/gxo/internal/template"
+	"github.com/gxo-labs/gxo/internal/util"
+	gxo "github.com/gxo-labs/gxo/pkg/gxo/v1"
+	gxoerrors "github.com/gxo-labs/gxo/pkg/gxo/v1/errors"
+)
+
+// recordIteration keeps the outcome of an iteration of a looped task.
+func (r *playbookRun) recordIteration(taskID string, result gxo.IterationResult) {
+	r.iterationsMu.Lock()
+	defer r.iterationsMu.Unlock()
+	if r.iterations[taskID] == nil {
+		r.iterations[taskID] = make(map[int]gxo.IterationResult)
+	}
+	r.iterations[taskID][result.Index] = result
+}
+
+// iterationResults returns the outcomes of a looped task's iterations, in the
+// order of its loop items.
+func (r *playbookRun) iterationResults(taskID string) []gxo.IterationResult {
+	r.iterationsMu.Lock()
+	defer r.iterationsMu.Unlock()
+	byIndex := r.iterations[taskID]
+	if len(byIndex) == 0 {
+		return nil
+	}
+	results := make([]gxo.IterationResult, 0, len(byIndex))
+	for _, result := range byIndex {
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Index < results[j].Index })
+	return results
+}
+
+// recordChanged marks a task as having changed something.
+func (r *playbookRun) recordChanged(taskID string) {
+	r.changedMu.Lock()
+	defer r.changedMu.Unlock()
+	r.changedTasks[taskID] = true
+}
+
+// isChanged reports whether a task changed something.
+func (r *playbookRun) isChanged(taskID string) bool {
+	r.changedMu.Lock()
+	defer r.changedMu.Unlock()
+	return r.changedTasks[taskID]
+}
+
+// countIterations tallies the statuses of a task's iterations. It returns nil
+// for a task without iterations.
+func countIterations(iterations []gxo.IterationResult) *gxo.IterationCounts {
+	if len(iterations) == 0 {
+		return nil
+	}
+	counts := &gxo.IterationCounts{Total: len(iterations)}
+	for _, iteration := range iterations {
+		switch iteration.Status {
+		case string(StatusCompleted):
+			counts.Completed++
+		case string(StatusFailed):
+			counts.Failed++
+		case string(StatusSkipped):
+			counts.Skipped++
+		}
+	}
+	return counts
+}
+
+// recordIterationResult reports the outcome of a loop iteration that started
+// at start, with its error redacted. A zero start marks an iteration that
+// never started.
+func (r *TaskRunner) recordIterationResult(taskID string, index int, name string, start time.Time, iterErr error) {
+	if r.recordIteration == nil {
+		return
+	}
+	status, message := r.iterationOutcome(iterErr)
+	result := gxo.IterationResult{Index: index, Name: name, Status: status, Error: message}
+	if !start.IsZero() {
+		result.StartTime, result.EndTime = start, time.Now()
+		result.Duration = result.EndTime.Sub(start)
+	}
+	r.recordIteration(taskID, result)
+}
+
+// loopResult is the entry a looped task registers for one of its iterations.
+func (r *TaskRunner) loopResult(index int, item, summary interface{}, iterErr error) map[string]interface{} {
+	status, message := r.iterationOutcome(iterErr)
+	return map[string]interface{}{
+		"item":    item,
+		"index":   index,
+		"status":  status,
+		"summary": summary,
+		"error":   message,
+	}
+}
+
+// collectLoopResults returns the entries of a looped task in the order of its
+// items. The iterations that never started, because ctxErr ended the loop,
+// are reported as skipped.
+func (r *TaskRunner) collectLoopResults(task *config.Task, items []interface{}, results []map[string]interface{}, ctxErr error) []interface{} {
+	collected := make([]interface{}, len(items))
+	for index, result := range results {
+		if result == nil {
+			reason := "iteration did not start"
+			if ctxErr != nil {
+				reason = fmt.Sprintf("%s: %v", reason, ctxErr)
+			}
+			notStarted := gxoerrors.NewSkippedError(reason)
+			r.recordIterationResult(task.InternalID, index, loopIterationName(task, items[index]), time.Time{}, notStarted)
+			result = r.loopResult(index, items[index], nil, notStarted)
+		}
+		collected[index] = result
+	}
+	return collected
+}
+
+// iterationOutcome returns the status of an iteration that ended with
+// iterErr, and the error redacted.
+func (r *TaskRunner) iterationOutcome(iterErr error) (status, message string) {
+	switch {
+	case iterErr == nil:
+		return string(StatusCompleted), ""
+	case gxoerrors.IsSkipped(iterErr):
+		status = string(StatusSkipped)
+	default:
+		status = string(StatusFailed)
+	}
+	return status, intTemplate.RedactSecretsInError(iterErr, r.redactedKeywords).Error()
+}
+
+// expandLoopMatrix returns the combinations of a loop matrix, each a map from
+// axis name to value. The first axis varies slowest; exclude entries are
+// applied before include entries.
+func expandLoopMatrix(matrix *config.LoopMatrix, data map[string]interface{}, renderer intTemplate.Renderer) ([]interface{}, error) {
+	var combinations []map[string]interface{}
+	if len(matrix.Axes) > 0 {
+		combinations = []map[string]interface{}{{}}
+	}
+	for _, axis := range matrix.Axes {
+		values, err := resolveMatrixAxis(axis, data, renderer)
+		if err != nil {
+			return nil, err
+		}
+		product := make([]map[string]interface{}, 0, len(combinations)*len(values))
+		for _, combination := range combinations {
+			for _, value := range values {
+				next := make(map[string]interface{}, len(combination)+1)
+				for k, v := range combination {
+					next[k] = v
+				}
+				next[axis.Name] = value
+				product = append(product, next)
+			}
+		}
+		combinations = product
+	}
+
+	kept := make([]map[string]interface{}, 0, len(combinations))
+	for _, combination := range combinations {
+		excluded := false
+		for _, exclude := range matrix.Exclude {
+			if matrixEntryMatches(combination, exclude, nil) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			kept = append(kept, combination)
+		}
+	}
+
+	axes := make(map[string]bool, len(matrix.Axes))
+	for _, axis := range matrix.Axes {
+		axes[axis.Name] = true
+	}
+	for _, include := range matrix.Include {
+		matched := false
+		for _, combination := range kept {
+			if !matrixEntryMatches(combination, include, axes) {
+				continue
+			}
+			matched = true
+			for k, v := range include {
+				if !axes[k] {
+					combination[k] = util.DeepCopy(v)
+				}
+			}
+		}
+		if !matched {
+			kept = append(kept, util.DeepCopy(include).(map[string]interface{}))
+		}
+	}
+
+	items := make([]interface{}, len(kept))
+	for i, combination := range kept {
+		items[i] = combination
+	}
+	return items, nil
+}
+
+// resolveMatrixAxis returns the values of a matrix axis, rendering it first if
+// it is a template.
+func resolveMatrixAxis(axis config.MatrixAxis, data map[string]interface{}, renderer intTemplate.Renderer) ([]interface{}, error) {
+	values := axis.Values
+	if template, ok := values.(string); ok {
+		resolved, err := renderer.Resolve(template, data)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve loop_matrix axis '%s' expression '%s': %w", axis.Name, template, err)
+		}
+		values = resolved
+	} else {
+		values = util.DeepCopy(values)
+	}
+	items, err := extractItems(values)
+	if err != nil {
+		return nil, fmt.Errorf("loop_matrix axis '%s': %w", axis.Name, err)
+	}
+	return items, nil
+}
+
+// matrixEntryMatches reports whether a combination has the values of an
+// include or exclude entry. When keys is set, only the entry's keys in it are
+// compared.
+func matrixEntryMatches(combination, entry map[string]interface{}, keys map[string]bool) bool {
+	for k, v := range entry {
+		if keys != nil && !keys[k] {
+			continue
+		}
+		value, ok := combination[k]
+		if !ok || !matrixValuesEqual(value, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// matrixValuesEqual compares matrix values loosely, so that a rendered axis
+// value matches the same value written in an entry.
+func matrixValuesEqual(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b) || fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+// loopIterationName returns the readable name of an iteration of a
+// 'loop_matrix': its axis values in axis order, then the keys added by
+// include entries in sorted order, e.g. "os=linux,arch=arm64". It returns ""
+// for a plain 'loop', whose iterations are named by their index.
+func loopIterationName(task *config.Task, item interface{}) string {
+	if task.LoopMatrix == nil {
+		return ""
+	}
+	combination, ok := item.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	seen := make(map[string]bool, len(combination))
+	var parts []string
+	for _, name := range task.LoopMatrix.AxisNames() {
+		if value, ok := combination[name]; ok {
+			parts = append(parts, fmt.Sprintf("%s=%v", name, value))
+			seen[name] = true
+		}
+	}
+	var extra []string
+	for k := range combination {
+		if !seen[k] {
+			extra = append(extra, k)
+		}
+	}
+	sort.Strings(extra)
+	for _, k := range extra {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, combination[k]))
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gxo-labs/gxo/internal/config"
+	intSecrets "github.com/gxo-labs/gxo/internal/secrets"
+	"github.com/gxo-labs/gxo/internal/template"
+)
+
+// renderOutputs renders the playbook's outputs against the final state of the
+// run into r.outputs. Secrets resolved while rendering are tracked and
+// redacted from the values. An output that fails to render fails a
+// successful run; after a failed run, it is left out with a warning, since
+// the values it refers to may never have been registered.
+func (r *playbookRun) renderOutputs(playbook *config.Playbook, runSucceeded bool) error {
+	outputs := make(map[string]interface{}, len(playbook.Outputs))
+	r.outputs = outputs
+	if len(playbook.Outputs) == 0 {
+		return nil
+	}
+
+	secretTracker := intSecrets.NewSecretTracker()
+	renderer := template.NewGoRenderer(r.secretsProvider, r.eventBus, secretTracker)
+	state := r.stateManager.GetAll()
+	names := make([]string, 0, len(playbook.Outputs))
+	for name := range playbook.Outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, err := renderer.Resolve(playbook.Outputs[name], state)
+		if err != nil {
+			if runSucceeded {
+				return fmt.Errorf("failed to render output '%s' of playbook '%s': %w", name, playbook.Name, err)
+			}
+			r.log.Warnf("Output '%s' was not rendered because the run failed: %v", name, err)
+			continue
+		}
+		outputs[name] = value
+	}
+
+	if redacted, wasRedacted := template.RedactTrackedSecrets(outputs, secretTracker); wasRedacted {
+		r.outputs = redacted.(map[string]interface{})
+		r.log.Warnf("SECURITY WARNING: Outputs of playbook '%s' contained one or more resolved secrets. The secret values have been redacted.", playbook.Name)
+		if r.secretsRedactedCounter != nil {
+			r.secretsRedactedCounter.Inc()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,334 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gxo-labs/gxo/internal/config"
+	intState "github.com/gxo-labs/gxo/internal/state"
+	"github.com/gxo-labs/gxo/internal/template"
+	gxov1state "github.com/gxo-labs/gxo/pkg/gxo/v1/state"
+)
+
+// Outcomes of a task's 'when' conditions in a plan.
+const (
+	PlanWhenTrue    = "true"    // All conditions evaluate to true from 'vars'.
+	PlanWhenFalse   = "false"   // A condition evaluates to false; the task will be skipped.
+	PlanWhenRuntime = "runtime" // A condition depends on values only known while running.
+)
+
+// Plan previews how a playbook would be executed, without executing it.
+type Plan struct {
+	Playbook       string        `json:"playbook"`
+	WorkerPoolSize int           `json:"worker_pool_size"`
+	Sections       []SectionPlan `json:"sections"`
+}
+
+// SectionPlan previews the execution of a single playbook section.
+type SectionPlan struct {
+	Section string     `json:"section"`
+	Waves   []PlanWave `json:"waves"`
+	// CriticalPath is the longest chain of dependent tasks in the section,
+	// which bounds how far concurrency can shorten it.
+	CriticalPath []string `json:"critical_path"`
+}
+
+// PlanWave is a group of tasks that can run concurrently once all earlier
+// waves have finished. A wave holds at most WorkerPoolSize tasks; blocks and
+// include_playbook tasks do not take a worker.
+type PlanWave struct {
+	Index int           `json:"index"`
+	Tasks []PlannedTask `json:"tasks"`
+}
+
+// PlannedTask describes a task within a wave.
+type PlannedTask struct {
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Block   string `json:"block,omitempty"`
+	IsBlock bool   `json:"is_block,omitempty"`
+	// When is one of the PlanWhen* values, or empty if the task has no
+	// 'when' condition.
+	When string    `json:"when,omitempty"`
+	Loop *LoopPlan `json:"loop,omitempty"`
+}
+
+// DisplayName returns the task's name, or its ID if it is unnamed.
+func (t PlannedTask) DisplayName() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.ID
+}
+
+// LoopPlan describes how far a looping task fans out.
+type LoopPlan struct {
+	// Static reports whether the loop items are known before the run, either
+	// because they are listed literally or because they come from 'vars'.
+	Static bool `json:"static"`
+	// Items is the number of iterations. It is only set for static loops.
+	Items    int `json:"items"`
+	Parallel int `json:"parallel"`
+	// Error is set if the static loop items cannot be resolved, in which case
+	// the task would fail.
+	Error string `json:"error,omitempty"`
+}
+
+// BuildPlan previews the execution of a loaded playbook with the given number
+// of workers. It builds each section's DAG exactly as a run would, and resolves
+// loop items and 'when' conditions that only depend on the playbook's 'vars'.
+// Nothing is executed: state is kept in a private in-memory store and secrets
+// are never resolved.
+func BuildPlan(playbook *config.Playbook, workerPoolSize int) (*Plan, error) {
+	if workerPoolSize <= 0 {
+		return nil, fmt.Errorf("worker pool size must be positive, got %d", workerPoolSize)
+	}
+	stateStore := intState.NewMemoryStateStore()
+	if err := stateStore.Load(playbook.Vars); err != nil {
+		return nil, fmt.Errorf("failed to load playbook vars: %w", err)
+	}
+	renderer := template.NewGoRenderer(nil, nil, nil)
+	planner := &planner{
+		playbook:   playbook,
+		state:      stateStore,
+		renderer:   renderer,
+		registered: make(map[string]struct{}),
+	}
+	for _, section := range []string{config.SectionTasks, config.SectionOnFailure, config.SectionFinally} {
+		for _, task := range config.FlattenTasks(playbook.SectionTasks(section)) {
+			if task.Register != "" {
+				planner.registered[task.Register] = struct{}{}
+			}
+		}
+	}
+
+	plan := &Plan{Playbook: playbook.Name, WorkerPoolSize: workerPoolSize, Sections: []SectionPlan{}}
+	for _, section := range []string{config.SectionTasks, config.SectionOnFailure, config.SectionFinally} {
+		tasks := config.FlattenTasks(playbook.SectionTasks(section))
+		if len(tasks) == 0 {
+			continue
+		}
+		dag, _, err := BuildSectionDAG(playbook, section, stateStore, renderer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build DAG for '%s' section: %w", section, err)
+		}
+		nodes := make([]*Node, len(tasks))
+		for i, task := range tasks {
+			nodes[i] = dag.Nodes[task.InternalID]
+		}
+		sectionPlan := SectionPlan{Section: section, Waves: []PlanWave{}}
+		for i, wave := range planWaves(nodes, workerPoolSize) {
+			planWave := PlanWave{Index: i + 1, Tasks: make([]PlannedTask, 0, len(wave))}
+			for _, node := range wave {
+				planWave.Tasks = append(planWave.Tasks, planner.describe(node))
+			}
+			sectionPlan.Waves = append(sectionPlan.Waves, planWave)
+		}
+		sectionPlan.CriticalPath = criticalPath(nodes)
+		plan.Sections = append(plan.Sections, sectionPlan)
+	}
+	return plan, nil
+}
+
+// planner resolves what can be known about a task before the run.
+type planner struct {
+	playbook   *config.Playbook
+	state      gxov1state.StateReader
+	renderer   template.Renderer
+	registered map[string]struct{}
+}
+
+func (p *planner) describe(node *Node) PlannedTask {
+	planned := PlannedTask{
+		ID:      node.ID,
+		Name:    node.Task.Name,
+		Type:    node.Task.Type,
+		IsBlock: node.IsBlock(),
+	}
+	if node.Block != nil {
+		planned.Block = node.Block.ID
+	}
+	// A block's own conditions are inherited by, and evaluated for, its members.
+	if !planned.IsBlock {
+		planned.When = p.planWhen(node.Task)
+		planned.Loop = p.planLoop(node.Task)
+	}
+	return planned
+}
+
+// isStatic reports whether a template of task only depends on the playbook's
+// 'vars', so that it renders the same before and during the run. Names the
+// task's own 'vars' define, including those inherited from its blocks, shadow
+// the playbook's and are rendered during the run, so they are not static.
+func (p *planner) isStatic(task *config.Task, tmplStr string) bool {
+	vars, err := p.renderer.ExtractVariables(tmplStr)
+	if err != nil || (vars == nil && strings.Contains(tmplStr, "{{")) {
+		return false
+	}
+	for _, fullVarPath := range vars {
+		root := strings.Split(fullVarPath, ".")[0]
+		if root == template.GxoStateKeyPrefix {
+			return false
+		}
+		if _, isRegistered := p.registered[root]; isRegistered {
+			return false
+		}
+		if _, isTaskVar := task.Vars[root]; isTaskVar {
+			return false
+		}
+		if _, isVar := p.playbook.Vars[root]; !isVar {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *planner) planWhen(task *config.Task) string {
+	conditions := task.WhenConditions()
+	if len(conditions) == 0 {
+		return ""
+	}
+	outcome := PlanWhenTrue
+	for _, condition := range conditions {
+		if !p.isStatic(task, condition) {
+			outcome = PlanWhenRuntime
+			continue
+		}
+		result, err := p.renderer.Render(condition, p.state.GetAll())
+		if err != nil {
+			outcome = PlanWhenRuntime
+			continue
+		}
+		if !evaluateConditionString(result) {
+			return PlanWhenFalse
+		}
+	}
+	return outcome
+}
+
+func (p *planner) planLoop(task *config.Task) *LoopPlan {
+	if !task.HasLoop() {
+		return nil
+	}
+	loopPlan := &LoopPlan{Parallel: task.GetLoopParallel()}
+	if loopStr, isTemplate := task.Loop.(string); isTemplate && !p.isStatic(task, loopStr) {
+		return loopPlan
+	}
+	if task.LoopMatrix != nil {
+		for _, axis := range task.LoopMatrix.Templates() {
+			if !p.isStatic(task, axis) {
+				return loopPlan
+			}
+		}
+	}
+	loopPlan.Static = true
+	items, err := (&TaskRunner{}).resolveLoopItems(task, p.state.GetAll(), p.renderer)
+	if err != nil {
+		loopPlan.Error = err.Error()
+		return loopPlan
+	}
+	loopPlan.Items = len(items)
+	return loopPlan
+}
+
+// planWaves groups the nodes, given in playbook order, into waves. A task
+// joins the earliest wave after those of its state dependencies; a stream
+// consumer may share its producers' wave, as it starts as soon as they do.
+func planWaves(nodes []*Node, workerPoolSize int) [][]*Node {
+	waveOf := make(map[string]int, len(nodes))
+	var waves [][]*Node
+	for len(waveOf) < len(nodes) {
+		current := len(waves)
+		var wave []*Node
+		workers := 0
+		for added := true; added; {
+			added = false
+			for _, node := range nodes {
+				if _, placed := waveOf[node.ID]; placed || !readyInWave(node, waveOf, current) {
+					continue
+				}
+				if node.TakesWorker() {
+					if workers == workerPoolSize {
+						continue
+					}
+					workers++
+				}
+				waveOf[node.ID] = current
+				wave = append(wave, node)
+				added = true
+			}
+		}
+		if len(wave) == 0 {
+			// Unreachable for an acyclic DAG; guards against looping forever.
+			break
+		}
+		waves = append(waves, wave)
+	}
+	return waves
+}
+
+func readyInWave(node *Node, waveOf map[string]int, wave int) bool {
+	for id := range node.StateDependsOn {
+		if w, placed := waveOf[id]; !placed || w >= wave {
+			return false
+		}
+	}
+	for id := range node.StreamDependsOn {
+		if _, placed := waveOf[id]; !placed {
+			return false
+		}
+	}
+	return true
+}
+
+// criticalPath returns the display names of the longest chain of dependent
+// tasks. Blocks run no module and are not counted; ties are broken in favor of
+// the task listed first.
+func criticalPath(nodes []*Node) []string {
+	order := make(map[string]int, len(nodes))
+	for i, node := range nodes {
+		order[node.ID] = i
+	}
+	length := make(map[string]int, len(nodes))
+	previous := make(map[string]*Node, len(nodes))
+	var measure func(node *Node) int
+	measure = func(node *Node) int {
+		if l, done := length[node.ID]; done {
+			return l
+		}
+		var longest *Node
+		for _, deps := range []map[string]*Node{node.StateDependsOn, node.StreamDependsOn} {
+			for _, dep := range deps {
+				if longest == nil || measure(dep) > measure(longest) ||
+					(measure(dep) == measure(longest) && order[dep.ID] < order[longest.ID]) {
+					longest = dep
+				}
+			}
+		}
+		l := 0
+		if longest != nil {
+			l = measure(longest)
+			previous[node.ID] = longest
+		}
+		if !node.IsBlock() {
+			l++
+		}
+		length[node.ID] = l
+		return l
+	}
+
+	var end *Node
+	for _, node := range nodes {
+		if end == nil || measure(node) > measure(end) {
+			end = node
+		}
+	}
+	var path []string
+	for node := end; node != nil; node = previous[node.ID] {
+		if !node.IsBlock() {
+			path = append([]string{node.DisplayName()}, path...)
+		}
+	}
+	return path
+}
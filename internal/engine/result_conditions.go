@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/gxo-labs/gxo/internal/config"
+	intTemplate "github.com/gxo-labs/gxo/internal/template"
+	gxolog "github.com/gxo-labs/gxo/pkg/gxo/v1/log"
+)
+
+// resultVar is the name under which the 'until', 'failed_when' and
+// 'changed_when' conditions see the summary they are evaluated against.
+const resultVar = "result"
+
+// changedSummaryKey is the summary key through which a module reports that it
+// changed something, for tasks without 'changed_when'.
+const changedSummaryKey = "changed"
+
+// resultConditionData returns the data a result condition is rendered with:
+// the task's template data and the summary as '.result'.
+func resultConditionData(templateData map[string]interface{}, summary interface{}) map[string]interface{} {
+	data := make(map[string]interface{}, len(templateData)+1)
+	for k, v := range templateData {
+		data[k] = v
+	}
+	data[resultVar] = summary
+	return data
+}
+
+// applyFailedWhen decides the outcome of an execution that returned summary,
+// possibly nil, and performErr, after its retries, from the task's
+// 'failed_when' condition. A true condition fails the execution, keeping the
+// module's error if it returned one; a false one makes it succeed.
+func applyFailedWhen(task *config.Task, taskLogger gxolog.Logger, templateData map[string]interface{}, renderer intTemplate.Renderer, summary interface{}, performErr error) error {
+	conditionResult, err := renderer.Render(task.FailedWhen, resultConditionData(templateData, summary))
+	if err != nil {
+		return fmt.Errorf("failed to evaluate 'failed_when' condition: %w", err)
+	}
+	if !evaluateConditionString(conditionResult) {
+		if performErr != nil {
+			taskLogger.Infof("Ignoring module error because the 'failed_when' condition is false: %v", performErr)
+		}
+		return nil
+	}
+	if performErr != nil {
+		return performErr
+	}
+	return fmt.Errorf("'failed_when' condition is true: [%s]", conditionResult)
+}
+
+// evaluateChanged reports whether a task instance that succeeded with summary
+// changed something, according to its 'changed_when' condition or, without
+// one, to the 'changed' key of the summary.
+func evaluateChanged(task *config.Task, templateData map[string]interface{}, renderer intTemplate.Renderer, summary interface{}) (bool, error) {
+	if task.ChangedWhen == "" {
+		summaryMap, ok := summary.(map[string]interface{})
+		if !ok {
+			return false, nil
+		}
+		changed, _ := summaryMap[changedSummaryKey].(bool)
+		return changed, nil
+	}
+	conditionResult, err := renderer.Render(task.ChangedWhen, resultConditionData(templateData, summary))
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate 'changed_when' condition: %w", err)
+	}
+	return evaluateConditionString(conditionResult), nil
+}
@@ -0,0 +1,250 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gxo-labs/gxo/internal/config"
+	intState "github.com/gxo-labs/gxo/internal/state"
+	"github.com/gxo-labs/gxo/internal/template"
+	gxo "github.com/gxo-labs/gxo/pkg/gxo/v1"
+	"github.com/gxo-labs/gxo/pkg/gxo/v1/checkpoint"
+	gxov1state "github.com/gxo-labs/gxo/pkg/gxo/v1/state"
+)
+
+// playbookRun holds the state of a single execution of a playbook. It embeds
+// the engine for its providers and configuration; everything that changes
+// while a playbook executes lives here, so concurrent runs do not interfere.
+type playbookRun struct {
+	*Engine
+
+	// Per-run components. stateManager shadows the engine's configured store:
+	// it is the run's namespace of that store, or a fresh in-memory store if
+	// none was configured.
+	stateManager   gxov1state.Store
+	channelManager *ChannelManager
+	taskRunner     *TaskRunner
+	// workerSlots is the engine's pool at the time the run started.
+	workerSlots chan struct{}
+
+	// Runtime State
+	runID           string
+	playbook        *config.Playbook
+	playbookYAML    []byte
+	initialVars     map[string]interface{}
+	runStartTime    time.Time
+	firstFailedTask string
+	firstFailure    error
+	failureMu       sync.Mutex
+	checkpointMu    sync.Mutex
+	workQueue       chan string
+	dag             *DAG
+	nodesByID       map[string]*Node
+	totalTasks      int32
+	completedTasks  atomic.Int32
+	activeWorkers   atomic.Int32
+	readyChan       chan string
+	taskStatuses    map[string]TaskStatus
+	statusMu        sync.RWMutex
+	taskTimings     map[string]taskTiming
+	timingsMu       sync.RWMutex
+	taskErrors      map[string]error
+	errorsMu        sync.Mutex
+	// outputs holds the playbook's outputs, rendered once the run finished.
+	outputs map[string]interface{}
+	// prunedTasks holds the tasks left out by the task selection, with the
+	// reason they are skipped. reusedTasks marks those among them whose
+	// registered value was kept from a previous run.
+	prunedTasks map[string]error
+	reusedTasks map[string]bool
+	// haltedTasks marks the tasks skipped because a failure halted the run.
+	// They are left out of checkpoints, so that a resumed run executes them.
+	haltedTasks map[string]bool
+
+	// Include State. A run started by an include_playbook task executes a
+	// playbook loaded by its parent, with the given vars added to its own.
+	parent       *playbookRun
+	preloaded    *config.Playbook
+	includeVars  map[string]interface{}
+	includeChain []string
+	// includedReports holds the reports of the playbooks included by each
+	// task, keyed by task ID and then by loop iteration (-1 without a loop).
+	includedReports map[string]map[int]*gxo.ExecutionReport
+	includesMu      sync.Mutex
+	// iterations holds the outcome of each iteration of the looped tasks,
+	// keyed by task ID and then by loop iteration.
+	iterations   map[string]map[int]gxo.IterationResult
+	iterationsMu sync.Mutex
+	// changedTasks marks the tasks that reported a change.
+	changedTasks map[string]bool
+	changedMu    sync.Mutex
+	// cacheCounts tallies the cache hits and misses of each task with a
+	// 'cache', keyed by task ID.
+	cacheCounts map[string]*cacheCount
+	cacheMu     sync.Mutex
+
+	// Control State
+	paused         atomic.Bool
+	pauseChanged   chan struct{}
+	taskCancels    map[string]context.CancelFunc
+	cancelledTasks map[string]bool
+	cancelMu       sync.Mutex
+
+	// Handle State
+	cancel context.CancelFunc
+	done   chan struct{}
+	report *gxo.ExecutionReport
+	err    error
+}
+
+var _ gxo.RunHandle = (*playbookRun)(nil)
+
+// StateKeyGxoRunsPrefix is the state key prefix under which a run keeps its
+// state in the engine's configured state store: a run keeps it under
+// _gxo.runs.<run ID> while it executes, so that concurrent runs sharing the
+// store never see or replace each other's state.
+const StateKeyGxoRunsPrefix = template.GxoStateKeyPrefix + ".runs"
+
+// startRun creates a run and executes it in the background. When resumeFrom is
+// non-nil, the run continues from that checkpoint. With a configured state
+// store, the run keeps its state in its own namespace of it, which is removed
+// once the run finished. When publish is set, the run's final state is first
+// copied to the top level of the store.
+func (e *Engine) startRun(ctx context.Context, playbookYAML []byte, runID string, resumeFrom *checkpoint.Checkpoint, publish bool) (*playbookRun, error) {
+	var stateManager gxov1state.Store
+	var namespace *namespacedStore
+	if e.stateManager != nil {
+		namespace = newNamespacedStore(e.stateManager, StateKeyGxoRunsPrefix+"."+runID)
+		stateManager = namespace
+	} else {
+		stateManager = intState.NewMemoryStateStore()
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	r := e.newPlaybookRun(runID, stateManager, cancel)
+	r.playbookYAML = playbookYAML
+	go func() {
+		defer close(r.done)
+		defer cancel()
+		r.report, r.err = r.executeRun(runCtx, resumeFrom)
+		if namespace == nil {
+			return
+		}
+		if publish {
+			e.publishMu.Lock()
+			if err := namespace.publish(); err != nil {
+				e.log.Warnf("Failed to copy the state of run %s to the state store: %v", runID, err)
+			}
+			e.publishMu.Unlock()
+		}
+		if err := namespace.clear(); err != nil {
+			e.log.Warnf("Failed to remove the state of run %s from the state store: %v", runID, err)
+		}
+	}()
+	return r, nil
+}
+
+// newPlaybookRun creates the state of a run keeping its state in stateManager.
+// cancel cancels the context the run executes on.
+func (e *Engine) newPlaybookRun(runID string, stateManager gxov1state.Store, cancel context.CancelFunc) *playbookRun {
+	channelManager := NewChannelManager(e.defaultChannelPolicy)
+	taskRunner := NewTaskRunner(
+		stateManager,
+		e.pluginRegistry,
+		e.log,
+		channelManager,
+		e.retryHelper,
+		e.eventBus,
+		e.hooks,
+		e.secretsProvider,
+		e.tracerProvider,
+		e.redactedKeywords,
+		e.defaultTimeout,
+	)
+	taskRunner.secretsRedactedCounter = e.secretsRedactedCounter
+	taskRunner.cacheStore = e.cacheStore
+
+	r := &playbookRun{
+		Engine:          e,
+		stateManager:    stateManager,
+		channelManager:  channelManager,
+		taskRunner:      taskRunner,
+		workerSlots:     e.workerSlots,
+		runID:           runID,
+		taskStatuses:    make(map[string]TaskStatus),
+		taskTimings:     make(map[string]taskTiming),
+		taskErrors:      make(map[string]error),
+		prunedTasks:     make(map[string]error),
+		reusedTasks:     make(map[string]bool),
+		haltedTasks:     make(map[string]bool),
+		nodesByID:       make(map[string]*Node),
+		includedReports: make(map[string]map[int]*gxo.ExecutionReport),
+		iterations:      make(map[string]map[int]gxo.IterationResult),
+		changedTasks:    make(map[string]bool),
+		cacheCounts:     make(map[string]*cacheCount),
+		pauseChanged:    make(chan struct{}, 1),
+		taskCancels:     make(map[string]context.CancelFunc),
+		cancelledTasks:  make(map[string]bool),
+		cancel:          cancel,
+		done:            make(chan struct{}),
+	}
+	taskRunner.includePlaybook = r.includePlaybook
+	taskRunner.recordIteration = r.recordIteration
+	taskRunner.recordChanged = r.recordChanged
+	taskRunner.recordCacheLookup = r.recordCacheLookup
+	taskRunner.resolvePath = r.resolvePlaybookPath
+	return r
+}
+
+// RunID returns the ID of the run.
+func (r *playbookRun) RunID() string {
+	return r.runID
+}
+
+// Wait blocks until the run has finished and returns its report.
+func (r *playbookRun) Wait() (*gxo.ExecutionReport, error) {
+	<-r.done
+	return r.report, r.err
+}
+
+// Cancel cancels the run. Running tasks are interrupted, and the on_failure
+// and finally sections still run. Cancel does not wait for the run to finish.
+func (r *playbookRun) Cancel() {
+	r.cancel()
+}
+
+// Status returns a snapshot of the run's progress.
+func (r *playbookRun) Status() gxo.RunStatus {
+	select {
+	case <-r.done:
+		status := gxo.RunStatus{RunID: r.runID, State: r.report.OverallStatus, TaskStatuses: make(map[string]string)}
+		status.PlaybookName = r.report.PlaybookName
+		for id, result := range r.report.TaskResults {
+			status.TaskStatuses[id] = result.Status
+		}
+		status.TotalTasks = r.report.TotalTasks
+		status.FinishedTasks = r.report.CompletedTasks + r.report.FailedTasks + r.report.SkippedTasks
+		return status
+	default:
+	}
+
+	status := gxo.RunStatus{RunID: r.runID, State: gxo.RunStateRunning, TaskStatuses: make(map[string]string)}
+	if r.paused.Load() {
+		status.State = gxo.RunStatePaused
+	}
+	r.statusMu.RLock()
+	defer r.statusMu.RUnlock()
+	if r.playbook != nil {
+		status.PlaybookName = r.playbook.Name
+	}
+	for id, taskStatus := range r.taskStatuses {
+		status.TaskStatuses[id] = string(taskStatus)
+		if taskStatus == StatusCompleted || taskStatus == StatusFailed || taskStatus == StatusSkipped {
+			status.FinishedTasks++
+		}
+	}
+	status.TotalTasks = len(r.taskStatuses)
+	return status
+}
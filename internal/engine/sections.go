@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gxo-labs/gxo/internal/config"
+	"github.com/gxo-labs/gxo/internal/template"
+	gxoerrors "github.com/gxo-labs/gxo/pkg/gxo/v1/errors"
+)
+
+// State keys describing the outcome of the main tasks. They are written before
+// the on_failure and finally sections run so their tasks can react to it.
+const (
+	StateKeyGxoRunPrefix  = template.GxoStateKeyPrefix + ".run"
+	StateKeyRunStatus     = StateKeyGxoRunPrefix + ".status"
+	StateKeyRunFailedTask = StateKeyGxoRunPrefix + ".failed_task"
+	StateKeyRunError      = StateKeyGxoRunPrefix + ".error"
+)
+
+// recordFirstFailure remembers the first task whose unignored failure halted
+// the run, so it can be exposed to the on_failure and finally sections.
+func (r *playbookRun) recordFirstFailure(node *Node, taskErr error) {
+	r.failureMu.Lock()
+	defer r.failureMu.Unlock()
+	if r.firstFailedTask != "" {
+		return
+	}
+	r.firstFailedTask = node.ID
+	if node.Task != nil && node.Task.Name != "" {
+		r.firstFailedTask = node.Task.Name
+	}
+	r.firstFailure = template.RedactSecretsInError(taskErr, r.redactedKeywords)
+}
+
+// runSections executes the on_failure and finally sections after the main DAG
+// has finished, given the outcome mainErr of the main tasks. Sections run on a
+// context that is detached from the run's cancellation, so cleanup still
+// happens after a failure, a timeout or an interrupt. It returns the overall
+// outcome of the run: mainErr if the main tasks failed, otherwise the first
+// section failure.
+func (r *playbookRun) runSections(ctx context.Context, mainErr error) error {
+	if len(r.playbook.OnFailure) == 0 && len(r.playbook.Finally) == 0 {
+		return mainErr
+	}
+	r.writeRunOutcome(mainErr)
+	sectionCtx := context.WithoutCancel(ctx)
+
+	outcome := mainErr
+	if mainErr != nil && len(r.playbook.OnFailure) > 0 {
+		if err := r.runSection(sectionCtx, config.SectionOnFailure); err != nil && outcome == nil {
+			outcome = err
+		}
+	}
+	if len(r.playbook.Finally) > 0 {
+		if err := r.runSection(sectionCtx, config.SectionFinally); err != nil && outcome == nil {
+			outcome = err
+		}
+	}
+	return outcome
+}
+
+// writeRunOutcome stores the status of the main tasks and, if they failed, the
+// name and redacted error of the task that caused the failure.
+func (r *playbookRun) writeRunOutcome(mainErr error) {
+	status, failedTask, errMsg := string(StatusCompleted), "", ""
+	if mainErr != nil {
+		status = string(StatusFailed)
+		r.failureMu.Lock()
+		failedTask = r.firstFailedTask
+		if r.firstFailure != nil {
+			errMsg = r.firstFailure.Error()
+		}
+		r.failureMu.Unlock()
+		if errMsg == "" {
+			errMsg = template.RedactSecretsInError(mainErr, r.redactedKeywords).Error()
+		}
+	}
+	for key, value := range map[string]string{StateKeyRunStatus: status, StateKeyRunFailedTask: failedTask, StateKeyRunError: errMsg} {
+		if err := r.stateManager.Set(key, value); err != nil {
+			r.log.Errorf("Failed to write run outcome key %s to state: %v", key, err)
+		}
+	}
+}
+
+// runSection builds and schedules the DAG of a single section. It returns an
+// error if the DAG could not be built or any of its tasks failed.
+func (r *playbookRun) runSection(ctx context.Context, section string) error {
+	r.log.Infof("Running '%s' section...", section)
+	renderer := template.NewGoRenderer(r.secretsProvider, r.eventBus, nil)
+	sectionDAG, initialReadyNodes, err := BuildSectionDAG(r.playbook, section, r.stateManager, renderer)
+	if err != nil {
+		r.log.Errorf("Failed to build DAG for '%s' section: %v", section, err)
+		return fmt.Errorf("failed to build DAG for '%s' section: %w", section, err)
+	}
+	if len(initialReadyNodes) == 0 {
+		return gxoerrors.NewConfigError(fmt.Sprintf("no initially ready tasks found in '%s' section", section), nil)
+	}
+
+	r.dag = sectionDAG
+	r.totalTasks = int32(len(sectionDAG.Nodes))
+	r.completedTasks.Store(0)
+	if err := r.channelManager.CreateChannels(sectionDAG); err != nil {
+		r.log.Errorf("Failed to create execution channels for '%s' section: %v", section, err)
+		return fmt.Errorf("failed to create channels for '%s' section: %w", section, err)
+	}
+	r.initTaskStatuses()
+
+	if fatalErr := r.scheduleDAG(ctx, initialReadyNodes, 0); fatalErr != nil {
+		r.log.Errorf("'%s' section halted: %v", section, template.RedactSecretsInError(fatalErr, r.redactedKeywords))
+		return gxoerrors.NewConfigError(fmt.Sprintf("'%s' section finished due to fatal error", section), template.RedactSecretsInError(fatalErr, r.redactedKeywords))
+	}
+
+	r.statusMu.RLock()
+	defer r.statusMu.RUnlock()
+	for id := range sectionDAG.Nodes {
+		if r.taskStatuses[id] == StatusFailed {
+			return gxoerrors.NewConfigError(fmt.Sprintf("'%s' section finished with one or more failed tasks", section), nil)
+		}
+	}
+	r.log.Infof("'%s' section finished.", section)
+	return nil
+}
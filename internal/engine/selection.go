@@ -0,0 +1,257 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	gxo "github.com/gxo-labs/gxo/pkg/gxo/v1"
+	gxoerrors "github.com/gxo-labs/gxo/pkg/gxo/v1/errors"
+)
+
+// applySelection restricts the run to the tasks of r.dag picked by the
+// selection and their dependencies. The other tasks are recorded in
+// r.prunedTasks and are skipped with the reason once they become ready, so
+// that the report accounts for every task. A dependency whose registered value
+// is already in the state is skipped too and counts as completed for the
+// trigger rules of its dependents.
+func (r *playbookRun) applySelection(selection gxo.TaskSelection) error {
+	if selection.ReuseRunID != "" {
+		if err := r.loadReusedValues(selection.ReuseRunID); err != nil {
+			return err
+		}
+	}
+
+	picked, err := r.pickSelectedTasks(selection)
+	if err != nil {
+		return err
+	}
+	if len(picked) == 0 {
+		return gxoerrors.NewConfigError(fmt.Sprintf("task selection (%s) matches no task", describeSelection(selection)), nil)
+	}
+
+	selected := make(map[string]bool)
+	reused := make(map[string]string)
+	var queue []*Node
+	var include func(node *Node)
+	include = func(node *Node) {
+		if selected[node.ID] {
+			return
+		}
+		selected[node.ID] = true
+		queue = append(queue, node)
+		// A block chosen as a dependency runs as a whole.
+		for _, member := range blockMembers(node) {
+			include(member)
+		}
+	}
+	for _, node := range picked {
+		include(node)
+	}
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		// A block runs, to carry the status of its selected members, but
+		// does not pull in its other members.
+		if node.Block != nil && !selected[node.Block.ID] {
+			selected[node.Block.ID] = true
+			queue = append(queue, node.Block)
+		}
+		for depID, dep := range node.StateDependsOn {
+			if node.blockDeps[depID] || selected[depID] {
+				continue
+			}
+			if register := dep.Task.Register; register != "" {
+				if _, found := r.stateManager.Get(register); found {
+					reused[depID] = register
+					continue
+				}
+			}
+			include(dep)
+		}
+		// Streamed records are not persisted, so the tasks connected by a
+		// stream edge run together.
+		for _, producer := range node.StreamDependsOn {
+			include(producer)
+		}
+		for _, consumer := range node.RequiredBy {
+			if _, isStreamDep := consumer.StreamDependsOn[node.ID]; isStreamDep {
+				include(consumer)
+			}
+		}
+	}
+
+	description := describeSelection(selection)
+	for id := range r.dag.Nodes {
+		if selected[id] {
+			continue
+		}
+		if register, isReused := reused[id]; isReused {
+			r.reusedTasks[id] = true
+			r.prunedTasks[id] = gxoerrors.NewSkippedError(fmt.Sprintf("not selected (%s); its registered value '%s' is reused", description, register))
+		} else {
+			r.prunedTasks[id] = gxoerrors.NewSkippedError(fmt.Sprintf("not selected (%s)", description))
+		}
+	}
+	r.log.Infof("Task selection (%s) runs %d of %d tasks, reusing the registered values of %d.", description, len(selected), len(r.dag.Nodes), len(r.reusedTasks))
+	return nil
+}
+
+// pickSelectedTasks returns the tasks meeting all the criteria of the
+// selection, before dependencies are added.
+func (r *playbookRun) pickSelectedTasks(selection gxo.TaskSelection) ([]*Node, error) {
+	var only map[string]bool
+	if len(selection.Only) > 0 {
+		only = make(map[string]bool)
+		for _, name := range selection.Only {
+			node, err := r.selectedNode(name, "only")
+			if err != nil {
+				return nil, err
+			}
+			for _, member := range withBlockMembers(node) {
+				only[member.ID] = true
+			}
+		}
+	}
+	var downstream map[string]bool
+	if selection.StartAtTask != "" {
+		start, err := r.selectedNode(selection.StartAtTask, "start-at-task")
+		if err != nil {
+			return nil, err
+		}
+		downstream = make(map[string]bool)
+		queue := withBlockMembers(start)
+		for len(queue) > 0 {
+			node := queue[0]
+			queue = queue[1:]
+			if downstream[node.ID] {
+				continue
+			}
+			downstream[node.ID] = true
+			for _, dependent := range node.RequiredBy {
+				queue = append(queue, withBlockMembers(dependent)...)
+			}
+		}
+	}
+
+	var picked []*Node
+	for _, id := range sortedNodeIDs(r.dag) {
+		node := r.dag.Nodes[id]
+		if node.IsBlock() {
+			continue
+		}
+		if (only != nil && !only[id]) || (downstream != nil && !downstream[id]) {
+			continue
+		}
+		tags := nodeTags(node)
+		if len(selection.Tags) > 0 && !hasAnyTag(tags, selection.Tags) {
+			continue
+		}
+		if hasAnyTag(tags, selection.SkipTags) {
+			continue
+		}
+		picked = append(picked, node)
+	}
+	return picked, nil
+}
+
+// selectedNode returns the task a selection criterion names.
+func (r *playbookRun) selectedNode(name, criterion string) (*Node, error) {
+	if node, exists := r.dag.Nodes[name]; exists {
+		return node, nil
+	}
+	return nil, gxoerrors.NewConfigError(fmt.Sprintf("task selection '%s' names unknown task '%s'", criterion, name), nil)
+}
+
+// loadReusedValues loads the values registered by a checkpointed run into the
+// state, where they satisfy the dependencies that registered them.
+func (r *playbookRun) loadReusedValues(runID string) error {
+	if r.checkpointStore == nil {
+		return gxoerrors.NewConfigError(fmt.Sprintf("cannot reuse the values of run %s: no checkpoint store is configured", runID), nil)
+	}
+	cp, err := r.checkpointStore.Load(runID)
+	if err != nil {
+		return gxoerrors.NewConfigError(fmt.Sprintf("cannot reuse the values of run %s", runID), err)
+	}
+	for key, value := range cp.Registered {
+		if err := r.stateManager.Set(key, value); err != nil {
+			return fmt.Errorf("failed to load registered value '%s' of run %s: %w", key, runID, err)
+		}
+	}
+	r.log.Infof("Loaded %d registered values of run %s.", len(cp.Registered), runID)
+	return nil
+}
+
+// blockMembers returns the body, rescue and always tasks of a block node,
+// recursively.
+func blockMembers(node *Node) []*Node {
+	var members []*Node
+	for _, part := range [][]*Node{node.BlockBody, node.BlockRescue, node.BlockAlways} {
+		for _, member := range part {
+			members = append(members, member)
+			members = append(members, blockMembers(member)...)
+		}
+	}
+	return members
+}
+
+// withBlockMembers returns the node followed by its block members, if any.
+func withBlockMembers(node *Node) []*Node {
+	return append([]*Node{node}, blockMembers(node)...)
+}
+
+// nodeTags returns the tags of a task and of its enclosing blocks.
+func nodeTags(node *Node) []string {
+	var tags []string
+	for current := node; current != nil; current = current.Block {
+		if current.Task != nil {
+			tags = append(tags, current.Task.Tags...)
+		}
+	}
+	return tags
+}
+
+// hasAnyTag reports whether any of the wanted tags is in tags.
+func hasAnyTag(tags, wanted []string) bool {
+	for _, tag := range tags {
+		for _, want := range wanted {
+			if tag == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sortedNodeIDs returns the IDs of the DAG's nodes in order.
+func sortedNodeIDs(dag *DAG) []string {
+	ids := make([]string, 0, len(dag.Nodes))
+	for id := range dag.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// describeSelection formats the criteria of a selection for messages, e.g.
+// "tags: deploy; only: migrate".
+func describeSelection(selection gxo.TaskSelection) string {
+	var parts []string
+	if len(selection.Tags) > 0 {
+		parts = append(parts, "tags: "+strings.Join(selection.Tags, ","))
+	}
+	if len(selection.SkipTags) > 0 {
+		parts = append(parts, "skip-tags: "+strings.Join(selection.SkipTags, ","))
+	}
+	if len(selection.Only) > 0 {
+		parts = append(parts, "only: "+strings.Join(selection.Only, ","))
+	}
+	if selection.StartAtTask != "" {
+		parts = append(parts, "start-at-task: "+selection.StartAtTask)
+	}
+	if len(parts) == 0 {
+		return "all tasks"
+	}
+	return strings.Join(parts, "; ")
+}
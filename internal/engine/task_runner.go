@@ -18,6 +18,8 @@ import (
 	"github.com/gxo-labs/gxo/internal/util"
 	intTracing "github.com/gxo-labs/gxo/internal/tracing"
 
+	gxo "github.com/gxo-labs/gxo/pkg/gxo/v1"
+	gxocache "github.com/gxo-labs/gxo/pkg/gxo/v1/cache"
 	"github.com/gxo-labs/gxo/pkg/gxo/v1/events"
 	gxoerrors "github.com/gxo-labs/gxo/pkg/gxo/v1/errors"
 	gxolog "github.com/gxo-labs/gxo/pkg/gxo/v1/log"
@@ -97,6 +99,19 @@ type TaskRunner struct {
 	redactedKeywords       map[string]struct{}
 	defaultTimeout         time.Duration
 	secretsRedactedCounter prometheus.Counter
+	// includePlaybook runs the playbook included by an include_playbook task.
+	includePlaybook func(ctx context.Context, task *config.Task, iteration int, path string, vars map[string]interface{}) (interface{}, error)
+	// recordIteration keeps the outcome of an iteration of a looped task.
+	recordIteration func(taskID string, result gxo.IterationResult)
+	// recordChanged marks a task as having changed something.
+	recordChanged func(taskID string)
+	// cacheStore holds the cached summaries of the tasks with a 'cache'. It
+	// is nil if caching is disabled.
+	cacheStore gxocache.Store
+	// recordCacheLookup counts a hit or a miss of a task's cache.
+	recordCacheLookup func(task *config.Task, hit bool)
+	// resolvePath resolves a path relative to the playbook's directory.
+	resolvePath func(path string) string
 }
 
 func NewTaskRunner(
@@ -164,9 +179,26 @@ func (r *TaskRunner) ExecuteTask(
 		accessMode: node.StatePolicy.AccessMode,
 	}
 
-	if task.When != "" {
+	// The task's vars are rendered against the state and overlaid on it for
+	// all the task's templates. Those of a looped task are rendered again for
+	// each iteration, once the loop variable is bound; until then, only the
+	// vars not referring to it are available, to 'when' and 'loop'.
+	preLoopVars := task.Vars
+	if task.HasLoop() {
+		preLoopVars = loopIndependentVars(task.Vars, task.GetLoopVar(), taskInstanceRenderer)
+	}
+	taskVars, varsErr := renderTaskVars(preLoopVars, policyReader.GetAll(), taskInstanceRenderer)
+	if varsErr != nil {
+		finalErr = fmt.Errorf("failed to render vars for task '%s': %w", task.InternalID, varsErr)
+		if taskSpan != nil {
+			intTracing.RecordErrorWithContext(taskSpan, finalErr, r.redactedKeywords)
+		}
+		return nil, finalErr
+	}
+
+	for _, condition := range task.WhenConditions() {
 		taskLogger.Debugf("Evaluating 'when' condition")
-		conditionResult, err := taskInstanceRenderer.Render(task.When, policyReader.GetAll())
+		conditionResult, err := taskInstanceRenderer.Render(condition, mergeVars(policyReader.GetAll(), taskVars))
 		if err != nil {
 			redactedErr := intTemplate.RedactSecretsInError(err, r.redactedKeywords)
 			finalErr = gxoerrors.NewSkippedError(fmt.Sprintf("'when' condition error: %v", redactedErr))
@@ -187,7 +219,7 @@ func (r *TaskRunner) ExecuteTask(
 		}
 	}
 
-	loopItems, loopErr := r.resolveLoopItems(task.Loop, policyReader, taskInstanceRenderer)
+	loopItems, loopErr := r.resolveLoopItems(task, mergeVars(policyReader.GetAll(), taskVars), taskInstanceRenderer)
 	if loopErr != nil {
 		finalErr = fmt.Errorf("failed to resolve loop items for task '%s': %w", task.InternalID, loopErr)
 		if taskSpan != nil {
@@ -195,13 +227,18 @@ func (r *TaskRunner) ExecuteTask(
 		}
 		return nil, finalErr
 	}
-	if task.Loop != nil && len(loopItems) == 0 {
+	if task.HasLoop() && len(loopItems) == 0 {
 		taskLogger.Infof("Loop resulted in zero items. Task considered completed (no-op).")
 		if taskSpan != nil {
 			taskSpan.SetAttributes(attribute.Int("gxo.task.loop_iterations", 0))
 			taskSpan.SetStatus(codes.Ok, "")
 		}
-		return nil, nil
+		if task.Register != "" {
+			if regErr := r.stateManager.Set(task.Register, []interface{}{}); regErr != nil {
+				return nil, fmt.Errorf("failed to register result: %w", regErr)
+			}
+		}
+		return []interface{}{}, nil
 	}
 	if taskSpan != nil && len(loopItems) > 0 {
 		taskSpan.SetAttributes(attribute.Int("gxo.task.loop_iterations", len(loopItems)))
@@ -226,6 +263,7 @@ func (r *TaskRunner) ExecuteTask(
 	}
 
 	if len(loopItems) > 0 {
+		loopResults := make([]map[string]interface{}, len(loopItems))
 		var loopWg sync.WaitGroup
 		semaphore := make(chan struct{}, parallelism)
 		for i, item := range loopItems {
@@ -249,32 +287,38 @@ func (r *TaskRunner) ExecuteTask(
 					return
 				}
 
+				iterName := loopIterationName(task, currentItem)
 				iterLogger := taskLogger.With("loop_iteration", index)
+				if iterName != "" {
+					iterLogger = taskLogger.With("loop_iteration", iterName)
+				}
+				iterStart := time.Now()
 				iterSummary, iterErr := r.executeSingleTaskInstance(
 					instanceCtx, task, node, iterLogger, policyReader,
 					map[string]interface{}{loopVarName: currentItem},
-					aggregatedErrChan, index, tracer, isNoopTracer,
+					aggregatedErrChan, index, iterName, tracer, isNoopTracer,
 					taskInstanceRenderer, // Pass taskInstanceRenderer
+					secretTracker,
 				)
+				r.recordIterationResult(task.InternalID, index, iterName, iterStart, iterErr)
 
 				loopErrMu.Lock()
-				if iterErr != nil && !gxoerrors.IsSkipped(iterErr) {
-					if finalInstanceErr == nil {
-						finalInstanceErr = iterErr
-					}
-				} else if iterErr == nil && finalInstanceErr == nil {
-					finalInstanceSummary = iterSummary
+				loopResults[index] = r.loopResult(index, currentItem, iterSummary, iterErr)
+				if iterErr != nil && !gxoerrors.IsSkipped(iterErr) && finalInstanceErr == nil {
+					finalInstanceErr = iterErr
 				}
 				loopErrMu.Unlock()
 			}(i, item)
 		}
 	LoopEnd:
 		loopWg.Wait()
+		finalInstanceSummary = r.collectLoopResults(task, loopItems, loopResults, instanceCtx.Err())
 	} else {
 		finalInstanceSummary, finalInstanceErr = r.executeSingleTaskInstance(
-			instanceCtx, task, node, taskLogger, policyReader, nil,
-			aggregatedErrChan, -1, tracer, isNoopTracer,
+			instanceCtx, task, node, taskLogger, policyReader, taskVars,
+			aggregatedErrChan, -1, "", tracer, isNoopTracer,
 			taskInstanceRenderer, // Pass taskInstanceRenderer
+			secretTracker,
 		)
 	}
 
@@ -294,7 +338,9 @@ func (r *TaskRunner) ExecuteTask(
 		taskSpan.SetAttributes(attribute.String("gxo.task.status", status))
 	}
 
-	if finalInstanceErr == nil && task.Register != "" {
+	// A loop registers the results of all its iterations, failed ones
+	// included, so that they can be inspected downstream.
+	if (finalInstanceErr == nil || len(loopItems) > 0) && task.Register != "" {
 		redactedSummary, wasRedacted := intTemplate.RedactTrackedSecrets(finalInstanceSummary, secretTracker)
 		if wasRedacted {
 			taskLogger.Warnf("SECURITY WARNING: Task '%s' summary contained one or more resolved secrets. The secret values have been redacted before registration.", task.Name)
@@ -303,7 +349,9 @@ func (r *TaskRunner) ExecuteTask(
 			}
 		}
 		if regErr := r.stateManager.Set(task.Register, redactedSummary); regErr != nil {
-			finalInstanceErr = fmt.Errorf("failed to register result: %w", regErr)
+			if finalInstanceErr == nil {
+				finalInstanceErr = fmt.Errorf("failed to register result: %w", regErr)
+			}
 		} else {
 			finalInstanceSummary = redactedSummary
 		}
@@ -318,12 +366,14 @@ func (r *TaskRunner) executeSingleTaskInstance(
 	node *Node,
 	taskLogger gxolog.Logger,
 	policyReader gxov1state.StateReader,
-	loopScopeData map[string]interface{},
+	scopeData map[string]interface{},
 	aggregatedErrChan chan<- error,
 	loopIteration int,
+	iterationName string,
 	tracer oteltrace.Tracer,
 	isNoopTracer bool,
 	taskInstanceRenderer intTemplate.Renderer,
+	secretTracker *secrets.SecretTracker,
 ) (summary interface{}, err error) {
 	defer func() {
 		if _, exists := r.channelManager.GetOutputManagedChannels(task.InternalID); exists {
@@ -339,7 +389,9 @@ func (r *TaskRunner) executeSingleTaskInstance(
 	instanceCtx := ctx
 	if !isNoopTracer {
 		spanName := "gxo.task.instance"
-		if loopIteration >= 0 {
+		if iterationName != "" {
+			spanName = "gxo.task.instance." + iterationName
+		} else if loopIteration >= 0 {
 			spanName = fmt.Sprintf("gxo.task.instance.%d", loopIteration)
 		}
 		instanceCtx, instanceSpan = tracer.Start(instanceCtx, spanName)
@@ -368,15 +420,37 @@ func (r *TaskRunner) executeSingleTaskInstance(
 		}
 	}
 
-	factory, getErr := r.pluginRegistry.Get(task.Type)
-	if getErr != nil {
-		return nil, getErr
+	// The task's vars and loop variable are overlaid on the state. The scope
+	// of an iteration holds only its loop variable, which the vars may refer
+	// to.
+	if loopIteration >= 0 {
+		iterationVars, varsErr := renderTaskVars(task.Vars, mergeVars(policyReader.GetAll(), scopeData), taskInstanceRenderer)
+		if varsErr != nil {
+			finalErr = fmt.Errorf("failed to render vars for task '%s': %w", task.InternalID, varsErr)
+			return nil, finalErr
+		}
+		scopeData = mergeVars(iterationVars, scopeData)
+	}
+	templateData := mergeVars(policyReader.GetAll(), scopeData)
+
+	taskEnvironment, envErr := renderEnvironment(task.Environment, templateData, taskInstanceRenderer)
+	if envErr != nil {
+		finalErr = fmt.Errorf("environment resolution failed: %w", envErr)
+		return nil, finalErr
+	}
+	if environment := mergeEnvironment(module.Environment(instanceCtx), taskEnvironment); len(environment) > 0 {
+		instanceCtx = context.WithValue(instanceCtx, module.EnvironmentKey{}, environment)
 	}
-	pluginInstance := factory()
 
-	templateData := policyReader.GetAll()
-	for k, v := range loopScopeData {
-		templateData[k] = v
+	var pluginInstance plugin.Module
+	if task.IsInclude() {
+		pluginInstance = &includePlaybookModule{runner: r, task: task, iteration: loopIteration, renderer: taskInstanceRenderer, templateData: templateData}
+	} else {
+		factory, getErr := r.pluginRegistry.Get(task.Type)
+		if getErr != nil {
+			return nil, getErr
+		}
+		pluginInstance = factory()
 	}
 
 	renderedParams := make(map[string]interface{})
@@ -403,6 +477,22 @@ func (r *TaskRunner) executeSingleTaskInstance(
 		renderedParams[module.ProducerIDMapKey] = producerIDMap
 	}
 
+	// A task whose result is cached skips its module when an earlier run
+	// executed it with the same inputs. Nothing ran, so a restored summary
+	// never counts as changed and 'changed_when' is not evaluated.
+	var cacheKey string
+	if r.cacheEnabled(instanceCtx, task) {
+		key, keyErr := r.computeCacheKey(task, renderedParams, module.Environment(instanceCtx), templateData, taskInstanceRenderer)
+		if keyErr != nil {
+			finalErr = fmt.Errorf("cache key computation failed: %w", keyErr)
+			return nil, finalErr
+		}
+		if cached, hit := r.lookupCache(instanceCtx, task, key, taskLogger); hit {
+			return cached, nil
+		}
+		cacheKey = key
+	}
+
 	inputChansMap, _ := r.channelManager.GetInputChannelMap(task.InternalID)
 	_, managedOutputChansExist := r.channelManager.GetOutputManagedChannels(task.InternalID)
 
@@ -417,36 +507,71 @@ func (r *TaskRunner) executeSingleTaskInstance(
 
 	retryCfg := retry.Config{Attempts: task.GetRetryAttempts(), Delay: task.GetRetryDelay(), MaxDelay: task.GetRetryMaxDelay(), BackoffFactor: task.GetRetryBackoffFactor(), Jitter: task.GetRetryJitter(), OnError: task.ShouldRetryOnError(), TaskName: task.InternalID}
 
-	performErr := r.retryHelper.Do(instanceCtx, retryCfg, func(opCtx context.Context) error {
-		var performSpan oteltrace.Span
-		performCtx := opCtx
-		if !isNoopTracer {
-			performCtx, performSpan = tracer.Start(opCtx, "gxo.plugin.perform", oteltrace.WithAttributes(attribute.String("gxo.plugin.type", task.Type)))
-			defer performSpan.End()
-		}
+	runAttempt := func(attemptCtx context.Context) error {
+		// The summary of the last execution is kept even if it failed, for
+		// 'failed_when' to judge it once the retries are over.
+		var attemptSummary interface{}
+		doErr := r.retryHelper.Do(attemptCtx, retryCfg, func(opCtx context.Context) error {
+			var performSpan oteltrace.Span
+			performCtx := opCtx
+			if !isNoopTracer {
+				performCtx, performSpan = tracer.Start(opCtx, "gxo.plugin.perform", oteltrace.WithAttributes(attribute.String("gxo.plugin.type", task.Type)))
+				defer performSpan.End()
+			}
 
-		eventPayload := map[string]interface{}{"task_id": task.InternalID, "task_name": task.Name}
-		r.eventBus.Emit(events.Event{Type: events.ModuleExecutionStart, Timestamp: time.Now(), TaskName: task.Name, TaskID: task.InternalID, Payload: eventPayload})
+			eventPayload := map[string]interface{}{"task_id": task.InternalID, "task_name": task.Name}
+			r.eventBus.Emit(events.Event{Type: events.ModuleExecutionStart, Timestamp: time.Now(), TaskName: task.Name, TaskID: task.InternalID, Payload: eventPayload})
 
-		performSummary, performErr := pluginInstance.Perform(performCtx, renderedParams, policyReader, inputChansMap, rawOutputChans, moduleErrChan)
+			performSummary, performErr := pluginInstance.Perform(performCtx, renderedParams, policyReader, inputChansMap, rawOutputChans, moduleErrChan)
 
-		if performSpan != nil {
-			if performErr != nil {
-				intTracing.RecordErrorWithContext(performSpan, performErr, r.redactedKeywords)
-			} else {
-				performSpan.SetStatus(codes.Ok, "")
+			if performSpan != nil {
+				if performErr != nil {
+					intTracing.RecordErrorWithContext(performSpan, performErr, r.redactedKeywords)
+				} else {
+					performSpan.SetStatus(codes.Ok, "")
+				}
 			}
+
+			eventPayload["error"] = performErr
+			eventPayload["summary"] = performSummary
+			r.eventBus.Emit(events.Event{Type: events.ModuleExecutionEnd, Timestamp: time.Now(), TaskName: task.Name, TaskID: task.InternalID, Payload: eventPayload})
+
+			attemptSummary = performSummary
+			return performErr
+		})
+		// 'failed_when' decides the outcome of the final execution once, so that
+		// a result it fails is not retried; a cancelled run stays failed.
+		if task.FailedWhen != "" && attemptCtx.Err() == nil {
+			doErr = applyFailedWhen(task, taskLogger, templateData, taskInstanceRenderer, attemptSummary, doErr)
+		}
+		if doErr == nil {
+			summary = attemptSummary
 		}
+		return doErr
+	}
 
-		eventPayload["error"] = performErr
-		eventPayload["summary"] = performSummary
-		r.eventBus.Emit(events.Event{Type: events.ModuleExecutionEnd, Timestamp: time.Now(), TaskName: task.Name, TaskID: task.InternalID, Payload: eventPayload})
+	var performErr error
+	if task.Until != "" {
+		performErr = r.pollUntil(instanceCtx, task, taskLogger, templateData, taskInstanceRenderer, func(pollCtx context.Context) (interface{}, error) {
+			summary = nil
+			attemptErr := runAttempt(pollCtx)
+			return summary, attemptErr
+		})
+	} else {
+		performErr = runAttempt(instanceCtx)
+	}
 
-		if performErr == nil {
-			summary = performSummary
+	if performErr == nil {
+		changed, changedErr := evaluateChanged(task, templateData, taskInstanceRenderer, summary)
+		if changedErr != nil {
+			performErr = changedErr
+		} else if changed && r.recordChanged != nil {
+			r.recordChanged(task.InternalID)
 		}
-		return performErr
-	})
+	}
+	if performErr == nil && cacheKey != "" {
+		r.storeCache(task, cacheKey, summary, secretTracker, taskLogger)
+	}
 
 	if performErr == nil && managedOutputChansExist {
 		if wg, exists := r.channelManager.GetProducerWaitGroup(task.InternalID); exists {
@@ -480,7 +605,11 @@ func (r *TaskRunner) executeSingleTaskInstance(
 	return summary, finalErr
 }
 
-func (r *TaskRunner) resolveLoopItems(loopInput interface{}, state gxov1state.StateReader, renderer intTemplate.Renderer) ([]interface{}, error) {
+func (r *TaskRunner) resolveLoopItems(task *config.Task, data map[string]interface{}, renderer intTemplate.Renderer) ([]interface{}, error) {
+	if task.LoopMatrix != nil {
+		return expandLoopMatrix(task.LoopMatrix, data, renderer)
+	}
+	loopInput := task.Loop
 	if loopInput == nil {
 		return nil, nil
 	}
@@ -488,7 +617,7 @@ func (r *TaskRunner) resolveLoopItems(loopInput interface{}, state gxov1state.St
 	var err error
 
 	if loopStr, ok := loopInput.(string); ok {
-		items, err = renderer.Resolve(loopStr, state.GetAll())
+		items, err = renderer.Resolve(loopStr, data)
 		if err != nil {
 			return nil, fmt.Errorf("could not resolve loop variable expression '%s': %w", loopStr, err)
 		}
@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+
+	"github.com/gxo-labs/gxo/internal/config"
+	"github.com/gxo-labs/gxo/internal/template"
+)
+
+// renderTaskVars renders a task's vars against data, the playbook's state.
+// Each var is rendered on its own, so vars cannot refer to one another.
+func renderTaskVars(vars map[string]interface{}, data map[string]interface{}, renderer template.Renderer) (map[string]interface{}, error) {
+	if len(vars) == 0 {
+		return nil, nil
+	}
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	rendered := make(map[string]interface{}, len(vars))
+	for _, name := range names {
+		value, err := renderNested(vars[name], renderer, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render task var '%s': %w", name, err)
+		}
+		rendered[name] = value
+	}
+	return rendered, nil
+}
+
+// loopIndependentVars returns the vars that do not refer to loopVar, those
+// of a looped task that can be rendered before its loop variable is bound.
+func loopIndependentVars(vars map[string]interface{}, loopVar string, renderer template.Renderer) map[string]interface{} {
+	independent := make(map[string]interface{}, len(vars))
+	for name, value := range vars {
+		if !refersToVar(value, loopVar, renderer) {
+			independent[name] = value
+		}
+	}
+	return independent
+}
+
+// refersToVar reports whether a template found in value refers to the
+// variable name or to one of its fields.
+func refersToVar(value interface{}, name string, renderer template.Renderer) bool {
+	for _, tmpl := range config.TemplatesIn(value) {
+		variables, _ := renderer.ExtractVariables(tmpl)
+		for _, variable := range variables {
+			if variable == name || strings.HasPrefix(variable, name+".") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// renderEnvironment renders the values of an environment against data.
+func renderEnvironment(environment map[string]string, data map[string]interface{}, renderer template.Renderer) (map[string]string, error) {
+	if len(environment) == 0 {
+		return nil, nil
+	}
+	rendered := make(map[string]string, len(environment))
+	for name, value := range environment {
+		renderedValue, err := renderer.Render(value, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render environment variable '%s': %w", name, err)
+		}
+		rendered[name] = renderedValue
+	}
+	return rendered, nil
+}
+
+// mergeEnvironment returns the variables of base overridden by those of
+// override.
+func mergeEnvironment(base, override map[string]string) map[string]string {
+	if len(base) == 0 {
+		return override
+	}
+	merged := make(map[string]string, len(base)+len(override))
+	maps.Copy(merged, base)
+	maps.Copy(merged, override)
+	return merged
+}
@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gxo-labs/gxo/internal/config"
+	gxoerrors "github.com/gxo-labs/gxo/pkg/gxo/v1/errors"
+)
+
+// evaluateTriggerRule decides whether a task whose dependencies have finished
+// may run. It returns a skipped error if the task's trigger rule is not
+// satisfied or, for rescue tasks, if no task in the block body failed. A task
+// skipped because a dependency failed is marked as such, so that the failure
+// keeps propagating to its own dependents. A dependency left out by the task
+// selection whose registered value is reused counts as completed. The caller
+// must hold statusMu.
+func (r *playbookRun) evaluateTriggerRule(node *Node) error {
+	if node.IsBlock() {
+		return nil
+	}
+
+	var completed, failed, skipped int
+	for depID, dep := range node.StateDependsOn {
+		if node.blockDeps[depID] {
+			continue
+		}
+		switch status := r.taskStatuses[depID]; {
+		case status == StatusFailed || dep.upstreamFailed.Load():
+			failed++
+		case status == StatusCompleted || r.reusedTasks[depID]:
+			completed++
+		default:
+			skipped++
+		}
+	}
+
+	if total := completed + failed + skipped; total > 0 {
+		rule := node.Task.GetTriggerRule()
+		var satisfied bool
+		switch rule {
+		case config.TriggerRuleAllSuccess:
+			satisfied = completed == total
+		case config.TriggerRuleAllDone:
+			satisfied = true
+		case config.TriggerRuleOneFailed:
+			satisfied = failed > 0
+		case config.TriggerRuleOneSuccess:
+			satisfied = completed > 0
+		default:
+			satisfied = failed == 0
+		}
+		if !satisfied {
+			if failed > 0 && rule != config.TriggerRuleOneFailed {
+				node.upstreamFailed.Store(true)
+			}
+			return gxoerrors.NewSkippedError(fmt.Sprintf("trigger_rule '%s' not satisfied (dependencies: %d completed, %d failed, %d skipped)", rule, completed, failed, skipped))
+		}
+	}
+
+	if node.Role == RoleRescue && r.firstFailedMember(node.Block.BlockBody) == nil {
+		return gxoerrors.NewSkippedError(fmt.Sprintf("no task in block '%s' failed", node.Block.DisplayName()))
+	}
+	return nil
+}
+
+// handlesFailure reports whether a task still runs after a failure halted the
+// run, because its trigger rule lets it react to failed dependencies.
+func handlesFailure(node *Node) bool {
+	if node.IsBlock() || node.Task == nil {
+		return false
+	}
+	rule := node.Task.GetTriggerRule()
+	return rule == config.TriggerRuleOneFailed || rule == config.TriggerRuleAllDone
+}
+
+// haltedSkipError returns the reason a task is skipped once a failure halted
+// the run.
+func (r *playbookRun) haltedSkipError() error {
+	r.failureMu.Lock()
+	failedTask := r.firstFailedTask
+	r.failureMu.Unlock()
+	if failedTask == "" {
+		return gxoerrors.NewSkippedError("run halted after a task failed")
+	}
+	return gxoerrors.NewSkippedError(fmt.Sprintf("run halted after task '%s' failed", failedTask))
+}
+
+// skipWithoutRunning marks a ready task as Skipped without dispatching it. Its
+// output streams are closed and its stream consumers released, so they see an
+// empty stream rather than waiting forever, and, as a consumer, it stops
+// holding up its producers.
+func (r *playbookRun) skipWithoutRunning(ctx context.Context, node *Node, skipErr error, fatalErrChan chan<- error) {
+	r.log.Infof("Task %s skipped: %v", node.ID, skipErr)
+	if _, exists := r.channelManager.GetOutputManagedChannels(node.ID); exists {
+		r.channelManager.CloseOutputChannels(node.ID)
+	}
+	if producerWgs, exists := r.channelManager.GetConsumerProducerWaitGroups(node.ID); exists {
+		for _, wg := range producerWgs {
+			wg.Done()
+		}
+	}
+	r.signalStreamDependents(node)
+	r.handleTaskCompletion(ctx, node.ID, StatusSkipped, skipErr, fatalErrChan, false)
+}
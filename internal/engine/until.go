@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gxo-labs/gxo/internal/config"
+	intTemplate "github.com/gxo-labs/gxo/internal/template"
+	"github.com/gxo-labs/gxo/pkg/gxo/v1/events"
+	gxolog "github.com/gxo-labs/gxo/pkg/gxo/v1/log"
+)
+
+// pollUntil runs attempt until the task's 'until' condition, rendered with the
+// attempt's summary as '.result', is true. It waits 'until_control.delay'
+// between attempts and gives up after 'until_control.max_attempts' attempts or
+// once 'until_control.timeout' has passed. An attempt that fails ends the
+// polling with its error; errors are left to 'retry'.
+func (r *TaskRunner) pollUntil(
+	ctx context.Context,
+	task *config.Task,
+	taskLogger gxolog.Logger,
+	templateData map[string]interface{},
+	renderer intTemplate.Renderer,
+	attempt func(context.Context) (interface{}, error),
+) error {
+	maxAttempts := task.GetUntilMaxAttempts()
+	delay := task.GetUntilDelay()
+	timeout := task.GetUntilTimeout()
+
+	pollCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		pollCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	deadlineErr := func(attempts int, last string) error {
+		return fmt.Errorf("'until' condition did not become true within %v (%d attempts, last result: [%s])", timeout, attempts, last)
+	}
+
+	var lastResult string
+	for attemptNum := 1; ; attemptNum++ {
+		summary, err := attempt(pollCtx)
+		if err != nil {
+			if ctx.Err() == nil && pollCtx.Err() != nil {
+				return deadlineErr(attemptNum-1, lastResult)
+			}
+			return err
+		}
+
+		lastResult, err = renderer.Render(task.Until, resultConditionData(templateData, summary))
+		if err != nil {
+			return fmt.Errorf("failed to evaluate 'until' condition on attempt %d: %w", attemptNum, err)
+		}
+		conditionMet := evaluateConditionString(lastResult)
+		r.eventBus.Emit(events.Event{Type: events.TaskPolled, Timestamp: time.Now(), TaskName: task.Name, TaskID: task.InternalID, Payload: map[string]interface{}{
+			"task_id":       task.InternalID,
+			"task_name":     task.Name,
+			"attempt":       attemptNum,
+			"max_attempts":  maxAttempts,
+			"condition_met": conditionMet,
+		}})
+
+		if conditionMet {
+			if attemptNum > 1 {
+				taskLogger.Infof("'until' condition became true on attempt %d/%d", attemptNum, maxAttempts)
+			}
+			return nil
+		}
+		if attemptNum >= maxAttempts {
+			return fmt.Errorf("'until' condition never became true after %d attempts (last result: [%s])", attemptNum, lastResult)
+		}
+
+		taskLogger.Infof("'until' condition not met on attempt %d/%d (result: [%s]), polling again in %v", attemptNum, maxAttempts, lastResult, delay)
+		select {
+		case <-time.After(delay):
+		case <-pollCtx.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return deadlineErr(attemptNum, lastResult)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Package graph describes the execution DAG the engine infers for a playbook,
+// and renders it as DOT, Mermaid or JSON without executing anything.
+package graph
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gxo-labs/gxo/internal/config"
+	"github.com/gxo-labs/gxo/internal/engine"
+	"github.com/gxo-labs/gxo/internal/state"
+	"github.com/gxo-labs/gxo/internal/template"
+)
+
+// Graph is a serializable description of a playbook's execution DAGs, one per
+// section.
+type Graph struct {
+	Playbook string `json:"playbook"`
+	Nodes    []Node `json:"nodes"`
+	Edges    []Edge `json:"edges"`
+}
+
+// Node describes a single task together with its resolved policies.
+type Node struct {
+	ID          string `json:"id"`
+	Name        string `json:"name,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Section     string `json:"section"`
+	Role        string `json:"role"`
+	Block       string `json:"block,omitempty"`
+	IsBlock     bool   `json:"is_block,omitempty"`
+	TriggerRule string `json:"trigger_rule,omitempty"`
+	// SkipOnNoInput is the resolved TaskPolicy.
+	SkipOnNoInput bool `json:"skip_on_no_input"`
+	// StateAccessMode is the resolved StatePolicy.
+	StateAccessMode string `json:"state_access_mode"`
+}
+
+// Label returns the task's name, or its ID if it is unnamed.
+func (n Node) Label() string {
+	if n.Name != "" {
+		return n.Name
+	}
+	return n.ID
+}
+
+// Edge is a dependency of task To on task From. Kind is one of
+// config.EdgeState, config.EdgeStream or config.EdgeBlock; Explicit marks
+// state edges declared through 'depends_on'.
+type Edge struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Kind     string `json:"kind"`
+	Explicit bool   `json:"explicit,omitempty"`
+}
+
+// Build builds the DAG of every section of a loaded playbook, exactly as the
+// engine would before a run, and describes it. Nodes are listed in playbook
+// order and edges in the order of their consumers.
+func Build(playbook *config.Playbook) (*Graph, error) {
+	g := &Graph{Playbook: playbook.Name, Nodes: []Node{}, Edges: []Edge{}}
+	renderer := template.NewGoRenderer(nil, nil, nil)
+
+	for _, section := range []string{config.SectionTasks, config.SectionOnFailure, config.SectionFinally} {
+		tasks := config.FlattenTasks(playbook.SectionTasks(section))
+		if len(tasks) == 0 {
+			continue
+		}
+		dag, _, err := engine.BuildSectionDAG(playbook, section, state.NewMemoryStateStore(), renderer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build DAG for '%s' section: %w", section, err)
+		}
+
+		order := make(map[string]int, len(tasks))
+		for i, task := range tasks {
+			order[task.InternalID] = i
+		}
+		for _, task := range tasks {
+			dagNode := dag.Nodes[task.InternalID]
+			g.Nodes = append(g.Nodes, describeNode(dagNode))
+
+			var edges []Edge
+			for producerID := range dagNode.StreamDependsOn {
+				edges = append(edges, Edge{From: producerID, To: dagNode.ID, Kind: config.EdgeStream})
+			}
+			for producerID := range dagNode.StateDependsOn {
+				edge := Edge{From: producerID, To: dagNode.ID, Kind: config.EdgeState}
+				if dagNode.IsBlockDependency(producerID) {
+					edge.Kind = config.EdgeBlock
+				}
+				_, edge.Explicit = dagNode.ExplicitDependsOn[producerID]
+				edges = append(edges, edge)
+			}
+			sort.Slice(edges, func(i, j int) bool {
+				if order[edges[i].From] != order[edges[j].From] {
+					return order[edges[i].From] < order[edges[j].From]
+				}
+				return edges[i].Kind < edges[j].Kind
+			})
+			g.Edges = append(g.Edges, edges...)
+		}
+	}
+	return g, nil
+}
+
+func describeNode(dagNode *engine.Node) Node {
+	node := Node{
+		ID:      dagNode.ID,
+		Name:    dagNode.Task.Name,
+		Type:    dagNode.Task.Type,
+		Section: dagNode.Section,
+		Role:    string(dagNode.Role),
+		IsBlock: dagNode.IsBlock(),
+	}
+	if !node.IsBlock {
+		node.TriggerRule = dagNode.Task.GetTriggerRule()
+	}
+	if dagNode.Block != nil {
+		node.Block = dagNode.Block.ID
+	}
+	if dagNode.TaskPolicy != nil && dagNode.TaskPolicy.SkipOnNoInput != nil {
+		node.SkipOnNoInput = *dagNode.TaskPolicy.SkipOnNoInput
+	}
+	if dagNode.StatePolicy != nil {
+		node.StateAccessMode = string(dagNode.StatePolicy.AccessMode)
+	}
+	return node
+}
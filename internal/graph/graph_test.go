@@ -0,0 +1,116 @@
+package graph_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/gxo-labs/gxo/internal/config"
+	"github.com/gxo-labs/gxo/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const graphPlaybookYAML = `
+schemaVersion: "v1.0.0"
+name: graph_test
+state_policy:
+  access_mode: deep_copy
+tasks:
+  - name: produce
+    type: mock
+    register: produced
+  - name: consume
+    type: mock
+    stream_inputs: [produce]
+    state_policy:
+      access_mode: unsafe_direct_reference
+  - name: report
+    type: mock
+    params:
+      data: "{{ .produced }}"
+    trigger_rule: all_done
+  - name: deploy
+    block:
+      - name: rollout
+        type: mock
+        depends_on: [report]
+    rescue:
+      - name: rollback
+        type: mock
+finally:
+  - name: cleanup
+    type: mock
+`
+
+func buildTestGraph(t *testing.T) *graph.Graph {
+	t.Helper()
+	playbook, err := config.LoadPlaybook([]byte(graphPlaybookYAML), "graph_test.yaml")
+	require.NoError(t, err)
+	g, err := graph.Build(playbook)
+	require.NoError(t, err)
+	return g
+}
+
+// TestBuild_NodesAndEdges verifies that every task, including block members and
+// later sections, is described with its resolved policies and dependencies.
+func TestBuild_NodesAndEdges(t *testing.T) {
+	g := buildTestGraph(t)
+
+	nodes := make(map[string]graph.Node)
+	var order []string
+	for _, n := range g.Nodes {
+		nodes[n.ID] = n
+		order = append(order, n.ID)
+	}
+	assert.Equal(t, []string{"produce", "consume", "report", "deploy", "rollout", "rollback", "cleanup"}, order)
+
+	assert.Equal(t, "deep_copy", nodes["produce"].StateAccessMode)
+	assert.Equal(t, "unsafe_direct_reference", nodes["consume"].StateAccessMode)
+	assert.Equal(t, config.TriggerRuleAllDone, nodes["report"].TriggerRule)
+	assert.Equal(t, config.TriggerRuleNoneFailed, nodes["produce"].TriggerRule)
+	assert.True(t, nodes["deploy"].IsBlock)
+	assert.Empty(t, nodes["deploy"].TriggerRule)
+	assert.Equal(t, "deploy", nodes["rollback"].Block)
+	assert.Equal(t, "rescue", nodes["rollback"].Role)
+	assert.Equal(t, config.SectionFinally, nodes["cleanup"].Section)
+
+	assert.Contains(t, g.Edges, graph.Edge{From: "produce", To: "consume", Kind: config.EdgeStream})
+	assert.Contains(t, g.Edges, graph.Edge{From: "produce", To: "report", Kind: config.EdgeState})
+	assert.Contains(t, g.Edges, graph.Edge{From: "report", To: "rollout", Kind: config.EdgeState, Explicit: true})
+	assert.Contains(t, g.Edges, graph.Edge{From: "rollout", To: "rollback", Kind: config.EdgeBlock})
+	assert.Contains(t, g.Edges, graph.Edge{From: "rollback", To: "deploy", Kind: config.EdgeBlock})
+}
+
+// TestRender_Formats verifies the DOT, Mermaid and JSON renderings.
+func TestRender_Formats(t *testing.T) {
+	g := buildTestGraph(t)
+
+	var dot bytes.Buffer
+	require.NoError(t, graph.Render(&dot, g, graph.FormatDOT))
+	assert.Contains(t, dot.String(), `digraph "graph_test" {`)
+	assert.Contains(t, dot.String(), `"produce" -> "consume" [label="stream", style=dashed];`)
+	assert.Contains(t, dot.String(), `"report" -> "rollout" [label="depends_on", style=solid];`)
+	assert.Contains(t, dot.String(), `subgraph "cluster_finally" {`)
+	assert.Contains(t, dot.String(), `"deploy" [label="deploy\nblock", shape=diamond];`)
+
+	var mermaid bytes.Buffer
+	require.NoError(t, graph.Render(&mermaid, g, graph.FormatMermaid))
+	assert.Contains(t, mermaid.String(), "flowchart LR\n")
+	assert.Contains(t, mermaid.String(), "  n0 -.->|stream| n1\n")
+	assert.Contains(t, mermaid.String(), "  n4 ==>|block| n5\n")
+	assert.Contains(t, mermaid.String(), "  subgraph finally\n")
+
+	var jsonOut bytes.Buffer
+	require.NoError(t, graph.Render(&jsonOut, g, graph.FormatJSON))
+	var decoded graph.Graph
+	require.NoError(t, json.Unmarshal(jsonOut.Bytes(), &decoded))
+	assert.Equal(t, *g, decoded)
+}
+
+func TestRender_UnsupportedFormat(t *testing.T) {
+	g := buildTestGraph(t)
+	err := graph.Render(&bytes.Buffer{}, g, "svg")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported graph format 'svg'")
+}
@@ -0,0 +1,161 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gxo-labs/gxo/internal/config"
+)
+
+// Supported output formats.
+const (
+	FormatDOT     = "dot"
+	FormatMermaid = "mermaid"
+	FormatJSON    = "json"
+)
+
+// Render writes the graph to w in the given format.
+func Render(w io.Writer, g *Graph, format string) error {
+	switch format {
+	case FormatDOT:
+		return renderDOT(w, g)
+	case FormatMermaid:
+		return renderMermaid(w, g)
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(g)
+	default:
+		return fmt.Errorf("unsupported graph format '%s' (supported: %s, %s, %s)", format, FormatDOT, FormatMermaid, FormatJSON)
+	}
+}
+
+// nodeDetails returns the lines describing a node below its label.
+func nodeDetails(n Node) []string {
+	if n.IsBlock {
+		return []string{"block"}
+	}
+	details := []string{n.Type}
+	if n.Role != "" && n.Role != "task" {
+		details = append(details, "role: "+n.Role)
+	}
+	details = append(details,
+		"trigger_rule: "+n.TriggerRule,
+		fmt.Sprintf("skip_on_no_input: %t", n.SkipOnNoInput),
+		"state_access: "+n.StateAccessMode,
+	)
+	return details
+}
+
+// edgeLabel returns the label shown on an edge.
+func edgeLabel(e Edge) string {
+	if e.Explicit {
+		return "depends_on"
+	}
+	return e.Kind
+}
+
+func renderDOT(w io.Writer, g *Graph) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "digraph %s {\n", dotQuote(g.Playbook))
+	sb.WriteString("  rankdir=LR;\n")
+	sb.WriteString("  node [shape=box, fontname=\"Helvetica\"];\n")
+
+	section := ""
+	for _, n := range g.Nodes {
+		if n.Section != section {
+			if section != "" && section != config.SectionTasks {
+				sb.WriteString("  }\n")
+			}
+			section = n.Section
+			if section != config.SectionTasks {
+				fmt.Fprintf(&sb, "  subgraph %s {\n    label=%s;\n    style=dashed;\n", dotQuote("cluster_"+section), dotQuote(section))
+			}
+		}
+		indent := "  "
+		if section != config.SectionTasks {
+			indent = "    "
+		}
+		label := strings.Join(append([]string{n.Label()}, nodeDetails(n)...), "\n")
+		shape := "box"
+		if n.IsBlock {
+			shape = "diamond"
+		}
+		fmt.Fprintf(&sb, "%s%s [label=%s, shape=%s];\n", indent, dotQuote(n.ID), dotQuote(label), shape)
+	}
+	if section != "" && section != config.SectionTasks {
+		sb.WriteString("  }\n")
+	}
+
+	for _, e := range g.Edges {
+		style := "solid"
+		switch e.Kind {
+		case config.EdgeStream:
+			style = "dashed"
+		case config.EdgeBlock:
+			style = "dotted"
+		}
+		fmt.Fprintf(&sb, "  %s -> %s [label=%s, style=%s];\n", dotQuote(e.From), dotQuote(e.To), dotQuote(edgeLabel(e)), style)
+	}
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// dotQuote quotes a string as a DOT identifier.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+func renderMermaid(w io.Writer, g *Graph) error {
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+
+	// Mermaid node IDs must be simple identifiers, so nodes are numbered.
+	ids := make(map[string]string, len(g.Nodes))
+	section := ""
+	for i, n := range g.Nodes {
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+		if n.Section != section {
+			if section != "" && section != config.SectionTasks {
+				sb.WriteString("  end\n")
+			}
+			section = n.Section
+			if section != config.SectionTasks {
+				fmt.Fprintf(&sb, "  subgraph %s\n", section)
+			}
+		}
+		label := strings.Join(append([]string{n.Label()}, nodeDetails(n)...), "<br/>")
+		if n.IsBlock {
+			fmt.Fprintf(&sb, "  %s{%s}\n", ids[n.ID], mermaidQuote(label))
+		} else {
+			fmt.Fprintf(&sb, "  %s[%s]\n", ids[n.ID], mermaidQuote(label))
+		}
+	}
+	if section != "" && section != config.SectionTasks {
+		sb.WriteString("  end\n")
+	}
+
+	for _, e := range g.Edges {
+		arrow := "-->"
+		switch e.Kind {
+		case config.EdgeStream:
+			arrow = "-.->"
+		case config.EdgeBlock:
+			arrow = "==>"
+		}
+		fmt.Fprintf(&sb, "  %s %s|%s| %s\n", ids[e.From], arrow, edgeLabel(e), ids[e.To])
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// mermaidQuote quotes a node label, escaping characters Mermaid would parse.
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
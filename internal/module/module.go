@@ -1,6 +1,8 @@
 package module
 
 import (
+	"context"
+
 	"github.com/gxo-labs/gxo/internal/config"
 	gxolog "github.com/gxo-labs/gxo/pkg/gxo/v1/log"
 	gxov1state "github.com/gxo-labs/gxo/pkg/gxo/v1/state"
@@ -11,6 +13,21 @@ import (
 // provided to their Perform method to determine if they should simulate actions.
 type DryRunKey struct{}
 
+// EnvironmentKey is the context key under which modules receive the
+// environment variables of their task, as a map[string]string. It merges, in
+// increasing precedence, the variables of the playbook, of the enclosing
+// blocks and of the task itself; a task of an included playbook also gets
+// those of the including task. Modules running processes should add them to
+// the process's environment.
+type EnvironmentKey struct{}
+
+// Environment returns the environment variables passed to a module through its
+// context, or nil if there are none.
+func Environment(ctx context.Context) map[string]string {
+	environment, _ := ctx.Value(EnvironmentKey{}).(map[string]string)
+	return environment
+}
+
 // ProducerIDMapKey is the key used in a module's parameters to pass the
 // mapping of producer task names to their internal IDs. This allows streaming
 // modules to correctly identify their input channels.
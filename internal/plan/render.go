@@ -0,0 +1,79 @@
+// Package plan renders the execution preview built by engine.BuildPlan.
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gxo-labs/gxo/internal/engine"
+)
+
+// Supported output formats.
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+)
+
+// Render writes the plan to w in the given format.
+func Render(w io.Writer, p *engine.Plan, format string) error {
+	switch format {
+	case FormatTable:
+		return renderTable(w, p)
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(p)
+	default:
+		return fmt.Errorf("unsupported plan format '%s' (supported: %s, %s)", format, FormatTable, FormatJSON)
+	}
+}
+
+func renderTable(w io.Writer, p *engine.Plan) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Playbook: %s\nWorkers:  %d\n", p.Playbook, p.WorkerPoolSize)
+	for _, section := range p.Sections {
+		fmt.Fprintf(&sb, "\nSection: %s\n", section.Section)
+		tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "WAVE\tTASK\tTYPE\tBLOCK\tWHEN\tLOOP")
+		for _, wave := range section.Waves {
+			for _, task := range wave.Tasks {
+				taskType := task.Type
+				if task.IsBlock {
+					taskType = "block"
+				}
+				fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n",
+					wave.Index, task.DisplayName(), taskType, orDash(task.Block), orDash(task.When), describeLoop(task.Loop))
+			}
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(&sb, "Critical path (length %d): %s\n", len(section.CriticalPath), strings.Join(section.CriticalPath, " -> "))
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// describeLoop summarizes how far a loop fans out.
+func describeLoop(loop *engine.LoopPlan) string {
+	switch {
+	case loop == nil:
+		return "-"
+	case loop.Error != "":
+		return "error: " + loop.Error
+	case !loop.Static:
+		return fmt.Sprintf("dynamic (parallel %d)", loop.Parallel)
+	default:
+		return fmt.Sprintf("%d items (parallel %d)", loop.Items, loop.Parallel)
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,58 @@
+package plan_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/gxo-labs/gxo/internal/engine"
+	"github.com/gxo-labs/gxo/internal/plan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPlan() *engine.Plan {
+	return &engine.Plan{
+		Playbook:       "render_test",
+		WorkerPoolSize: 2,
+		Sections: []engine.SectionPlan{{
+			Section: "tasks",
+			Waves: []engine.PlanWave{
+				{Index: 1, Tasks: []engine.PlannedTask{
+					{ID: "fetch", Name: "fetch", Type: "exec"},
+					{ID: "build", Name: "build", Type: "exec", Loop: &engine.LoopPlan{Static: true, Items: 3, Parallel: 2}},
+				}},
+				{Index: 2, Tasks: []engine.PlannedTask{
+					{ID: "deploy", Name: "deploy", Type: "exec", When: engine.PlanWhenFalse, Loop: &engine.LoopPlan{Parallel: 1}},
+				}},
+			},
+			CriticalPath: []string{"build", "deploy"},
+		}},
+	}
+}
+
+func TestRender_Table(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, plan.Render(&out, testPlan(), plan.FormatTable))
+
+	assert.Contains(t, out.String(), "Playbook: render_test\nWorkers:  2\n")
+	assert.Contains(t, out.String(), "Section: tasks\n")
+	assert.Regexp(t, `1\s+build\s+exec\s+-\s+-\s+3 items \(parallel 2\)`, out.String())
+	assert.Regexp(t, `2\s+deploy\s+exec\s+-\s+false\s+dynamic \(parallel 1\)`, out.String())
+	assert.Contains(t, out.String(), "Critical path (length 2): build -> deploy\n")
+}
+
+func TestRender_JSON(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, plan.Render(&out, testPlan(), plan.FormatJSON))
+
+	var decoded engine.Plan
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, *testPlan(), decoded)
+}
+
+func TestRender_UnsupportedFormat(t *testing.T) {
+	err := plan.Render(&bytes.Buffer{}, testPlan(), "yaml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported plan format 'yaml'")
+}
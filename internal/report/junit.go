@@ -0,0 +1,166 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	gxo "github.com/gxo-labs/gxo/pkg/gxo/v1"
+)
+
+// junitTestSuites is the root element of a JUnit XML report.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite holds the test cases of one playbook run.
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// junitTestCase is a task, or one iteration of a looped task.
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// runTestCaseName names the test case that carries the failure of a run whose
+// tasks all passed, such as a failure to render its outputs.
+const runTestCaseName = "(playbook run)"
+
+// writeJUnit writes the report as JUnit XML. Each task is a test case, each
+// iteration of a looped task is one, and each playbook included by a task gets
+// a test suite of its own. Blocks are left out: their members are reported.
+func writeJUnit(w io.Writer, report *gxo.ExecutionReport) error {
+	suites := junitTestSuites{Name: report.PlaybookName, Time: seconds(report.Duration)}
+	appendJUnitSuites(&suites, report, report.PlaybookName)
+	for _, suite := range suites.Suites {
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// appendJUnitSuites adds the suite of a report, named name, followed by the
+// suites of the playbooks it included.
+func appendJUnitSuites(suites *junitTestSuites, report *gxo.ExecutionReport, name string) {
+	suite := junitTestSuite{
+		Name: name,
+		Time: seconds(report.Duration),
+		Properties: []junitProperty{
+			{Name: "run_id", Value: report.RunID},
+			{Name: "overall_status", Value: report.OverallStatus},
+		},
+	}
+	if !report.StartTime.IsZero() {
+		suite.Timestamp = report.StartTime.UTC().Format("2006-01-02T15:04:05")
+	}
+
+	blocks := make(map[string]bool)
+	for _, result := range report.TaskResults {
+		if result.Block != "" {
+			blocks[result.Block] = true
+		}
+	}
+
+	var nested []string
+	for _, id := range sortedTaskIDs(report) {
+		result := report.TaskResults[id]
+		if blocks[id] {
+			continue
+		}
+		className := report.PlaybookName
+		if result.Section != "" {
+			className += "." + result.Section
+		}
+		if result.Block != "" {
+			className += "." + result.Block
+		}
+		failuresBefore := suite.Failures
+		for _, iteration := range result.Iterations {
+			suite.addCase(className, iterationCaseName(id, iteration), iteration.Status, iteration.Error, iteration.Duration)
+		}
+		// A looped task also fails on its own, e.g. when its loop cannot be
+		// resolved, and then gets a test case carrying that failure.
+		if len(result.Iterations) == 0 || (result.Status == "Failed" && suite.Failures == failuresBefore) {
+			suite.addCase(className, id, result.Status, result.Error, result.Duration)
+		}
+		if len(result.NestedReports) > 0 {
+			nested = append(nested, id)
+		}
+	}
+	if report.OverallStatus == "Failed" && suite.Failures == 0 {
+		suite.addCase(report.PlaybookName, runTestCaseName, "Failed", report.Error, report.Duration)
+	}
+	suites.Suites = append(suites.Suites, suite)
+
+	for _, id := range nested {
+		nestedReports := report.TaskResults[id].NestedReports
+		for i, nestedReport := range nestedReports {
+			nestedName := name + "/" + id
+			if len(nestedReports) > 1 {
+				nestedName = fmt.Sprintf("%s[%d]", nestedName, i)
+			}
+			appendJUnitSuites(suites, nestedReport, nestedName)
+		}
+	}
+}
+
+// addCase adds a test case with the given outcome. Tasks left pending or
+// running when the run ended count as failures, as they do in the report.
+func (s *junitTestSuite) addCase(className, name, status, message string, duration time.Duration) {
+	testCase := junitTestCase{ClassName: className, Name: name, Time: seconds(duration)}
+	switch status {
+	case "Completed":
+	case "Skipped":
+		testCase.Skipped = &junitMessage{Message: message}
+		s.Skipped++
+	default:
+		if message == "" {
+			message = "Task " + status
+		}
+		testCase.Failure = &junitMessage{Message: message, Type: status, Text: message}
+		s.Failures++
+	}
+	s.Tests++
+	s.Cases = append(s.Cases, testCase)
+}
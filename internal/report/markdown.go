@@ -0,0 +1,86 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	gxo "github.com/gxo-labs/gxo/pkg/gxo/v1"
+)
+
+// writeMarkdown writes the report as a Markdown summary, suited to CI job
+// summaries and pull request comments.
+func writeMarkdown(w io.Writer, report *gxo.ExecutionReport) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Playbook `%s`: %s\n\n", report.PlaybookName, report.OverallStatus)
+	sb.WriteString("| Run ID | Duration | Tasks | Completed | Failed | Skipped | Changed |\n")
+	sb.WriteString("|---|---|---|---|---|---|---|\n")
+	fmt.Fprintf(&sb, "| %s | %ss | %d | %d | %d | %d | %d |\n",
+		markdownCell(report.RunID), seconds(report.Duration), report.TotalTasks, report.CompletedTasks, report.FailedTasks, report.SkippedTasks, report.ChangedTasks)
+	if report.CacheHits > 0 || report.CacheMisses > 0 {
+		fmt.Fprintf(&sb, "\n**Cache:** %d hits, %d misses\n", report.CacheHits, report.CacheMisses)
+	}
+	if report.Error != "" {
+		fmt.Fprintf(&sb, "\n**Error:** %s\n", markdownCell(report.Error))
+	}
+
+	if len(report.TaskResults) > 0 {
+		sb.WriteString("\n## Tasks\n\n")
+		sb.WriteString("| Task | Status | Duration | Details |\n")
+		sb.WriteString("|---|---|---|---|\n")
+		for _, id := range sortedTaskIDs(report) {
+			result := report.TaskResults[id]
+			fmt.Fprintf(&sb, "| `%s` | %s | %ss | %s |\n", id, result.Status, seconds(result.Duration), markdownCell(taskDetails(result)))
+			for _, iteration := range result.Iterations {
+				fmt.Fprintf(&sb, "| `%s` | %s | %ss | %s |\n", iterationCaseName(id, iteration), iteration.Status, seconds(iteration.Duration), markdownCell(iteration.Error))
+			}
+		}
+	}
+
+	if len(report.Outputs) > 0 {
+		outputs, err := json.MarshalIndent(report.Outputs, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode outputs: %w", err)
+		}
+		fmt.Fprintf(&sb, "\n## Outputs\n\n```json\n%s\n```\n", outputs)
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// taskDetails describes where a task sits and why it did not complete.
+func taskDetails(result gxo.TaskResult) string {
+	var details []string
+	if result.Changed {
+		details = append(details, "changed")
+	}
+	if result.CacheHits > 0 || result.CacheMisses > 0 {
+		details = append(details, fmt.Sprintf("cache: %d hits, %d misses", result.CacheHits, result.CacheMisses))
+	}
+	if result.Section != "" {
+		details = append(details, "section: "+result.Section)
+	}
+	if result.Block != "" {
+		details = append(details, "block: "+result.Block)
+	}
+	if counts := result.IterationCounts; counts != nil {
+		details = append(details, fmt.Sprintf("iterations: %d completed, %d failed, %d skipped", counts.Completed, counts.Failed, counts.Skipped))
+	}
+	for _, nested := range result.NestedReports {
+		details = append(details, fmt.Sprintf("included playbook %s: %s", nested.PlaybookName, nested.OverallStatus))
+	}
+	if result.Error != "" {
+		details = append(details, result.Error)
+	}
+	return strings.Join(details, "; ")
+}
+
+// markdownCell escapes text for a table cell, which must be a single line
+// without unescaped pipes.
+func markdownCell(text string) string {
+	text = strings.ReplaceAll(text, "|", `\|`)
+	text = strings.ReplaceAll(text, "\r\n", "<br>")
+	return strings.ReplaceAll(text, "\n", "<br>")
+}
@@ -0,0 +1,93 @@
+// Package report exports execution reports for other tools: as versioned
+// JSON, as JUnit XML for CI systems, or as Markdown for humans.
+package report
+
+import (
+	_ "embed" // Required for //go:embed directive
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	gxo "github.com/gxo-labs/gxo/pkg/gxo/v1"
+)
+
+// Supported output formats.
+const (
+	FormatJSON     = "json"
+	FormatJUnit    = "junit"
+	FormatMarkdown = "markdown"
+)
+
+// SchemaVersion is the version of the JSON report's schema, recorded in every
+// JSON report.
+const SchemaVersion = "v1.0.0"
+
+// Schema is the JSON Schema of the JSON report.
+//
+//go:embed gxo_report_schema_v1.0.0.json
+var Schema []byte
+
+// Document is the JSON report: the execution report tagged with the version
+// of its schema.
+type Document struct {
+	SchemaVersion string `json:"schema_version"`
+	*gxo.ExecutionReport
+}
+
+// IsSupportedFormat reports whether format is one Write supports.
+func IsSupportedFormat(format string) bool {
+	return format == FormatJSON || format == FormatJUnit || format == FormatMarkdown
+}
+
+// Write writes the report to w in the given format. Task errors in the report
+// are already redacted by the engine.
+func Write(w io.Writer, report *gxo.ExecutionReport, format string) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(Document{SchemaVersion: SchemaVersion, ExecutionReport: report})
+	case FormatJUnit:
+		return writeJUnit(w, report)
+	case FormatMarkdown:
+		return writeMarkdown(w, report)
+	default:
+		return fmt.Errorf("unsupported report format '%s' (supported: %s, %s, %s)", format, FormatJSON, FormatJUnit, FormatMarkdown)
+	}
+}
+
+// sortedTaskIDs returns the IDs of the report's tasks in the order they
+// started; tasks that never started come last. Ties are broken by ID.
+func sortedTaskIDs(report *gxo.ExecutionReport) []string {
+	ids := make([]string, 0, len(report.TaskResults))
+	for id := range report.TaskResults {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := report.TaskResults[ids[i]].StartTime, report.TaskResults[ids[j]].StartTime
+		if a.IsZero() != b.IsZero() {
+			return b.IsZero()
+		}
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// seconds formats a duration in seconds, as JUnit and Markdown show it.
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// iterationCaseName names an iteration of a task, by its matrix values when it
+// has them and by its index otherwise.
+func iterationCaseName(taskID string, iteration gxo.IterationResult) string {
+	if iteration.Name != "" {
+		return fmt.Sprintf("%s[%s]", taskID, iteration.Name)
+	}
+	return fmt.Sprintf("%s[%d]", taskID, iteration.Index)
+}
@@ -0,0 +1,143 @@
+package report_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/gxo-labs/gxo/internal/report"
+	gxo "github.com/gxo-labs/gxo/pkg/gxo/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+func testReport() *gxo.ExecutionReport {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return &gxo.ExecutionReport{
+		RunID:          "run-1",
+		PlaybookName:   "deploy",
+		OverallStatus:  "Failed",
+		StartTime:      start,
+		EndTime:        start.Add(3 * time.Second),
+		Duration:       3 * time.Second,
+		TotalTasks:     4,
+		CompletedTasks: 2,
+		FailedTasks:    1,
+		SkippedTasks:   1,
+		ChangedTasks:   1,
+		CacheHits:      1,
+		CacheMisses:    1,
+		Error:          "task 'push' failed",
+		TaskResults: map[string]gxo.TaskResult{
+			"build": {Status: "Completed", Changed: true, CacheMisses: 1, StartTime: start, EndTime: start.Add(time.Second), Duration: time.Second},
+			"push": {Status: "Failed", Error: "push to [REDACTED] | denied", StartTime: start.Add(time.Second), Duration: 2 * time.Second,
+				Iterations: []gxo.IterationResult{
+					{Index: 0, Status: "Completed", StartTime: start.Add(time.Second), Duration: time.Second},
+					{Index: 1, Status: "Failed", Error: "push to [REDACTED] | denied", StartTime: start.Add(time.Second), Duration: 2 * time.Second},
+				},
+				IterationCounts: &gxo.IterationCounts{Total: 2, Completed: 1, Failed: 1}},
+			"notify":  {Status: "Skipped", Error: "task skipped: 'when' condition false"},
+			"cleanup": {Status: "Completed", Section: "finally", CacheHits: 1, StartTime: start.Add(2 * time.Second)},
+		},
+		Outputs: map[string]interface{}{"image": "app:1.0"},
+	}
+}
+
+func TestWrite_JSONMatchesSchema(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, report.Write(&out, testReport(), report.FormatJSON))
+
+	result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(report.Schema), gojsonschema.NewBytesLoader(out.Bytes()))
+	require.NoError(t, err)
+	assert.True(t, result.Valid(), "JSON report must match its schema: %v", result.Errors())
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, report.SchemaVersion, decoded["schema_version"])
+	assert.Equal(t, "run-1", decoded["run_id"])
+	assert.Equal(t, float64(1), decoded["changed_tasks"])
+	assert.Equal(t, float64(1), decoded["cache_hits"])
+	iterations := decoded["task_results"].(map[string]interface{})["push"].(map[string]interface{})["iterations"].([]interface{})
+	assert.Len(t, iterations, 2)
+}
+
+func TestWrite_JUnit(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, report.Write(&out, testReport(), report.FormatJUnit))
+
+	var suites struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+		Skipped  int `xml:"skipped,attr"`
+		Suites   []struct {
+			Name  string `xml:"name,attr"`
+			Cases []struct {
+				ClassName string `xml:"classname,attr"`
+				Name      string `xml:"name,attr"`
+				Failure   *struct {
+					Message string `xml:"message,attr"`
+				} `xml:"failure"`
+				Skipped *struct {
+					Message string `xml:"message,attr"`
+				} `xml:"skipped"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	require.NoError(t, xml.Unmarshal(out.Bytes(), &suites))
+	assert.Equal(t, 5, suites.Tests, "Each loop iteration is a test case of its own")
+	assert.Equal(t, 1, suites.Failures)
+	assert.Equal(t, 1, suites.Skipped)
+	require.Len(t, suites.Suites, 1)
+
+	cases := suites.Suites[0].Cases
+	require.Len(t, cases, 5)
+	assert.Equal(t, "build", cases[0].Name)
+	assert.Equal(t, "push[0]", cases[1].Name)
+	assert.Nil(t, cases[1].Failure)
+	assert.Equal(t, "push[1]", cases[2].Name)
+	require.NotNil(t, cases[2].Failure)
+	assert.Equal(t, "push to [REDACTED] | denied", cases[2].Failure.Message)
+	assert.Equal(t, "deploy.finally", cases[3].ClassName)
+	assert.Equal(t, "notify", cases[4].Name)
+	require.NotNil(t, cases[4].Skipped)
+	assert.Equal(t, "task skipped: 'when' condition false", cases[4].Skipped.Message)
+}
+
+func TestWrite_JUnitIncludedPlaybooks(t *testing.T) {
+	child := &gxo.ExecutionReport{PlaybookName: "child", OverallStatus: "Completed", TaskResults: map[string]gxo.TaskResult{
+		"step": {Status: "Completed"},
+	}}
+	parent := &gxo.ExecutionReport{PlaybookName: "parent", OverallStatus: "Failed", Error: "failed to render output 'url'", TaskResults: map[string]gxo.TaskResult{
+		"include": {Status: "Completed", NestedReports: []*gxo.ExecutionReport{child}},
+	}}
+
+	var out bytes.Buffer
+	require.NoError(t, report.Write(&out, parent, report.FormatJUnit))
+	assert.Contains(t, out.String(), `<testsuite name="parent/include"`)
+	assert.Contains(t, out.String(), `<testcase classname="child" name="step"`)
+	assert.Contains(t, out.String(), `name="(playbook run)"`, "A failed run whose tasks passed must still fail the suite")
+	assert.Contains(t, out.String(), `<failure message="failed to render output &#39;url&#39;" type="Failed">`)
+}
+
+func TestWrite_Markdown(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, report.Write(&out, testReport(), report.FormatMarkdown))
+
+	assert.Contains(t, out.String(), "# Playbook `deploy`: Failed\n")
+	assert.Contains(t, out.String(), "| run-1 | 3.000s | 4 | 2 | 1 | 1 | 1 |\n")
+	assert.Contains(t, out.String(), "\n**Cache:** 1 hits, 1 misses\n")
+	assert.Contains(t, out.String(), "| `build` | Completed | 1.000s | changed; cache: 0 hits, 1 misses |\n")
+	assert.Contains(t, out.String(), "| `push[1]` | Failed | 2.000s | push to [REDACTED] \\| denied |\n")
+	assert.Contains(t, out.String(), "| `push` | Failed | 2.000s | iterations: 1 completed, 1 failed, 0 skipped; push to [REDACTED] \\| denied |\n")
+	assert.Contains(t, out.String(), "| `cleanup` | Completed | 0.000s | cache: 1 hits, 0 misses; section: finally |\n")
+	assert.Contains(t, out.String(), "```json\n{\n  \"image\": \"app:1.0\"\n}\n```\n")
+}
+
+func TestWrite_UnsupportedFormat(t *testing.T) {
+	err := report.Write(&bytes.Buffer{}, testReport(), "html")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported report format 'html'")
+}
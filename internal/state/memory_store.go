@@ -56,6 +56,23 @@ func (s *MemoryStateStore) GetAll() map[string]interface{} {
 	return nestedData
 }
 
+// GetAllWithPrefix returns a deep, nested copy of the keys under prefix, with
+// the prefix removed. Only those keys are copied, so its cost does not grow
+// with the rest of the state.
+func (s *MemoryStateStore) GetAllWithPrefix(prefix string) map[string]interface{} {
+	keyPrefix := prefix + "."
+	s.mu.RLock()
+	flatData := make(map[string]interface{})
+	for key, value := range s.data {
+		if strings.HasPrefix(key, keyPrefix) {
+			flatData[strings.TrimPrefix(key, keyPrefix)] = value
+		}
+	}
+	s.mu.RUnlock()
+
+	return unflattenAndDeepCopy(flatData)
+}
+
 // unflattenAndDeepCopy converts a flat map with dot-notation keys into a nested map.
 func unflattenAndDeepCopy(flatData map[string]interface{}) map[string]interface{} {
 	nestedMap := make(map[string]interface{})
@@ -137,4 +154,5 @@ func (s *MemoryStateStore) Close() error {
 // Compile-time checks to ensure MemoryStateStore implements both the internal
 // and public state store interfaces.
 var _ StateStore = (*MemoryStateStore)(nil)
-var _ gxo.Store = (*MemoryStateStore)(nil)
\ No newline at end of file
+var _ gxo.Store = (*MemoryStateStore)(nil)
+var _ gxo.PrefixReader = (*MemoryStateStore)(nil)
\ No newline at end of file
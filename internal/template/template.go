@@ -187,9 +187,9 @@ func getFullVarPath(node parse.Node, funcMap template.FuncMap) string {
 	case *parse.DotNode:
 		return ""
 	case *parse.IdentifierNode:
-		if _, isFunc := funcMap[n.Ident]; !isFunc {
-			return n.Ident
-		}
+		// Identifiers always name functions, including builtins such as 'not'
+		// and 'len' that are not part of the FuncMap.
+		return ""
 	}
 	return ""
 }
@@ -53,23 +53,50 @@ func NewNoOpProvider() (*OtelTracerProvider, error) {
 	}, nil
 }
 
+// Settings configures a provider created with NewProvider. Unset fields fall
+// back to the standard OpenTelemetry environment variables (OTEL_*).
+type Settings struct {
+	// Disabled turns tracing off, like OTEL_SDK_DISABLED=true.
+	Disabled bool
+	// Protocol is the OTLP protocol: "grpc", "http" or "http/protobuf".
+	Protocol string
+	// Endpoint is the address of the OTLP collector.
+	Endpoint string
+	// Insecure disables TLS on the connection to the collector.
+	Insecure bool
+	// ServiceName is the service name spans are reported under.
+	ServiceName string
+}
+
 // NewProviderFromEnv creates an OtelTracerProvider configured using standard
 // OpenTelemetry environment variables (OTEL_*).
 // If tracing is disabled (OTEL_SDK_DISABLED=true) or essential configuration
 // (like endpoint) is missing or invalid, it falls back to using a NoOp provider.
 // This function does *not* set the global OTel provider.
 func NewProviderFromEnv(ctx context.Context) (*OtelTracerProvider, error) {
-	// Check if tracing is explicitly disabled via environment variable.
+	return NewProvider(ctx, Settings{})
+}
+
+// NewProvider creates an OtelTracerProvider configured by settings, falling
+// back to the OpenTelemetry environment variables for the unset ones. Like
+// NewProviderFromEnv, it uses a NoOp provider when tracing is disabled or
+// cannot be configured.
+func NewProvider(ctx context.Context, settings Settings) (*OtelTracerProvider, error) {
+	// Check if tracing is explicitly disabled via settings or environment variable.
+	if settings.Disabled {
+		fmt.Fprintln(os.Stderr, "Info: OpenTelemetry tracing disabled via the 'tracing.enabled' setting.")
+		return NewNoOpProvider()
+	}
 	if strings.ToLower(os.Getenv("OTEL_SDK_DISABLED")) == "true" {
-		fmt.Println("Info: OpenTelemetry tracing disabled via OTEL_SDK_DISABLED.")
+		fmt.Fprintln(os.Stderr, "Info: OpenTelemetry tracing disabled via OTEL_SDK_DISABLED.")
 		return NewNoOpProvider()
 	}
 
 	// Attempt to create a resource description using environment variables and system info.
 	// This adds metadata like service name, host, OS, etc., to traces.
 	res, err := resource.New(ctx,
-		resource.WithSchemaURL(semconv.SchemaURL), // Specify schema URL for compatibility.
-		resource.WithAttributes(semconv.ServiceNameKey.String(otelServiceName())), // Set service name.
+		resource.WithSchemaURL(semconv.SchemaURL),                                         // Specify schema URL for compatibility.
+		resource.WithAttributes(semconv.ServiceNameKey.String(otelServiceName(settings))), // Set service name.
 		// Automatically detect process, OS, container, and host information.
 		resource.WithProcess(), resource.WithOS(), resource.WithContainer(), resource.WithHost(),
 	)
@@ -80,7 +107,7 @@ func NewProviderFromEnv(ctx context.Context) (*OtelTracerProvider, error) {
 	}
 
 	// Create the appropriate OTLP exporter (gRPC or HTTP) based on environment configuration.
-	exporter, err := createExporter(ctx)
+	exporter, err := createExporter(ctx, settings)
 	if err != nil {
 		// If exporter creation fails (e.g., invalid config), log warning and use NoOp.
 		fmt.Fprintf(os.Stderr, "Warning: Failed to create OTLP exporter from environment: %v. Using NoOp tracer.\n", err)
@@ -88,7 +115,7 @@ func NewProviderFromEnv(ctx context.Context) (*OtelTracerProvider, error) {
 	}
 	// If no endpoint was configured, createExporter returns nil. Use NoOp in this case.
 	if exporter == nil {
-		fmt.Println("Info: OpenTelemetry endpoint not configured (e.g., OTEL_EXPORTER_OTLP_ENDPOINT not set). Using NoOp tracer.")
+		fmt.Fprintln(os.Stderr, "Info: OpenTelemetry endpoint not configured (e.g., OTEL_EXPORTER_OTLP_ENDPOINT not set). Using NoOp tracer.")
 		return NewNoOpProvider()
 	}
 
@@ -107,7 +134,7 @@ func NewProviderFromEnv(ctx context.Context) (*OtelTracerProvider, error) {
 		sdktrace.WithSpanProcessor(bsp),
 	)
 
-	fmt.Println("Info: OpenTelemetry SDK provider configured based on environment.")
+	fmt.Fprintln(os.Stderr, "Info: OpenTelemetry SDK provider configured based on environment.")
 	// Return the wrapper struct containing the configured SDK provider and exporter.
 	return &OtelTracerProvider{
 		provider:    sdkTP, // Store the SDK provider as the trace.TracerProvider interface.
@@ -117,18 +144,26 @@ func NewProviderFromEnv(ctx context.Context) (*OtelTracerProvider, error) {
 }
 
 // createExporter determines the OTLP protocol (gRPC or HTTP) and endpoint from
-// environment variables and creates the corresponding span exporter instance.
-// Returns nil if no endpoint is configured, or an error for invalid configurations.
-func createExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+// the settings or environment variables and creates the corresponding span
+// exporter instance. Returns nil if no endpoint is configured, or an error for
+// invalid configurations.
+func createExporter(ctx context.Context, settings Settings) (sdktrace.SpanExporter, error) {
 	// Determine protocol (default to grpc).
-	protocol := strings.ToLower(os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL"))
+	protocol := strings.ToLower(settings.Protocol)
+	if protocol == "" {
+		protocol = strings.ToLower(os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL"))
+	}
 	if protocol == "" {
 		protocol = "grpc"
 	}
 
 	// Get endpoint. If not set, default based on protocol or return nil if unknown.
-	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
-	endpointSource := "environment"
+	endpoint := settings.Endpoint
+	endpointSource := "settings"
+	if endpoint == "" {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		endpointSource = "environment"
+	}
 	if endpoint == "" {
 		endpointSource = "default"
 		switch protocol {
@@ -140,7 +175,7 @@ func createExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
 			// No explicit endpoint and unsupported protocol requires no exporter.
 			return nil, nil
 		}
-		fmt.Printf("Info: OTEL_EXPORTER_OTLP_ENDPOINT not set, using %s endpoint: %s\n", strings.ToUpper(protocol), endpoint)
+		fmt.Fprintf(os.Stderr, "Info: OTEL_EXPORTER_OTLP_ENDPOINT not set, using %s endpoint: %s\n", strings.ToUpper(protocol), endpoint)
 	}
 
 	// Parse common OTLP environment configurations.
@@ -148,8 +183,8 @@ func createExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
 	timeout := parseTimeout(os.Getenv("OTEL_EXPORTER_OTLP_TIMEOUT"), 10*time.Second) // 10s default timeout
 	compression := os.Getenv("OTEL_EXPORTER_OTLP_COMPRESSION")                       // e.g., "gzip"
 	// Check OTEL_EXPORTER_OTLP_INSECURE or OTEL_EXPORTER_OTLP_TRACES_INSECURE
-	grpcInsecure := isInsecure(os.Getenv("OTEL_EXPORTER_OTLP_INSECURE"), os.Getenv("OTEL_EXPORTER_OTLP_TRACES_INSECURE"))
-	httpInsecure := isInsecure(os.Getenv("OTEL_EXPORTER_OTLP_INSECURE"), os.Getenv("OTEL_EXPORTER_OTLP_TRACES_INSECURE"))
+	grpcInsecure := settings.Insecure || isInsecure(os.Getenv("OTEL_EXPORTER_OTLP_INSECURE"), os.Getenv("OTEL_EXPORTER_OTLP_TRACES_INSECURE"))
+	httpInsecure := settings.Insecure || isInsecure(os.Getenv("OTEL_EXPORTER_OTLP_INSECURE"), os.Getenv("OTEL_EXPORTER_OTLP_TRACES_INSECURE"))
 
 	// Create exporter based on protocol.
 	switch protocol {
@@ -170,7 +205,7 @@ func createExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
 		if strings.ToLower(compression) == "gzip" {
 			opts = append(opts, otlptracegrpc.WithCompressor(gzip.Name))
 		}
-		fmt.Printf("Info: Configuring OTLP gRPC exporter (endpoint: %s [%s], insecure: %t, compression: %s)\n", endpoint, endpointSource, grpcInsecure, compression)
+		fmt.Fprintf(os.Stderr, "Info: Configuring OTLP gRPC exporter (endpoint: %s [%s], insecure: %t, compression: %s)\n", endpoint, endpointSource, grpcInsecure, compression)
 		// Create and return the gRPC exporter.
 		return otlptracegrpc.New(ctx, opts...)
 
@@ -198,7 +233,7 @@ func createExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
 		if strings.ToLower(compression) == "gzip" {
 			opts = append(opts, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
 		}
-		fmt.Printf("Info: Configuring OTLP HTTP exporter (endpoint: %s%s [%s], insecure: %t, compression: %s)\n", baseURL, httpPath, endpointSource, httpInsecure, compression)
+		fmt.Fprintf(os.Stderr, "Info: Configuring OTLP HTTP exporter (endpoint: %s%s [%s], insecure: %t, compression: %s)\n", baseURL, httpPath, endpointSource, httpInsecure, compression)
 		// Create and return the HTTP exporter.
 		return otlptracehttp.New(ctx, opts...)
 
@@ -229,7 +264,7 @@ func (p *OtelTracerProvider) Shutdown(ctx context.Context) error {
 
 	// Only attempt shutdown if an actual SDK provider is configured.
 	if p.sdkProvider != nil {
-		fmt.Println("Info: Shutting down OpenTelemetry SDK tracer provider...")
+		fmt.Fprintln(os.Stderr, "Info: Shutting down OpenTelemetry SDK tracer provider...")
 		// Attempt to shut down the SDK provider.
 		if err := p.sdkProvider.Shutdown(ctx); err != nil {
 			fmt.Fprintf(os.Stderr, "Error shutting down OTel tracer provider: %v\n", err)
@@ -239,7 +274,7 @@ func (p *OtelTracerProvider) Shutdown(ctx context.Context) error {
 
 	// Only attempt shutdown if an actual exporter is configured.
 	if p.exporter != nil {
-		fmt.Println("Info: Shutting down OpenTelemetry exporter...")
+		fmt.Fprintln(os.Stderr, "Info: Shutting down OpenTelemetry exporter...")
 		// Attempt to shut down the exporter.
 		if expErr := p.exporter.Shutdown(ctx); expErr != nil {
 			fmt.Fprintf(os.Stderr, "Error shutting down OTel exporter: %v\n", expErr)
@@ -248,13 +283,13 @@ func (p *OtelTracerProvider) Shutdown(ctx context.Context) error {
 				firstError = expErr
 			}
 		} else {
-			fmt.Println("Info: OpenTelemetry exporter shut down successfully.")
+			fmt.Fprintln(os.Stderr, "Info: OpenTelemetry exporter shut down successfully.")
 		}
 	}
 
 	// Log overall success only if resources were actually shut down without error.
 	if firstError == nil && (p.sdkProvider != nil || p.exporter != nil) {
-		fmt.Println("Info: OpenTelemetry tracing shut down successfully.")
+		fmt.Fprintln(os.Stderr, "Info: OpenTelemetry tracing shut down successfully.")
 	} else if p.sdkProvider == nil && p.exporter == nil {
 		// Log nothing explicit if it was NoOp from the start.
 	}
@@ -269,8 +304,11 @@ func (p *OtelTracerProvider) IsEffectivelyNoOp() bool {
 	return p.sdkProvider == nil
 }
 
-// otelServiceName determines the service name, prioritizing OTEL_SERVICE_NAME env var.
-func otelServiceName() string {
+// otelServiceName determines the service name, prioritizing the settings, then the OTEL_SERVICE_NAME env var.
+func otelServiceName(settings Settings) string {
+	if settings.ServiceName != "" {
+		return settings.ServiceName
+	}
 	name := os.Getenv("OTEL_SERVICE_NAME")
 	if name == "" {
 		name = "gxo" // Default service name if not set.
@@ -0,0 +1,34 @@
+package util
+
+import "encoding/json"
+
+// NormalizeJSONNumbers walks decoded JSON data and converts json.Number
+// values into int when they are integral and float64 otherwise, matching what
+// the YAML decoder produces for playbook variables.
+func NormalizeJSONNumbers(data interface{}) interface{} {
+	switch v := data.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return int(i)
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case map[string]interface{}:
+		if v == nil {
+			return map[string]interface{}(nil)
+		}
+		for key, val := range v {
+			v[key] = NormalizeJSONNumbers(val)
+		}
+		return v
+	case []interface{}:
+		for i, val := range v {
+			v[i] = NormalizeJSONNumbers(val)
+		}
+		return v
+	default:
+		return data
+	}
+}
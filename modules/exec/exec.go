@@ -3,6 +3,8 @@ package exec
 import (
 	"context"
 	"fmt"
+	"os"
+	"sort"
 	"sync"
 
 	"github.com/gxo-labs/gxo/internal/command"
@@ -74,6 +76,12 @@ func (m *ExecModule) Perform(
 	if err != nil {
 		return nil, err
 	}
+	// The environment of the playbook, blocks and task is added to the
+	// process's own. Entries of the 'environment' param come last and take
+	// precedence; given alone, they still replace the process's environment.
+	if taskEnvironment := module.Environment(ctx); len(taskEnvironment) > 0 {
+		environment = append(withEnvironment(os.Environ(), taskEnvironment), environment...)
+	}
 
 	// Check the context for the DryRunKey to determine if we are in dry-run mode.
 	if isDryRun := ctx.Value(module.DryRunKey{}) == true; isDryRun {
@@ -122,4 +130,20 @@ func (m *ExecModule) Perform(
 
 	// Command executed successfully (exit code 0).
 	return summaryMap, nil
+}
+
+// withEnvironment returns base, a list of "KEY=value" entries, followed by the
+// given variables sorted by name. When a key is repeated, the command uses its
+// last value.
+func withEnvironment(base []string, variables map[string]string) []string {
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	merged := append([]string{}, base...)
+	for _, name := range names {
+		merged = append(merged, name+"="+variables[name])
+	}
+	return merged
 }
\ No newline at end of file
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/gxo-labs/gxo/internal/config"
+	"github.com/gxo-labs/gxo/pkg/gxo/v1/cache"
+	"github.com/gxo-labs/gxo/pkg/gxo/v1/checkpoint"
 	"github.com/gxo-labs/gxo/pkg/gxo/v1/events"
 	gxoerrors "github.com/gxo-labs/gxo/pkg/gxo/v1/errors"
 	"github.com/gxo-labs/gxo/pkg/gxo/v1/metrics"
@@ -17,8 +19,23 @@ import (
 
 // EngineV1 defines the public interface for the GXO automation engine.
 type EngineV1 interface {
-	// RunPlaybook executes a playbook from its raw YAML content.
+	// RunPlaybook executes a playbook from its raw YAML content and waits for
+	// it to finish. Once it finished, its final state is copied to the top
+	// level of a store set with WithStateStore.
 	RunPlaybook(ctx context.Context, playbookYAML []byte) (*ExecutionReport, error)
+	// StartPlaybook starts executing a playbook from its raw YAML content and
+	// returns a handle to the run without waiting for it. Runs started on the
+	// same engine execute concurrently and share its worker pool. Each run
+	// keeps its state apart from the others: in its own in-memory store, or
+	// under _gxo.runs.<run ID> in a store set with WithStateStore, which is
+	// removed once the run finished.
+	StartPlaybook(ctx context.Context, playbookYAML []byte) (RunHandle, error)
+	// ResumeRun continues a previously interrupted or failed run from its
+	// persisted checkpoint. Requires a checkpoint store to be configured.
+	ResumeRun(ctx context.Context, runID string) (*ExecutionReport, error)
+	// StartResumeRun is like ResumeRun, but returns a handle to the resumed
+	// run without waiting for it to finish.
+	StartResumeRun(ctx context.Context, runID string) (RunHandle, error)
 
 	// MetricsRegistryProvider returns the underlying metrics provider.
 	MetricsRegistryProvider() metrics.RegistryProvider
@@ -32,6 +49,8 @@ type EngineV1 interface {
 	SetPluginRegistry(registry plugin.Registry) error
 	SetMetricsRegistryProvider(provider metrics.RegistryProvider) error
 	SetTracerProvider(provider tracing.TracerProvider) error
+	SetCheckpointStore(store checkpoint.Store) error
+	SetCacheStore(store cache.Store) error
 	SetDefaultTimeout(timeout time.Duration) error
 	SetWorkerPoolSize(size int) error
 	SetDefaultChannelPolicy(policy ChannelPolicy) error
@@ -42,6 +61,52 @@ type EngineV1 interface {
 // EngineOption is a function type used to configure the GXO engine at creation.
 type EngineOption func(EngineV1) error
 
+// PlaybookPathKey is the context key giving the path of the file a playbook
+// passed as raw YAML was read from. The engine resolves the playbook's
+// imports and included playbooks relative to it, and cites it in errors.
+type PlaybookPathKey struct{}
+
+// InputValuesKey is the context key giving the values supplied for a
+// playbook's declared inputs, as a map[string]interface{}. The engine checks
+// them against the declarations before the run starts. String values are
+// parsed into the declared type, so command line values can be passed as is.
+type InputValuesKey struct{}
+
+// TaskSelectionKey is the context key giving the TaskSelection restricting a
+// run to part of the playbook.
+type TaskSelectionKey struct{}
+
+// NoCacheKey is the context key that, set to true, makes the tasks with a
+// 'cache' execute even when a fresh cached result exists. Their results are
+// still cached for later runs.
+type NoCacheKey struct{}
+
+// TaskSelection restricts a run to part of the playbook's main tasks. The
+// criteria combine: a task is selected if it meets all of those that are set.
+// The dependencies of selected tasks are selected too, unless the value they
+// register is already in the state, e.g. restored from the run given by
+// ReuseRunID. The tasks left out are reported as Skipped with the reason.
+type TaskSelection struct {
+	// Tags selects the tasks having at least one of the tags. A task has its
+	// own tags and those of its enclosing blocks.
+	Tags []string `json:"tags,omitempty"`
+	// SkipTags leaves out the tasks having any of the tags.
+	SkipTags []string `json:"skip_tags,omitempty"`
+	// Only selects the named tasks. Naming a block selects all its members.
+	Only []string `json:"only,omitempty"`
+	// StartAtTask selects the named task and every task depending on it,
+	// directly or not.
+	StartAtTask string `json:"start_at_task,omitempty"`
+	// ReuseRunID names a checkpointed run whose registered values are loaded
+	// into the state, so the dependencies that registered them need not run.
+	ReuseRunID string `json:"reuse_run_id,omitempty"`
+}
+
+// IsEmpty reports whether the selection selects every task.
+func (s TaskSelection) IsEmpty() bool {
+	return len(s.Tags) == 0 && len(s.SkipTags) == 0 && len(s.Only) == 0 && s.StartAtTask == "" && s.ReuseRunID == ""
+}
+
 // TaskResult holds the final outcome of a single task execution.
 type TaskResult struct {
 	Status    string        `json:"status"`
@@ -49,10 +114,56 @@ type TaskResult struct {
 	StartTime time.Time     `json:"start_time"`
 	EndTime   time.Time     `json:"end_time"`
 	Duration  time.Duration `json:"duration"`
+	// Changed reports whether the task changed something, as decided by its
+	// 'changed_when' condition or its module's summary. A looped task
+	// changed something if any of its iterations did. An iteration restored
+	// from the cache never changed anything.
+	Changed bool `json:"changed"`
+	// CacheHits and CacheMisses count the executions of a task with a
+	// 'cache' whose summary was restored from the cache, and those that ran
+	// the module, one per loop iteration.
+	CacheHits   int `json:"cache_hits,omitempty"`
+	CacheMisses int `json:"cache_misses,omitempty"`
+	// Section is the playbook section the task belongs to ("on_failure" or
+	// "finally"). It is empty for tasks in the main 'tasks' list.
+	Section string `json:"section,omitempty"`
+	// Block is the name of the innermost block enclosing the task. It is
+	// empty for tasks that are not part of a block.
+	Block string `json:"block,omitempty"`
+	// NestedReports holds the reports of the playbooks run by an
+	// include_playbook task, one per loop iteration.
+	NestedReports []*ExecutionReport `json:"nested_reports,omitempty"`
+	// Iterations holds the outcome of each iteration of a task with a
+	// 'loop', in the order of the loop items. Iterations that never started,
+	// because the task was cancelled or timed out, are Skipped.
+	Iterations []IterationResult `json:"iterations,omitempty"`
+	// IterationCounts tallies the statuses of Iterations.
+	IterationCounts *IterationCounts `json:"iteration_counts,omitempty"`
+}
+
+// IterationCounts tallies the outcomes of a looped task's iterations.
+type IterationCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+}
+
+// IterationResult holds the outcome of a single iteration of a looped task.
+// Name is set for the iterations of a 'loop_matrix', e.g. "os=linux,arch=arm64".
+type IterationResult struct {
+	Index     int           `json:"index"`
+	Name      string        `json:"name,omitempty"`
+	Status    string        `json:"status"`
+	Error     string        `json:"error,omitempty"`
+	StartTime time.Time     `json:"start_time"`
+	EndTime   time.Time     `json:"end_time"`
+	Duration  time.Duration `json:"duration"`
 }
 
 // ExecutionReport provides a comprehensive summary of a completed playbook run.
 type ExecutionReport struct {
+	RunID          string                `json:"run_id,omitempty"`
 	PlaybookName   string                `json:"playbook_name"`
 	OverallStatus  string                `json:"overall_status"`
 	StartTime      time.Time             `json:"start_time"`
@@ -62,8 +173,54 @@ type ExecutionReport struct {
 	CompletedTasks int                   `json:"completed_tasks"`
 	FailedTasks    int                   `json:"failed_tasks"`
 	SkippedTasks   int                   `json:"skipped_tasks"`
+	ChangedTasks   int                   `json:"changed_tasks"` // Completed tasks that changed something.
+	CacheHits      int                   `json:"cache_hits"`    // Task executions restored from the cache.
+	CacheMisses    int                   `json:"cache_misses"`  // Task executions with a 'cache' that ran their module.
 	Error          string                `json:"error,omitempty"`
 	TaskResults    map[string]TaskResult `json:"task_results"`
+	// Outputs holds the playbook's outputs, rendered against its final state.
+	// Secrets resolved while rendering them are redacted.
+	Outputs map[string]interface{} `json:"outputs,omitempty"`
+}
+
+// Lifecycle states of a playbook run, as reported by RunStatus.
+const (
+	RunStateRunning   = "Running"
+	RunStatePaused    = "Paused"
+	RunStateCompleted = "Completed"
+	RunStateFailed    = "Failed"
+)
+
+// RunHandle controls a playbook run started with StartPlaybook.
+type RunHandle interface {
+	// RunID returns the ID of the run.
+	RunID() string
+	// Wait blocks until the run has finished and returns its report.
+	Wait() (*ExecutionReport, error)
+	// Cancel cancels the run without waiting for it to finish.
+	Cancel()
+	// Status returns a snapshot of the run's progress.
+	Status() RunStatus
+	// Pause stops the run from starting new tasks; running tasks finish.
+	Pause()
+	// Resume lets a paused run start tasks again.
+	Resume()
+	// CancelTask cancels a single running task, given its name or ID,
+	// without cancelling the rest of the run.
+	CancelTask(name string) error
+}
+
+// RunStatus is a snapshot of the progress of a playbook run.
+type RunStatus struct {
+	RunID        string `json:"run_id"`
+	PlaybookName string `json:"playbook_name,omitempty"`
+	// State is one of RunStateRunning, RunStatePaused, RunStateCompleted or
+	// RunStateFailed.
+	State         string `json:"state"`
+	TotalTasks    int    `json:"total_tasks"`
+	FinishedTasks int    `json:"finished_tasks"`
+	// TaskStatuses maps the ID of every task scheduled so far to its status.
+	TaskStatuses map[string]string `json:"task_statuses"`
 }
 
 // ChannelPolicy defines the public configuration for streaming channels.
@@ -72,7 +229,12 @@ type ChannelPolicy struct {
 	OverflowStrategy string `yaml:"overflow_strategy,omitempty" json:"overflow_strategy,omitempty"`
 }
 
-// WithStateStore is an engine option to provide a custom state store.
+// WithStateStore is an engine option to provide a custom state store. Every run
+// keeps its state in it under _gxo.runs.<run ID> until it finishes, so that
+// concurrent runs never see or replace each other's state. RunPlaybook and
+// ResumeRun then copy their final state to the top level of the store. Without
+// this option, every run gets its own in-memory store, which is discarded once
+// the run finished.
 func WithStateStore(store state.Store) EngineOption {
 	return func(e EngineV1) error {
 		if store == nil {
@@ -132,6 +294,28 @@ func WithTracerProvider(provider tracing.TracerProvider) EngineOption {
 	}
 }
 
+// WithCheckpointStore is an engine option to persist run checkpoints, which
+// allows interrupted or failed runs to be resumed with ResumeRun.
+func WithCheckpointStore(store checkpoint.Store) EngineOption {
+	return func(e EngineV1) error {
+		if store == nil {
+			return gxoerrors.NewConfigError("checkpoint store cannot be nil", nil)
+		}
+		return e.SetCheckpointStore(store)
+	}
+}
+
+// WithCacheStore is an engine option to cache the summaries of the tasks
+// with a 'cache', so that later runs with the same inputs can skip them.
+func WithCacheStore(store cache.Store) EngineOption {
+	return func(e EngineV1) error {
+		if store == nil {
+			return gxoerrors.NewConfigError("cache store cannot be nil", nil)
+		}
+		return e.SetCacheStore(store)
+	}
+}
+
 // WithWorkerPoolSize is an engine option to configure the number of concurrent task workers.
 func WithWorkerPoolSize(size int) EngineOption {
 	return func(e EngineV1) error {
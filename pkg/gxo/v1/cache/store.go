@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound indicates that no cache entry exists for the requested key.
+var ErrNotFound = errors.New("cache entry not found")
+
+// Entry is the cached outcome of a task execution. Its key is a content hash
+// of everything the execution depended on: the module type, the rendered
+// params and environment, the task's cache key and the contents of its input
+// files.
+type Entry struct {
+	// Key is the hex-encoded SHA-256 hash identifying the execution.
+	Key string `json:"key"`
+	// TaskID is the internal ID of the task that produced the entry.
+	TaskID string `json:"task_id"`
+	// ModuleType is the type of the module that produced the summary.
+	ModuleType string `json:"module_type"`
+	// Summary is the (already redacted) summary returned by the module.
+	Summary interface{} `json:"summary"`
+	// CreatedAt is the time the execution finished.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Store defines the interface for persisting and retrieving cached task
+// results. Implementations must be thread-safe, as the engine executes tasks
+// and loop iterations concurrently.
+type Store interface {
+	// Get retrieves the entry for the given key. It returns ErrNotFound if no
+	// entry exists for that key.
+	Get(key string) (*Entry, error)
+
+	// Put persists the entry, replacing any previous entry with the same Key.
+	// Implementations should make the write atomic so that a crash mid-write
+	// never leaves a corrupt entry behind.
+	Put(entry *Entry) error
+}
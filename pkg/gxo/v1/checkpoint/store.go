@@ -0,0 +1,50 @@
+package checkpoint
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound indicates that no checkpoint exists for the requested run ID.
+var ErrNotFound = errors.New("checkpoint not found")
+
+// Checkpoint is a durable snapshot of a playbook run. It captures everything the
+// engine needs to resume a run without re-executing work that already finished:
+// the playbook source, the variables the run started with, the values registered
+// by tasks, and the last known status of every task.
+type Checkpoint struct {
+	// RunID uniquely identifies the run this checkpoint belongs to.
+	RunID string `json:"run_id"`
+	// PlaybookName is the 'name' field of the playbook being executed.
+	PlaybookName string `json:"playbook_name"`
+	// PlaybookPath is the path hint the playbook was loaded with, if any.
+	PlaybookPath string `json:"playbook_path,omitempty"`
+	// PlaybookYAML is the raw playbook source, so a run can be resumed even if
+	// the original file has since been moved or edited.
+	PlaybookYAML string `json:"playbook_yaml"`
+	// Vars holds the initial variables the run was started with.
+	Vars map[string]interface{} `json:"vars,omitempty"`
+	// Registered maps each 'register' key to the (already redacted) value
+	// registered by its task.
+	Registered map[string]interface{} `json:"registered,omitempty"`
+	// TaskStatuses maps each task's internal ID to its last known status.
+	TaskStatuses map[string]string `json:"task_statuses"`
+	// CreatedAt is the time the run was first started.
+	CreatedAt time.Time `json:"created_at"`
+	// UpdatedAt is the time this checkpoint was last written.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Store defines the interface for persisting and retrieving run checkpoints.
+// Implementations must be thread-safe, as the engine saves checkpoints from
+// multiple task completion paths concurrently.
+type Store interface {
+	// Save persists the checkpoint, replacing any previous checkpoint with the
+	// same RunID. Implementations should make the write atomic so that a crash
+	// mid-write never leaves a corrupt checkpoint behind.
+	Save(cp *Checkpoint) error
+
+	// Load retrieves the checkpoint for the given run ID. It returns
+	// ErrNotFound if no checkpoint exists for that run.
+	Load(runID string) (*Checkpoint, error)
+}
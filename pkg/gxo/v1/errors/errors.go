@@ -25,21 +25,62 @@ func (e *ConfigError) Error() string {
 }
 func (e *ConfigError) Unwrap() error { return e.Cause }
 
+// Position locates a value in a source file. Line and Column are 1-based;
+// zero means unknown.
+type Position struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+// IsKnown reports whether the position points to a file or a line.
+func (p Position) IsKnown() bool {
+	return p.File != "" || p.Line > 0
+}
+
+// String formats the position as "file:line:col", omitting unknown parts.
+func (p Position) String() string {
+	s := p.File
+	if p.Line > 0 {
+		s = fmt.Sprintf("%s:%d", s, p.Line)
+		if p.Column > 0 {
+			s = fmt.Sprintf("%s:%d", s, p.Column)
+		}
+	}
+	return s
+}
+
 // ValidationError indicates that some input (e.g., playbook structure,
 // schema version, parameters) failed validation checks.
 type ValidationError struct {
 	Message string
 	Cause   error
+	// Position locates the invalid value in its source file, if known.
+	Position Position
+	// Field is the path of the invalid value, e.g. "tasks.3.retry", if known.
+	Field string
+	// Errors holds the individual problems when the error reports several.
+	Errors []*ValidationError
 }
 
 func NewValidationError(message string, cause error) *ValidationError {
 	return &ValidationError{Message: message, Cause: cause}
 }
+
+// NewValidationErrorAt creates a validation error for the value at a field
+// path and source position.
+func NewValidationErrorAt(position Position, field, message string, cause error) *ValidationError {
+	return &ValidationError{Message: message, Cause: cause, Position: position, Field: field}
+}
 func (e *ValidationError) Error() string {
+	message := e.Message
+	if e.Position.IsKnown() {
+		message = fmt.Sprintf("%s: %s", e.Position, message)
+	}
 	if e.Cause != nil {
-		return fmt.Sprintf("validation error: %s: %v", e.Message, e.Cause)
+		return fmt.Sprintf("validation error: %s: %v", message, e.Cause)
 	}
-	return fmt.Sprintf("validation error: %s", e.Message)
+	return fmt.Sprintf("validation error: %s", message)
 }
 func (e *ValidationError) Unwrap() error { return e.Cause }
 
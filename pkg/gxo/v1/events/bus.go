@@ -17,6 +17,10 @@ const (
 	RecordErrorOccurred  EventType = "RecordErrorOccurred"  // Non-fatal error from module errChan
 	FatalErrorOccurred   EventType = "FatalErrorOccurred"   // Fatal error from Perform or engine logic
 	SecretAccessed       EventType = "SecretAccessed"       // A secret value was accessed via template func
+	RunPaused            EventType = "RunPaused"            // The run stopped starting new tasks
+	RunResumed           EventType = "RunResumed"           // A paused run started tasks again
+	TaskCancelled        EventType = "TaskCancelled"        // A single running task was cancelled on request
+	TaskPolled           EventType = "TaskPolled"           // A task with 'until' finished an attempt and evaluated its condition
 )
 
 // Event represents a significant occurrence within the GXO engine.
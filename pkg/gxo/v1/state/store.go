@@ -50,3 +50,12 @@ type Store interface {
 	// Close releases any resources held by the store (e.g., database connections).
 	Close() error
 }
+
+// PrefixReader is an optional interface a StateReader may implement to read
+// the part of the state under a key prefix without copying the rest of it.
+type PrefixReader interface {
+	// GetAllWithPrefix returns the nested map found at prefix in the map
+	// GetAll would return, or an empty map if there is none. The same
+	// immutability rules as for GetAll apply to the result.
+	GetAllWithPrefix(prefix string) map[string]interface{}
+}